@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/fixtures"
+	"personalized-dashboard/shared/repository"
+)
+
+func main() {
+	at := flag.String("at", "now", "reference time of the fixtures (RFC 3339, or 'now')")
+	dryRun := flag.Bool("print", false, "print the fixture set as JSON instead of writing it")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: seed [-at now|2025-01-01T00:00:00Z] [-print]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	reference := time.Now().Truncate(time.Second)
+	if *at != "now" {
+		parsed, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			log.Fatalf("invalid -at: %v", err)
+		}
+		reference = parsed
+	}
+
+	set := fixtures.Build(reference)
+
+	if *dryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(set)
+		return
+	}
+
+	db, err := database.SetupDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	summary, err := fixtures.Seed(context.Background(), repository.New(db), set)
+	if err != nil {
+		log.Fatalf("Seeding failed, nothing was written: %v", err)
+	}
+	fmt.Printf("Seeded %s\n", summary)
+}
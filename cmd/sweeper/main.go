@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/retention"
+)
+
+func main() {
+	once := flag.Bool("once", false, "run a single sweep, print the report as JSON and exit")
+	interval := flag.Duration("interval", time.Hour, "time between sweeps (SWEEP_INTERVAL overrides the default)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: sweeper [-once] [-interval 1h]\n")
+		flag.PrintDefaults()
+	}
+	if value := os.Getenv("SWEEP_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("invalid SWEEP_INTERVAL: %v", err)
+		}
+		*interval = parsed
+	}
+	flag.Parse()
+
+	policies, err := retention.PoliciesFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.SetupDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	sweeper := retention.New(db, policies)
+
+	if *once {
+		report, err := sweeper.Sweep(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Retention sweeper running every %s", *interval)
+	sweeper.Run(ctx, *interval)
+}
@@ -1,7 +1,10 @@
 module api-gateway
 
-go 1.20
+go 1.23.0
 
 require (
 	gofr.dev v1.44.1
+	personalized-dashboard v0.0.0-00010101000000-000000000000
 )
+
+replace personalized-dashboard => ..
@@ -1,128 +1,149 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
+	"context"
+	"log"
 
 	"gofr.dev"
 	"gofr.dev/pkg/gofr"
-)
 
-type GatewayService struct {
-	newsServiceURL           string
-	jobsServiceURL           string
-	videosServiceURL         string
-	dealsServiceURL          string
-	recommendationServiceURL string
-	userServiceURL           string
-	nftServiceURL            string
-}
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/batch"
+	"personalized-dashboard/shared/dashboard"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/graph"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/httpcache"
+	"personalized-dashboard/shared/idempotency"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/ratelimit"
+	"personalized-dashboard/shared/routes"
+	"personalized-dashboard/shared/tracing"
+)
 
 func main() {
 	app := gofr.New()
 
-	gateway := &GatewayService{
-		newsServiceURL:           getEnv("NEWS_SERVICE_URL", "http://localhost:8001"),
-		jobsServiceURL:           getEnv("JOBS_SERVICE_URL", "http://localhost:8002"),
-		videosServiceURL:         getEnv("VIDEOS_SERVICE_URL", "http://localhost:8003"),
-		dealsServiceURL:          getEnv("DEALS_SERVICE_URL", "http://localhost:8004"),
-		recommendationServiceURL: getEnv("RECOMMENDATION_SERVICE_URL", "http://localhost:8005"),
-		userServiceURL:           getEnv("USER_SERVICE_URL", "http://localhost:8006"),
-		nftServiceURL:            getEnv("NFT_SERVICE_URL", "http://localhost:8007"),
+	// Trace requests across the gateway and the services
+	traceConfig, err := tracing.ConfigFromEnv("api-gateway")
+	if err != nil {
+		log.Fatal(err)
 	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
 
-	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
-		return map[string]string{"status": "healthy", "service": "api-gateway"}, nil
-	})
-
-	// News endpoints
-	app.GET("/api/news", gateway.proxyToService(gateway.newsServiceURL+"/api/news"))
-	app.GET("/api/news/trending", gateway.proxyToService(gateway.newsServiceURL+"/api/news/trending"))
-	app.GET("/api/news/search", gateway.proxyToService(gateway.newsServiceURL+"/api/news/search"))
-
-	// Jobs endpoints
-	app.GET("/api/jobs", gateway.proxyToService(gateway.jobsServiceURL+"/api/jobs"))
-	app.GET("/api/jobs/trending", gateway.proxyToService(gateway.jobsServiceURL+"/api/jobs/trending"))
-	app.GET("/api/jobs/search", gateway.proxyToService(gateway.jobsServiceURL+"/api/jobs/search"))
-
-	// Videos endpoints
-	app.GET("/api/videos", gateway.proxyToService(gateway.videosServiceURL+"/api/videos"))
-	app.GET("/api/videos/trending", gateway.proxyToService(gateway.videosServiceURL+"/api/videos/trending"))
-	app.GET("/api/videos/search", gateway.proxyToService(gateway.videosServiceURL+"/api/videos/search"))
-
-	// Deals endpoints
-	app.GET("/api/deals", gateway.proxyToService(gateway.dealsServiceURL+"/api/deals"))
-	app.GET("/api/deals/trending", gateway.proxyToService(gateway.dealsServiceURL+"/api/deals/trending"))
-	app.GET("/api/deals/search", gateway.proxyToService(gateway.dealsServiceURL+"/api/deals/search"))
-
-	// Recommendation endpoints
-	app.GET("/api/recommendations", gateway.proxyToService(gateway.recommendationServiceURL+"/api/recommendations"))
-
-	// User endpoints
-	app.POST("/api/users", gateway.proxyToService(gateway.userServiceURL+"/api/users"))
-	app.GET("/api/users/:id", gateway.proxyToService(gateway.userServiceURL+"/api/users/:id"))
+	// Proxied routes come from the route file; SIGHUP reloads it
+	router, err := routes.NewRouter(routes.FileFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.ReloadOnSignal(context.Background())
 
-	// NFT endpoints
-	app.POST("/api/nft/mint", gateway.proxyToService(gateway.nftServiceURL+"/api/nft/mint"))
-	app.GET("/api/nft/:user_id", gateway.proxyToService(gateway.nftServiceURL+"/api/nft/:user_id"))
+	// Cache GET responses of routes with a cache setting
+	cacheConfig, err := httpcache.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.UseCache(httpcache.New(cacheConfig))
 
-	// Start server
-	app.Start()
-}
+	// Verify bearer tokens and pass the user on as a trusted X-User-ID
+	authConfig, err := auth.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	verifier, err := auth.NewVerifier(authConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	verifier.AllowAnonymous(router.Public)
 
-func (gs *GatewayService) proxyToService(targetURL string) func(ctx *gofr.Context) (interface{}, error) {
-	return func(ctx *gofr.Context) (interface{}, error) {
-		// Build the full URL with query parameters
-		fullURL := targetURL
-		if len(ctx.Request().URL.RawQuery) > 0 {
-			fullURL += "?" + ctx.Request().URL.RawQuery
-		}
+	// Batches at /api/batch; each sub-request goes through auth, rate limits and routing
+	batchConfig, err := batch.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	batcher := batch.New(batchConfig)
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+	app.UseMiddleware(batcher.Middleware)
+	app.UseMiddleware(verifier.Middleware)
+
+	// Throttle clients per user, API key or IP before they reach the paid upstreams
+	rateConfig, err := ratelimit.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rateStore, err := ratelimit.OpenStore(rateConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter := ratelimit.New(rateConfig, rateStore)
+	app.UseMiddleware(limiter.Middleware)
 
-		// Create HTTP request
-		req, err := http.NewRequest(ctx.Request().Method, fullURL, ctx.Request().Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %v", err)
-		}
+	// Replay stored responses to POSTs retried with the same Idempotency-Key
+	idempotencyConfig, err := idempotency.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	idempotencyStore, err := idempotency.OpenStore(idempotencyConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keeper := idempotency.New(idempotencyConfig, idempotencyStore)
+	app.UseMiddleware(keeper.Middleware)
 
-		// Copy headers
-		for key, values := range ctx.Request().Header {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+	app.UseMiddleware(router.Middleware)
 
-		// Make request
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to make request: %v", err)
-		}
-		defer resp.Body.Close()
+	// Dashboard sections, fetched concurrently for /api/dashboard
+	sections, err := dashboard.SectionsFromEnv(router.UpstreamURLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aggregator := dashboard.New(sections)
 
-		// Read response
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %v", err)
-		}
+	// GraphQL over the services at /graphql
+	graphConfig, err := graph.ConfigFromEnv(router.UpstreamURLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	graphServer, err := graph.New(graphConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.UseMiddleware(graphServer.Middleware)
 
-		// Parse JSON response
-		var result interface{}
-		if err := json.Unmarshal(body, &result); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %v", err)
+	// Health check
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
+		return map[string]string{"status": "healthy", "service": "api-gateway"}, nil
+	}))
+
+	// Health and readiness of every upstream, with their provider keys for
+	// admins and internal callers
+	deepHealth := health.NewDeep(router.UpstreamURLs)
+	app.UseMiddleware(health.Internal)
+	app.GET("/health/deep", metrics.Handle("/health/deep", func(ctx *gofr.Context) (interface{}, error) {
+		return deepHealth.Handle(ctx)
+	}))
+
+	// Upstream policies and circuit breaker states, for admins and internal
+	// callers
+	app.GET("/admin/upstreams", metrics.Handle("/admin/upstreams", func(ctx *gofr.Context) (interface{}, error) {
+		if !health.Detailed(ctx) {
+			return nil, &auth.ForbiddenError{Err: health.ErrRestricted}
 		}
+		return router.UpstreamStatus(), nil
+	}))
 
-		return result, nil
-	}
-}
+	// Composite dashboard, for the user the auth middleware verified
+	app.GET("/api/dashboard", metrics.Handle("/api/dashboard", func(ctx *gofr.Context) (interface{}, error) {
+		return aggregator.Build(ctx, dashboard.Request{UserID: auth.UserIDFrom(ctx), RequestID: tracing.RequestIDFrom(ctx), Param: ctx.Param})
+	}))
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+	// Start server
+	app.Start()
 }
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/batch"
+	"personalized-dashboard/shared/dashboard"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/graph"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/httpcache"
+	"personalized-dashboard/shared/idempotency"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/ratelimit"
+	"personalized-dashboard/shared/routes"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,95 +27,107 @@ func main() {
 		port = p
 	}
 
-	// Health check
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "api-gateway"})
-	})
+	// Trace requests across the gateway and the services
+	traceConfig, err := tracing.ConfigFromEnv("api-gateway")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Proxied routes come from the route file; SIGHUP reloads it
+	router, err := routes.NewRouter(routes.FileFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.ReloadOnSignal(context.Background())
+
+	// Cache GET responses of routes with a cache setting
+	cacheConfig, err := httpcache.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.UseCache(httpcache.New(cacheConfig))
+
+	// Verify bearer tokens and pass the user on as a trusted X-User-ID
+	authConfig, err := auth.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	verifier, err := auth.NewVerifier(authConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	verifier.AllowAnonymous(router.Public)
+
+	// Batches at /api/batch; each sub-request goes through auth, rate limits and routing
+	batchConfig, err := batch.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	batcher := batch.New(batchConfig)
 
-	// News endpoints
-	http.HandleFunc("/api/news", proxyToService("http://localhost:8001/api/news"))
-	http.HandleFunc("/api/news/trending", proxyToService("http://localhost:8001/api/news/trending"))
-	http.HandleFunc("/api/news/search", proxyToService("http://localhost:8001/api/news/search"))
+	// Throttle clients per user, API key or IP before they reach the paid upstreams
+	rateConfig, err := ratelimit.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rateStore, err := ratelimit.OpenStore(rateConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter := ratelimit.New(rateConfig, rateStore)
 
-	// Jobs endpoints
-	http.HandleFunc("/api/jobs", proxyToService("http://localhost:8002/api/jobs"))
-	http.HandleFunc("/api/jobs/trending", proxyToService("http://localhost:8002/api/jobs/trending"))
-	http.HandleFunc("/api/jobs/search", proxyToService("http://localhost:8002/api/jobs/search"))
+	// Replay stored responses to POSTs retried with the same Idempotency-Key
+	idempotencyConfig, err := idempotency.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	idempotencyStore, err := idempotency.OpenStore(idempotencyConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keeper := idempotency.New(idempotencyConfig, idempotencyStore)
 
-	// Videos endpoints
-	http.HandleFunc("/api/videos", proxyToService("http://localhost:8003/api/videos"))
-	http.HandleFunc("/api/videos/trending", proxyToService("http://localhost:8003/api/videos/trending"))
-	http.HandleFunc("/api/videos/search", proxyToService("http://localhost:8003/api/videos/search"))
+	// Dashboard sections, fetched concurrently for /api/dashboard
+	sections, err := dashboard.SectionsFromEnv(router.UpstreamURLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aggregator := dashboard.New(sections)
 
-	// Deals endpoints
-	http.HandleFunc("/api/deals", proxyToService("http://localhost:8004/api/deals"))
-	http.HandleFunc("/api/deals/trending", proxyToService("http://localhost:8004/api/deals/trending"))
-	http.HandleFunc("/api/deals/search", proxyToService("http://localhost:8004/api/deals/search"))
+	// GraphQL over the services at /graphql
+	graphConfig, err := graph.ConfigFromEnv(router.UpstreamURLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	graphServer, err := graph.New(graphConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Movies endpoints
-	http.HandleFunc("/api/movies", proxyToService("http://localhost:8008/api/movies"))
-	http.HandleFunc("/api/movies/trending", proxyToService("http://localhost:8008/api/movies/trending"))
-	http.HandleFunc("/api/movies/search", proxyToService("http://localhost:8008/api/movies/search"))
+	// Health check
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "api-gateway"})
+	})
 
-	// Food endpoints
-	http.HandleFunc("/api/food", proxyToService("http://localhost:8009/api/food"))
-	http.HandleFunc("/api/food/trending", proxyToService("http://localhost:8009/api/food/trending"))
-	http.HandleFunc("/api/food/search", proxyToService("http://localhost:8009/api/food/search"))
+	// Health and readiness of every upstream, with their provider keys for
+	// admins and internal callers
+	http.Handle("/health/deep", health.NewDeep(router.UpstreamURLs))
 
-	// Recommendation endpoints
-	http.HandleFunc("/api/recommendations", proxyToService("http://localhost:8005/api/recommendations"))
+	// Upstream policies and circuit breaker states
+	http.Handle("/admin/upstreams", health.Restricted(router.AdminHandler()))
 
-	// User endpoints
-	http.HandleFunc("/api/users", proxyToService("http://localhost:8006/api/users"))
+	// Composite dashboard
+	http.Handle("/api/dashboard", aggregator)
 
-	// NFT endpoints
-	http.HandleFunc("/api/nft/mint", proxyToService("http://localhost:8007/api/nft/mint"))
-	http.HandleFunc("/api/nft/", proxyToService("http://localhost:8007/api/nft/"))
+	// GraphQL
+	http.Handle(graph.Path, graphServer)
 
 	log.Printf("API Gateway starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
-}
-
-func proxyToService(targetURL string) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Build the full URL with query parameters
-		fullURL := targetURL
-		if len(r.URL.RawQuery) > 0 {
-			fullURL += "?" + r.URL.RawQuery
-		}
-
-		// Create HTTP request
-		req, err := http.NewRequest(r.Method, fullURL, r.Body)
-		if err != nil {
-			http.Error(w, "Failed to create request", http.StatusInternalServerError)
-			return
-		}
-
-		// Copy headers
-		for key, values := range r.Header {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
-
-		// Make request
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			http.Error(w, "Failed to make request", http.StatusInternalServerError)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Copy response headers
-		for key, values := range resp.Header {
-			for _, value := range values {
-				w.Header().Add(key, value)
-			}
-		}
-
-		// Copy response body
-		io.Copy(w, resp.Body)
-	}
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(batcher.Middleware(verifier.Middleware(limiter.Middleware(keeper.Middleware(fields.Middleware(router.Middleware(metrics.Mux(http.DefaultServeMux)))))))))))
 }
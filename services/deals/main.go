@@ -1,45 +1,93 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"gofr.dev/pkg/gofr"
 	"github.com/patrickmn/go-cache"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/ingest"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type DealsService struct {
 	amazonAPIKey  string
 	flipkartAPIKey string
 	cache         *cache.Cache
+	store         *ingest.Ingester
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("deals-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	dealsService := &DealsService{
 		amazonAPIKey:  os.Getenv("AMAZON_API_KEY"),
 		flipkartAPIKey: os.Getenv("FLIPKART_API_KEY"),
 		cache:         cache.New(10*time.Minute, 20*time.Minute),
 	}
 
+	// Serve the stored deals when a database is available
+	store, db, dbErr := ingest.Open()
+	if dbErr != nil {
+		log.Printf("Warning: content store disabled: %v", dbErr)
+	} else {
+		dealsService.store = store
+	}
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "deals"}, nil
-	})
+	}))
+
+	// Readiness check: database and cache, with pool stats, and the provider keys
+	readiness := health.NewReadiness("deals").
+		Add("database", health.OpenedDatabase(db, dbErr)).
+		Add("cache", health.Cache(dealsService.cache)).
+		Providers(
+			health.Provider{Name: "amazon", Env: []string{"AMAZON_API_KEY"}},
+			health.Provider{Name: "flipkart", Env: []string{"FLIPKART_API_KEY"}},
+		)
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// Get deals by category
-	app.GET("/api/deals", dealsService.GetDeals)
+	app.GET("/api/deals", metrics.Handle("/api/deals", dealsService.GetDeals))
 	
 	// Get trending deals
-	app.GET("/api/deals/trending", dealsService.GetTrendingDeals)
+	app.GET("/api/deals/trending", metrics.Handle("/api/deals/trending", dealsService.GetTrendingDeals))
 	
 	// Search deals
-	app.GET("/api/deals/search", dealsService.SearchDeals)
+	app.GET("/api/deals/search", metrics.Handle("/api/deals/search", dealsService.SearchDeals))
 
 	app.Run()
 }
@@ -50,8 +98,13 @@ func (ds *DealsService) GetDeals(ctx *gofr.Context) (interface{}, error) {
 		category = "electronics"
 	}
 
+	// Serve from the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return ds.storedDeals(ctx, category)
+	}
+
 	// Check cache first
-	if cached, found := ds.cache.Get("deals_" + category); found {
+	if cached, found := metrics.CacheGet(ds.cache, "deals", "deals_"+category); found {
 		return cached, nil
 	}
 
@@ -62,6 +115,7 @@ func (ds *DealsService) GetDeals(ctx *gofr.Context) (interface{}, error) {
 		"category": category,
 		"count":    len(deals),
 		"deals":    deals,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeDeal, deals),
 	}
 
 	// Cache the result
@@ -72,7 +126,7 @@ func (ds *DealsService) GetDeals(ctx *gofr.Context) (interface{}, error) {
 
 func (ds *DealsService) GetTrendingDeals(ctx *gofr.Context) (interface{}, error) {
 	// Check cache first
-	if cached, found := ds.cache.Get("trending_deals"); found {
+	if cached, found := metrics.CacheGet(ds.cache, "deals", "trending_deals"); found {
 		return cached, nil
 	}
 
@@ -88,6 +142,7 @@ func (ds *DealsService) GetTrendingDeals(ctx *gofr.Context) (interface{}, error)
 	result := map[string]interface{}{
 		"count": len(allDeals),
 		"deals": allDeals,
+		"items": models.ContentItemsFromMaps(models.ContentTypeDeal, allDeals),
 	}
 
 	// Cache the result
@@ -102,9 +157,14 @@ func (ds *DealsService) SearchDeals(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("query parameter 'q' is required")
 	}
 
+	// Search the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return ds.searchStoredDeals(ctx, query, ctx.Param("category"))
+	}
+
 	// Check cache first
 	cacheKey := "search_deals_" + query
-	if cached, found := ds.cache.Get(cacheKey); found {
+	if cached, found := metrics.CacheGet(ds.cache, "deals", cacheKey); found {
 		return cached, nil
 	}
 
@@ -114,6 +174,7 @@ func (ds *DealsService) SearchDeals(ctx *gofr.Context) (interface{}, error) {
 		"query": query,
 		"count": len(deals),
 		"deals": deals,
+		"items": models.ContentItemsFromMaps(models.ContentTypeDeal, deals),
 	}
 
 	// Cache the result
@@ -122,6 +183,10 @@ func (ds *DealsService) SearchDeals(ctx *gofr.Context) (interface{}, error) {
 	return result, nil
 }
 
+// fetchDealsFromMultipleSources returns demo deals. They are not stored in
+// the content store: their URLs are fixed per position, so storing them
+// would overwrite the same rows with other products on every request. The
+// stored corpus comes from the seed fixtures until real providers are wired.
 func (ds *DealsService) fetchDealsFromMultipleSources(category string, limit int) []map[string]interface{} {
 	deals := make([]map[string]interface{}, 0)
 	
@@ -290,19 +355,43 @@ func (ds *DealsService) searchDealsFromMultipleSources(query string, limit int)
 }
 
 func containsIgnoreCase(s, substr string) bool {
-	return len(s) >= len(substr) && 
-		   (s == substr || 
-		    len(s) > len(substr) && 
-		    (s[:len(substr)] == substr || 
-		     s[len(s)-len(substr):] == substr ||
-		     containsSubstring(s, substr)))
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
 
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+func (ds *DealsService) storedDeals(ctx context.Context, category string) (interface{}, error) {
+	if ds.store == nil {
+		return nil, fmt.Errorf("content store is not configured")
 	}
-	return false
+
+	deals, err := ds.store.StoredDeals(ctx, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored deals: %v", err)
+	}
+
+	return map[string]interface{}{
+		"category": category,
+		"count":    len(deals),
+		"deals":    deals,
+		"items":    models.ContentItems(deals),
+		"source":   "stored",
+	}, nil
+}
+
+func (ds *DealsService) searchStoredDeals(ctx context.Context, query, category string) (interface{}, error) {
+	if ds.store == nil {
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	deals, err := ds.store.SearchDeals(ctx, query, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stored deals: %v", err)
+	}
+
+	return map[string]interface{}{
+		"query":  query,
+		"count":  len(deals),
+		"deals":  deals,
+		"items":  models.ContentItems(deals),
+		"source": "stored",
+	}, nil
 }
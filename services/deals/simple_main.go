@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,17 +22,31 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("deals-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "deals"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("deals").Providers(health.Provider{Name: "amazon", Env: []string{"AMAZON_API_KEY"}}))
+
 	http.HandleFunc("/api/deals", getDeals)
 	http.HandleFunc("/api/deals/trending", getTrendingDeals)
 	http.HandleFunc("/api/deals/search", searchDeals)
 
 	log.Printf("Deals service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(fields.Middleware(metrics.Mux(http.DefaultServeMux))))))
 }
 
 func getDeals(w http.ResponseWriter, r *http.Request) {
@@ -61,7 +82,7 @@ func getDeals(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -77,7 +98,7 @@ func getDeals(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingDeals(w http.ResponseWriter, r *http.Request) {
@@ -103,7 +124,7 @@ func getTrendingDeals(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchDeals(w http.ResponseWriter, r *http.Request) {
@@ -136,5 +157,13 @@ func searchDeals(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
+}
+
+// withItems adds the shared content envelope next to the deals payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if deals, ok := result["deals"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeDeal, deals)
+	}
+	return result
 }
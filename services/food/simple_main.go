@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,12 +23,29 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("food-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "food"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("food").Providers(
+		health.Provider{Name: "edamam", Env: []string{"EDAMAM_APP_ID", "EDAMAM_APP_KEY"}},
+		health.Provider{Name: "spoonacular", Env: []string{"RECIPE_API_KEY"}},
+	))
+
 	// Get recipes by category
 	http.HandleFunc("/api/food", getRecipes)
 	
@@ -31,18 +56,18 @@ func main() {
 	http.HandleFunc("/api/food/search", searchRecipes)
 
 	log.Printf("Food service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(fields.Middleware(metrics.Mux(http.DefaultServeMux)))))))
 }
 
 func getRecipes(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
-	userID := r.URL.Query().Get("user_id")
+	userID := auth.UserIDFrom(r.Context())
 	
 	if category == "" {
 		category = "popular"
 	}
 	
-	// If user_id is provided, get user preferences
+	// For a signed-in user, get user preferences
 	if userID != "" {
 		preferences := getUserPreferences(userID)
 		if len(preferences.FoodCategories) > 0 {
@@ -74,7 +99,7 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -94,7 +119,7 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	log.Printf("Fetching real recipes from: %s", url)
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		log.Printf("Failed to fetch recipes: %v", err)
 		http.Error(w, "Failed to fetch recipes from API", http.StatusInternalServerError)
@@ -129,7 +154,7 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	for _, recipe := range recipeResp.Results {
 		// Get recipe summary
 		summaryURL := fmt.Sprintf("https://api.spoonacular.com/recipes/%d/summary?apiKey=%s", recipe.ID, apiKey)
-		summaryResp, err := http.Get(summaryURL)
+		summaryResp, err := tracing.Get(r.Context(), summaryURL)
 		var summary string
 		if err == nil && summaryResp.StatusCode == http.StatusOK {
 			var summaryData struct {
@@ -162,7 +187,7 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingRecipes(w http.ResponseWriter, r *http.Request) {
@@ -186,14 +211,14 @@ func getTrendingRecipes(w http.ResponseWriter, r *http.Request) {
 			"recipes": recipes,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
 	// Real Spoonacular trending API call
 	url := fmt.Sprintf("https://api.spoonacular.com/recipes/complexSearch?apiKey=%s&number=20&sort=popularity", apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		http.Error(w, "Failed to fetch trending recipes", http.StatusInternalServerError)
 		return
@@ -241,7 +266,7 @@ func getTrendingRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchRecipes(w http.ResponseWriter, r *http.Request) {
@@ -269,14 +294,14 @@ func searchRecipes(w http.ResponseWriter, r *http.Request) {
 			"recipes": recipes,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
 	// Real Spoonacular search API call
 	url := fmt.Sprintf("https://api.spoonacular.com/recipes/complexSearch?apiKey=%s&query=%s&number=20", apiKey, query)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		http.Error(w, "Failed to search recipes", http.StatusInternalServerError)
 		return
@@ -325,7 +350,7 @@ func searchRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 // Helper function to get user preferences
@@ -336,3 +361,11 @@ func getUserPreferences(userID string) map[string]interface{} {
 		"food_categories": []string{"popular", "healthy"},
 	}
 }
+
+// withItems adds the shared content envelope next to the recipes payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if recipes, ok := result["recipes"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeRecipe, recipes)
+	}
+	return result
+}
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
 	"time"
 
 	"gofr.dev/pkg/gofr"
 	"github.com/patrickmn/go-cache"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/ingest"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type LinkedInJobResponse struct {
@@ -43,29 +52,65 @@ type LinkedInJobResponse struct {
 type JobsService struct {
 	apiKey string
 	cache  *cache.Cache
+	store  *ingest.Ingester
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("jobs-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	jobsService := &JobsService{
 		apiKey: os.Getenv("LINKEDIN_API_KEY"),
 		cache:  cache.New(10*time.Minute, 20*time.Minute),
 	}
 
+	// Keep every fetched listing in the content store when a database is available
+	store, db, dbErr := ingest.Open()
+	if dbErr != nil {
+		log.Printf("Warning: content store disabled: %v", dbErr)
+	} else {
+		jobsService.store = store
+	}
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "jobs"}, nil
-	})
+	}))
+
+	// Readiness check: database and cache, with pool stats, and the provider keys
+	readiness := health.NewReadiness("jobs").
+		Add("database", health.OpenedDatabase(db, dbErr)).
+		Add("cache", health.Cache(jobsService.cache)).
+		Providers(health.Provider{Name: "linkedin", Env: []string{"LINKEDIN_API_KEY"}})
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// Get jobs by category
-	app.GET("/api/jobs", jobsService.GetJobs)
+	app.GET("/api/jobs", metrics.Handle("/api/jobs", jobsService.GetJobs))
 	
 	// Get trending jobs
-	app.GET("/api/jobs/trending", jobsService.GetTrendingJobs)
+	app.GET("/api/jobs/trending", metrics.Handle("/api/jobs/trending", jobsService.GetTrendingJobs))
 	
 	// Search jobs
-	app.GET("/api/jobs/search", jobsService.SearchJobs)
+	app.GET("/api/jobs/search", metrics.Handle("/api/jobs/search", jobsService.SearchJobs))
 
 	app.Run()
 }
@@ -76,8 +121,13 @@ func (js *JobsService) GetJobs(ctx *gofr.Context) (interface{}, error) {
 		category = "technology"
 	}
 
+	// Serve from the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return js.storedJobs(ctx, category, nil)
+	}
+
 	// Check cache first
-	if cached, found := js.cache.Get("jobs_" + category); found {
+	if cached, found := metrics.CacheGet(js.cache, "jobs", "jobs_"+category); found {
 		return cached, nil
 	}
 
@@ -99,12 +149,16 @@ func (js *JobsService) GetJobs(ctx *gofr.Context) (interface{}, error) {
 	}
 
 	// Use LinkedIn Jobs API (Note: This is a simplified version - real LinkedIn API requires OAuth)
-	jobs := js.fetchJobsFromLinkedIn(keyword, 20)
+	jobs, err := js.fetchJobs(ctx, category, keyword, 20)
+	if err != nil {
+		return js.storedJobs(ctx, category, fmt.Errorf("failed to fetch jobs: %v", err))
+	}
 
 	result := map[string]interface{}{
 		"category": category,
 		"count":    len(jobs),
 		"jobs":     jobs,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeJob, jobs),
 	}
 
 	// Cache the result
@@ -115,7 +169,7 @@ func (js *JobsService) GetJobs(ctx *gofr.Context) (interface{}, error) {
 
 func (js *JobsService) GetTrendingJobs(ctx *gofr.Context) (interface{}, error) {
 	// Check cache first
-	if cached, found := js.cache.Get("trending_jobs"); found {
+	if cached, found := metrics.CacheGet(js.cache, "jobs", "trending_jobs"); found {
 		return cached, nil
 	}
 
@@ -124,13 +178,18 @@ func (js *JobsService) GetTrendingJobs(ctx *gofr.Context) (interface{}, error) {
 	allJobs := make([]map[string]interface{}, 0)
 
 	for _, category := range categories {
-		jobs := js.fetchJobsFromLinkedIn(category, 5)
+		jobs, err := js.fetchJobs(ctx, category, category, 5)
+		if err != nil {
+			log.Printf("Failed to fetch %s jobs: %v", category, err)
+			continue
+		}
 		allJobs = append(allJobs, jobs...)
 	}
 
 	result := map[string]interface{}{
 		"count": len(allJobs),
 		"jobs":  allJobs,
+		"items": models.ContentItemsFromMaps(models.ContentTypeJob, allJobs),
 	}
 
 	// Cache the result
@@ -151,18 +210,27 @@ func (js *JobsService) SearchJobs(ctx *gofr.Context) (interface{}, error) {
 		limit = "20"
 	}
 
+	// Search the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return js.searchStoredJobs(ctx, query, ctx.Param("category"), nil)
+	}
+
 	// Check cache first
 	cacheKey := "search_jobs_" + query + "_" + location + "_" + limit
-	if cached, found := js.cache.Get(cacheKey); found {
+	if cached, found := metrics.CacheGet(js.cache, "jobs", cacheKey); found {
 		return cached, nil
 	}
 
-	jobs := js.fetchJobsFromLinkedIn(query+" "+location, 20)
+	jobs, err := js.fetchJobs(ctx, "", query+" "+location, 20)
+	if err != nil {
+		return js.searchStoredJobs(ctx, query, "", fmt.Errorf("failed to search jobs: %v", err))
+	}
 
 	result := map[string]interface{}{
 		"query":  query,
 		"count":  len(jobs),
 		"jobs":   jobs,
+		"items":  models.ContentItemsFromMaps(models.ContentTypeJob, jobs),
 	}
 
 	// Cache the result
@@ -171,6 +239,23 @@ func (js *JobsService) SearchJobs(ctx *gofr.Context) (interface{}, error) {
 	return result, nil
 }
 
+// fetchJobs returns LinkedIn listings when an API key is configured and the
+// demo listings otherwise. Only LinkedIn listings are stored: the demo ones
+// reuse fixed URLs with keyword-dependent titles, so storing them would
+// overwrite the same rows on every search.
+func (js *JobsService) fetchJobs(ctx context.Context, category, keyword string, limit int) ([]map[string]interface{}, error) {
+	if js.apiKey == "" {
+		return js.fetchJobsFromLinkedIn(keyword, limit), nil
+	}
+
+	jobs, err := js.fetchRealLinkedInJobs(ctx, keyword, limit)
+	if err != nil {
+		return nil, err
+	}
+	js.ingest(ctx, category, jobs)
+	return jobs, nil
+}
+
 func (js *JobsService) fetchJobsFromLinkedIn(keyword string, limit int) []map[string]interface{} {
 	// Note: This is a mock implementation since LinkedIn API requires OAuth
 	// In a real implementation, you would use the LinkedIn Jobs API with proper authentication
@@ -221,13 +306,13 @@ func (js *JobsService) fetchJobsFromLinkedIn(keyword string, limit int) []map[st
 }
 
 // Real LinkedIn API integration would look like this:
-func (js *JobsService) fetchRealLinkedInJobs(keyword string, limit int) ([]map[string]interface{}, error) {
+func (js *JobsService) fetchRealLinkedInJobs(ctx context.Context, keyword string, limit int) ([]map[string]interface{}, error) {
 	// This would be the actual LinkedIn API call
 	// Note: LinkedIn API requires OAuth 2.0 authentication
 	
-	url := fmt.Sprintf("https://api.linkedin.com/v2/jobSearch?keywords=%s&count=%d", keyword, limit)
+	url := fmt.Sprintf("https://api.linkedin.com/v2/jobSearch?keywords=%s&count=%d", neturl.QueryEscape(keyword), limit)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -235,8 +320,7 @@ func (js *JobsService) fetchRealLinkedInJobs(keyword string, limit int) ([]map[s
 	req.Header.Set("Authorization", "Bearer "+js.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := tracing.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -274,3 +358,79 @@ func (js *JobsService) fetchRealLinkedInJobs(keyword string, limit int) ([]map[s
 	
 	return jobs, nil
 }
+
+// ingest upserts fetched listings into the content store in the background,
+// so the live response does not wait for the database. Failures are only
+// logged.
+func (js *JobsService) ingest(ctx context.Context, category string, jobs []map[string]interface{}) {
+	if js.store == nil {
+		return
+	}
+
+	ctx, cancel := ingest.Detach(ctx)
+	go func() {
+		defer cancel()
+		result, err := js.store.Jobs(ctx, category, jobs)
+		if err != nil {
+			log.Printf("Failed to store jobs: %v", err)
+			return
+		}
+		log.Printf("Stored jobs (%s): %s", category, result)
+	}()
+}
+
+// storedJobs serves a category from the content store. cause is the upstream
+// error that triggered the fallback and is returned when there is no store.
+func (js *JobsService) storedJobs(ctx context.Context, category string, cause error) (interface{}, error) {
+	if js.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Serving stored %s jobs: %v", category, cause)
+	}
+
+	jobs, err := js.store.StoredJobs(ctx, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored jobs: %v", err)
+	}
+
+	return map[string]interface{}{
+		"category": category,
+		"count":    len(jobs),
+		"jobs":     jobs,
+		"items":    models.ContentItems(jobs),
+		"source":   "stored",
+	}, nil
+}
+
+// searchStoredJobs runs the query against the content store, which keeps
+// search working when LinkedIn is unreachable or rate-limited.
+func (js *JobsService) searchStoredJobs(ctx context.Context, query, category string, cause error) (interface{}, error) {
+	if js.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Searching stored jobs for %q: %v", query, cause)
+	}
+
+	jobs, err := js.store.SearchJobs(ctx, query, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stored jobs: %v", err)
+	}
+
+	return map[string]interface{}{
+		"query":  query,
+		"count":  len(jobs),
+		"jobs":   jobs,
+		"items":  models.ContentItems(jobs),
+		"source": "stored",
+	}, nil
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,17 +22,31 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("jobs-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "jobs"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("jobs").Providers(health.Provider{Name: "adzuna", Env: []string{"ADZUNA_APP_ID", "ADZUNA_APP_KEY"}}))
+
 	http.HandleFunc("/api/jobs", getJobs)
 	http.HandleFunc("/api/jobs/trending", getTrendingJobs)
 	http.HandleFunc("/api/jobs/search", searchJobs)
 
 	log.Printf("Jobs service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(fields.Middleware(metrics.Mux(http.DefaultServeMux))))))
 }
 
 func getJobs(w http.ResponseWriter, r *http.Request) {
@@ -61,7 +82,7 @@ func getJobs(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -69,7 +90,7 @@ func getJobs(w http.ResponseWriter, r *http.Request) {
 	url := fmt.Sprintf("https://api.adzuna.com/v1/api/jobs/us/search/1?app_id=%s&app_key=%s&what=%s&results_per_page=20", appID, appKey, category)
 	
 	log.Printf("Fetching real jobs from: %s", url)
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		log.Printf("Failed to fetch jobs: %v", err)
 		http.Error(w, "Failed to fetch jobs from API", http.StatusInternalServerError)
@@ -117,7 +138,7 @@ func getJobs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingJobs(w http.ResponseWriter, r *http.Request) {
@@ -141,7 +162,7 @@ func getTrendingJobs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchJobs(w http.ResponseWriter, r *http.Request) {
@@ -172,5 +193,13 @@ func searchJobs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
+}
+
+// withItems adds the shared content envelope next to the jobs payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if jobs, ok := result["jobs"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeJob, jobs)
+	}
+	return result
 }
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,12 +23,29 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("movies-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "movies"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("movies").Providers(
+		health.Provider{Name: "omdb", Env: []string{"OMDB_API_KEY"}},
+		health.Provider{Name: "tmdb", Env: []string{"TMDB_API_KEY"}},
+	))
+
 	// Get movies by category
 	http.HandleFunc("/api/movies", getMovies)
 	
@@ -31,18 +56,18 @@ func main() {
 	http.HandleFunc("/api/movies/search", searchMovies)
 
 	log.Printf("Movies service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(fields.Middleware(metrics.Mux(http.DefaultServeMux)))))))
 }
 
 func getMovies(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
-	userID := r.URL.Query().Get("user_id")
+	userID := auth.UserIDFrom(r.Context())
 	
 	if category == "" {
 		category = "popular"
 	}
 	
-	// If user_id is provided, get user preferences
+	// For a signed-in user, get user preferences
 	if userID != "" {
 		preferences := getUserPreferences(userID)
 		if len(preferences.MovieGenres) > 0 {
@@ -74,7 +99,7 @@ func getMovies(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -94,7 +119,7 @@ func getMovies(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	log.Printf("Fetching real movies from: %s", url)
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		log.Printf("Failed to fetch movies: %v", err)
 		http.Error(w, "Failed to fetch movies from API", http.StatusInternalServerError)
@@ -151,7 +176,7 @@ func getMovies(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorResponse)
+		json.NewEncoder(w).Encode(withItems(errorResponse))
 		return
 	}
 
@@ -186,7 +211,7 @@ func getMovies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingMovies(w http.ResponseWriter, r *http.Request) {
@@ -209,14 +234,14 @@ func getTrendingMovies(w http.ResponseWriter, r *http.Request) {
 			"movies": movies,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
 	// Real TMDB trending API call
 	url := fmt.Sprintf("https://api.themoviedb.org/3/trending/movie/week?api_key=%s", apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		http.Error(w, "Failed to fetch trending movies", http.StatusInternalServerError)
 		return
@@ -269,7 +294,7 @@ func getTrendingMovies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchMovies(w http.ResponseWriter, r *http.Request) {
@@ -297,14 +322,14 @@ func searchMovies(w http.ResponseWriter, r *http.Request) {
 			"movies": movies,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
 	// Real TMDB search API call
 	url := fmt.Sprintf("https://api.themoviedb.org/3/search/movie?api_key=%s&query=%s&page=1", apiKey, query)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		http.Error(w, "Failed to search movies", http.StatusInternalServerError)
 		return
@@ -358,7 +383,7 @@ func searchMovies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 // Helper function to get user preferences
@@ -369,3 +394,11 @@ func getUserPreferences(userID string) map[string]interface{} {
 		"movie_genres": []string{"popular", "top_rated"},
 	}
 }
+
+// withItems adds the shared content envelope next to the movies payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if movies, ok := result["movies"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeMovie, movies)
+	}
+	return result
+}
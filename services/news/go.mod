@@ -1,10 +1,11 @@
 module news-service
 
-go 1.20
+go 1.23.0
 
 require (
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	gofr.dev v1.44.1
+	personalized-dashboard v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -101,3 +102,5 @@ require (
 	modernc.org/memory v1.11.0 // indirect
 	modernc.org/sqlite v1.38.2 // indirect
 )
+
+replace personalized-dashboard => ../..
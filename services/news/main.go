@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,6 +11,13 @@ import (
 
 	"gofr.dev/pkg/gofr"
 	"github.com/patrickmn/go-cache"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/ingest"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type NewsAPIResponse struct {
@@ -33,29 +41,65 @@ type NewsAPIResponse struct {
 type NewsService struct {
 	apiKey string
 	cache  *cache.Cache
+	store  *ingest.Ingester
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("news-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	newsService := &NewsService{
 		apiKey: os.Getenv("NEWS_API_KEY"),
 		cache:  cache.New(5*time.Minute, 10*time.Minute),
 	}
 
+	// Keep every fetched article in the content store when a database is available
+	store, db, dbErr := ingest.Open()
+	if dbErr != nil {
+		log.Printf("Warning: content store disabled: %v", dbErr)
+	} else {
+		newsService.store = store
+	}
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "news"}, nil
-	})
+	}))
+
+	// Readiness check: database and cache, with pool stats, and the provider keys
+	readiness := health.NewReadiness("news").
+		Add("database", health.OpenedDatabase(db, dbErr)).
+		Add("cache", health.Cache(newsService.cache)).
+		Providers(health.Provider{Name: "newsapi", Env: []string{"NEWS_API_KEY"}})
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// Get news by category
-	app.GET("/api/news", newsService.GetNews)
+	app.GET("/api/news", metrics.Handle("/api/news", newsService.GetNews))
 	
 	// Get trending news
-	app.GET("/api/news/trending", newsService.GetTrendingNews)
+	app.GET("/api/news/trending", metrics.Handle("/api/news/trending", newsService.GetTrendingNews))
 	
 	// Get news by query
-	app.GET("/api/news/search", newsService.SearchNews)
+	app.GET("/api/news/search", metrics.Handle("/api/news/search", newsService.SearchNews))
 
 	app.Run()
 }
@@ -66,26 +110,31 @@ func (ns *NewsService) GetNews(ctx *gofr.Context) (interface{}, error) {
 		category = "general"
 	}
 
+	// Serve from the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return ns.storedNews(ctx, category, nil)
+	}
+
 	// Check cache first
-	if cached, found := ns.cache.Get("news_" + category); found {
+	if cached, found := metrics.CacheGet(ns.cache, "news", "news_"+category); found {
 		return cached, nil
 	}
 
 	url := fmt.Sprintf("https://newsapi.org/v2/top-headlines?category=%s&apiKey=%s", category, ns.apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch news: %v", err)
+		return ns.storedNews(ctx, category, fmt.Errorf("failed to fetch news: %v", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("news API returned status: %d", resp.StatusCode)
+		return ns.storedNews(ctx, category, fmt.Errorf("news API returned status: %d", resp.StatusCode))
 	}
 
 	var newsResp NewsAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&newsResp); err != nil {
-		return nil, fmt.Errorf("failed to decode news response: %v", err)
+		return ns.storedNews(ctx, category, fmt.Errorf("failed to decode news response: %v", err))
 	}
 
 	// Transform to our format
@@ -103,10 +152,13 @@ func (ns *NewsService) GetNews(ctx *gofr.Context) (interface{}, error) {
 		})
 	}
 
+	ns.ingest(ctx, category, articles)
+
 	result := map[string]interface{}{
 		"category": category,
 		"count":    len(articles),
 		"articles": articles,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeNews, articles),
 	}
 
 	// Cache the result
@@ -117,7 +169,7 @@ func (ns *NewsService) GetNews(ctx *gofr.Context) (interface{}, error) {
 
 func (ns *NewsService) GetTrendingNews(ctx *gofr.Context) (interface{}, error) {
 	// Check cache first
-	if cached, found := ns.cache.Get("trending_news"); found {
+	if cached, found := metrics.CacheGet(ns.cache, "news", "trending_news"); found {
 		return cached, nil
 	}
 
@@ -128,7 +180,7 @@ func (ns *NewsService) GetTrendingNews(ctx *gofr.Context) (interface{}, error) {
 	for _, category := range categories {
 		url := fmt.Sprintf("https://newsapi.org/v2/top-headlines?category=%s&pageSize=5&apiKey=%s", category, ns.apiKey)
 		
-		resp, err := http.Get(url)
+		resp, err := tracing.Get(ctx, url)
 		if err != nil {
 			log.Printf("Failed to fetch %s news: %v", category, err)
 			continue
@@ -160,9 +212,12 @@ func (ns *NewsService) GetTrendingNews(ctx *gofr.Context) (interface{}, error) {
 		}
 	}
 
+	ns.ingest(ctx, "", allArticles)
+
 	result := map[string]interface{}{
 		"count":    len(allArticles),
 		"articles": allArticles,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeNews, allArticles),
 	}
 
 	// Cache the result
@@ -182,28 +237,33 @@ func (ns *NewsService) SearchNews(ctx *gofr.Context) (interface{}, error) {
 		pageSize = "20"
 	}
 
+	// Search the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return ns.searchStoredNews(ctx, query, ctx.Param("category"), nil)
+	}
+
 	// Check cache first
 	cacheKey := "search_" + query + "_" + pageSize
-	if cached, found := ns.cache.Get(cacheKey); found {
+	if cached, found := metrics.CacheGet(ns.cache, "news", cacheKey); found {
 		return cached, nil
 	}
 
 	url := fmt.Sprintf("https://newsapi.org/v2/everything?q=%s&pageSize=%s&sortBy=publishedAt&apiKey=%s", 
 		query, pageSize, ns.apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search news: %v", err)
+		return ns.searchStoredNews(ctx, query, "", fmt.Errorf("failed to search news: %v", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("news API returned status: %d", resp.StatusCode)
+		return ns.searchStoredNews(ctx, query, "", fmt.Errorf("news API returned status: %d", resp.StatusCode))
 	}
 
 	var newsResp NewsAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&newsResp); err != nil {
-		return nil, fmt.Errorf("failed to decode search response: %v", err)
+		return ns.searchStoredNews(ctx, query, "", fmt.Errorf("failed to decode search response: %v", err))
 	}
 
 	// Transform to our format
@@ -221,10 +281,13 @@ func (ns *NewsService) SearchNews(ctx *gofr.Context) (interface{}, error) {
 		})
 	}
 
+	ns.ingest(ctx, "", articles)
+
 	result := map[string]interface{}{
 		"query":    query,
 		"count":    len(articles),
 		"articles": articles,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeNews, articles),
 	}
 
 	// Cache the result
@@ -232,3 +295,79 @@ func (ns *NewsService) SearchNews(ctx *gofr.Context) (interface{}, error) {
 
 	return result, nil
 }
+
+// ingest upserts fetched articles into the content store in the background,
+// so the live response does not wait for the database. Failures are only
+// logged.
+func (ns *NewsService) ingest(ctx context.Context, category string, articles []map[string]interface{}) {
+	if ns.store == nil {
+		return
+	}
+
+	ctx, cancel := ingest.Detach(ctx)
+	go func() {
+		defer cancel()
+		result, err := ns.store.News(ctx, category, articles)
+		if err != nil {
+			log.Printf("Failed to store news: %v", err)
+			return
+		}
+		log.Printf("Stored news (%s): %s", category, result)
+	}()
+}
+
+// storedNews serves a category from the content store. cause is the upstream
+// error that triggered the fallback and is returned when there is no store.
+func (ns *NewsService) storedNews(ctx context.Context, category string, cause error) (interface{}, error) {
+	if ns.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Serving stored %s news: %v", category, cause)
+	}
+
+	articles, err := ns.store.StoredNews(ctx, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored news: %v", err)
+	}
+
+	return map[string]interface{}{
+		"category": category,
+		"count":    len(articles),
+		"articles": articles,
+		"items":    models.ContentItems(articles),
+		"source":   "stored",
+	}, nil
+}
+
+// searchStoredNews runs the query against the content store, which keeps
+// search working when the NewsAPI key is missing or rate-limited.
+func (ns *NewsService) searchStoredNews(ctx context.Context, query, category string, cause error) (interface{}, error) {
+	if ns.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Searching stored news for %q: %v", query, cause)
+	}
+
+	articles, err := ns.store.SearchNews(ctx, query, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stored news: %v", err)
+	}
+
+	return map[string]interface{}{
+		"query":    query,
+		"count":    len(articles),
+		"articles": articles,
+		"items":    models.ContentItems(articles),
+		"source":   "stored",
+	}, nil
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type NewsAPIResponse struct {
@@ -33,12 +41,26 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("news-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "news"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("news").Providers(health.Provider{Name: "newsapi", Env: []string{"NEWS_API_KEY"}}))
+
 	// Get news by category
 	http.HandleFunc("/api/news", getNews)
 	
@@ -49,18 +71,18 @@ func main() {
 	http.HandleFunc("/api/news/search", searchNews)
 
 	log.Printf("News service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(fields.Middleware(metrics.Mux(http.DefaultServeMux)))))))
 }
 
 func getNews(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
-	userID := r.URL.Query().Get("user_id")
+	userID := auth.UserIDFrom(r.Context())
 	
 	if category == "" {
 		category = "general"
 	}
 	
-	// If user_id is provided, get user preferences
+	// For a signed-in user, get user preferences
 	if userID != "" {
 		preferences := getUserPreferences(userID)
 		if len(preferences.NewsCategories) > 0 {
@@ -94,7 +116,7 @@ func getNews(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -102,7 +124,7 @@ func getNews(w http.ResponseWriter, r *http.Request) {
 	url := fmt.Sprintf("https://newsapi.org/v2/top-headlines?category=%s&apiKey=%s&pageSize=20", category, apiKey)
 	
 	log.Printf("Fetching real news from: %s", url)
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		log.Printf("Failed to fetch news: %v", err)
 		http.Error(w, "Failed to fetch news from API", http.StatusInternalServerError)
@@ -163,7 +185,7 @@ func getNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingNews(w http.ResponseWriter, r *http.Request) {
@@ -187,7 +209,7 @@ func getTrendingNews(w http.ResponseWriter, r *http.Request) {
 			"articles": articles,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
@@ -198,7 +220,7 @@ func getTrendingNews(w http.ResponseWriter, r *http.Request) {
 	for _, category := range categories {
 		url := fmt.Sprintf("https://newsapi.org/v2/top-headlines?category=%s&pageSize=5&apiKey=%s", category, apiKey)
 		
-		resp, err := http.Get(url)
+		resp, err := tracing.Get(r.Context(), url)
 		if err != nil {
 			log.Printf("Failed to fetch %s news: %v", category, err)
 			continue
@@ -248,7 +270,7 @@ func getTrendingNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchNews(w http.ResponseWriter, r *http.Request) {
@@ -279,14 +301,14 @@ func searchNews(w http.ResponseWriter, r *http.Request) {
 			"articles": articles,
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(withItems(result))
 		return
 	}
 
 	// Real NewsAPI search
 	url := fmt.Sprintf("https://newsapi.org/v2/everything?q=%s&pageSize=20&sortBy=publishedAt&apiKey=%s", query, apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(r.Context(), url)
 	if err != nil {
 		http.Error(w, "Failed to search news", http.StatusInternalServerError)
 		return
@@ -338,7 +360,7 @@ func searchNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 // Helper function to get user preferences
@@ -349,3 +371,11 @@ func getUserPreferences(userID string) map[string]interface{} {
 		"news_categories": []string{"technology", "business"},
 	}
 }
+
+// withItems adds the shared content envelope next to the articles payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if articles, ok := result["articles"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeNews, articles)
+	}
+	return result
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"gofr.dev/pkg/gofr"
 	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/audit"
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
 )
 
 type VerbwireResponse struct {
@@ -22,24 +31,64 @@ type VerbwireResponse struct {
 
 type NFTService struct {
 	verbwireAPIKey string
+	audit          *audit.Logger
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("nft-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	nftService := &NFTService{
 		verbwireAPIKey: os.Getenv("VERBWIRE_API_KEY"),
 	}
 
+	auditLog, db, dbErr := audit.Open()
+	if dbErr != nil {
+		log.Printf("Warning: audit log disabled: %v", dbErr)
+	} else {
+		nftService.audit = auditLog
+	}
+
+	// Take the user and roles from the headers the gateway sets
+	app.UseMiddleware(auth.Identity)
+
+	// Record the actor and request ID of every request for the audit log
+	app.UseMiddleware(audit.Middleware)
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "nft"}, nil
-	})
+	}))
+
+	// Readiness check: the audit log database, and the Verbwire key
+	readiness := health.NewReadiness("nft").
+		Add("database", health.OpenedDatabase(db, dbErr)).
+		Providers(health.Provider{Name: "verbwire", Env: []string{"VERBWIRE_API_KEY"}})
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// NFT endpoints
-	app.POST("/api/nft/mint", nftService.MintCoupon)
-	app.GET("/api/nft/:user_id", nftService.GetUserNFTs)
-	app.POST("/api/nft/:id/claim", nftService.ClaimNFT)
+	app.POST("/api/nft/mint", metrics.Handle("/api/nft/mint", nftService.MintCoupon))
+	app.GET("/api/nft/:user_id", metrics.Handle("/api/nft/:user_id", nftService.GetUserNFTs))
+	app.POST("/api/nft/:id/claim", metrics.Handle("/api/nft/:id/claim", nftService.ClaimNFT))
 
 	app.Run()
 }
@@ -50,9 +99,19 @@ func (ns *NFTService) MintCoupon(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("invalid request data: %v", err)
 	}
 
-	userID := mintData["user_id"].(string)
-	category := mintData["category"].(string)
-	discount := mintData["discount"].(float64)
+	// Coupons are minted for the caller; only support staff and admins may
+	// name another user_id
+	target, _ := mintData["user_id"].(string)
+	userID, err := auth.ActingUser(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	category, _ := mintData["category"].(string)
+	discount, ok := mintData["discount"].(float64)
+	if category == "" || !ok {
+		return nil, fmt.Errorf("category and discount are required")
+	}
 
 	// Call Verbwire API to mint NFT
 	nftData, err := ns.callVerbwireAPI(userID, category, discount)
@@ -60,8 +119,9 @@ func (ns *NFTService) MintCoupon(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("failed to mint NFT: %v", err)
 	}
 
+	couponID := uuid.New().String()
 	coupon := map[string]interface{}{
-		"id":               uuid.New().String(),
+		"id":               couponID,
 		"user_id":          userID,
 		"token_id":         nftData.Data.TokenID,
 		"contract_address": nftData.Data.ContractAddress,
@@ -74,11 +134,24 @@ func (ns *NFTService) MintCoupon(ctx *gofr.Context) (interface{}, error) {
 		"expires_at":       time.Now().Add(30 * 24 * time.Hour),
 	}
 
+	if err := ns.audit.Record(ctx, audit.Event{
+		Action:     "coupon.mint",
+		TargetType: "nft_coupon",
+		TargetID:   couponID,
+		UserID:     userID,
+		After:      coupon,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
 	return coupon, nil
 }
 
 func (ns *NFTService) GetUserNFTs(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("user_id")
+	userID, err := auth.ActingUser(ctx, ctx.Param("user_id"))
+	if err != nil {
+		return nil, err
+	}
 
 	// Mock NFT data
 	nfts := []map[string]interface{}{
@@ -121,6 +194,12 @@ func (ns *NFTService) GetUserNFTs(ctx *gofr.Context) (interface{}, error) {
 func (ns *NFTService) ClaimNFT(ctx *gofr.Context) (interface{}, error) {
 	nftID := ctx.Param("id")
 
+	// Coupons are claimed by their owner, so the actor is the user
+	claimant, err := auth.ActingUser(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
 	// Mock claim process
 	claim := map[string]interface{}{
 		"id":         nftID,
@@ -129,6 +208,16 @@ func (ns *NFTService) ClaimNFT(ctx *gofr.Context) (interface{}, error) {
 		"message":    "NFT coupon claimed successfully!",
 	}
 
+	if err := ns.audit.Record(ctx, audit.Event{
+		Action:     "coupon.claim",
+		TargetType: "nft_coupon",
+		TargetID:   nftID,
+		UserID:     claimant,
+		After:      map[string]interface{}{"status": claim["status"], "claimed_at": claim["claimed_at"]},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
 	return claim, nil
 }
 
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,17 +23,31 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("nft-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "nft"})
 	})
 
+	// Readiness
+	http.Handle("/ready", health.NewReadiness("nft"))
+
 	http.HandleFunc("/api/nft/mint", mintCoupon)
 	http.HandleFunc("/api/nft/", getUserNFTs)
 	http.HandleFunc("/api/nft/claim", claimNFT)
 
 	log.Printf("NFT service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(fields.Middleware(metrics.Mux(http.DefaultServeMux)))))))
 }
 
 func mintCoupon(w http.ResponseWriter, r *http.Request) {
@@ -40,9 +62,20 @@ func mintCoupon(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := mintData["user_id"].(string)
-	category := mintData["category"].(string)
-	discount := mintData["discount"].(float64)
+	// Coupons are minted for the caller; only support staff and admins may
+	// name another user_id
+	target, _ := mintData["user_id"].(string)
+	userID, ok := auth.Authorize(w, r, target)
+	if !ok {
+		return
+	}
+
+	category, _ := mintData["category"].(string)
+	discount, ok := mintData["discount"].(float64)
+	if category == "" || !ok {
+		http.Error(w, "Category and discount are required", http.StatusBadRequest)
+		return
+	}
 
 	coupon := map[string]interface{}{
 		"id":               fmt.Sprintf("nft_%d", time.Now().UnixNano()),
@@ -63,7 +96,10 @@ func mintCoupon(w http.ResponseWriter, r *http.Request) {
 }
 
 func getUserNFTs(w http.ResponseWriter, r *http.Request) {
-	userID := "demo_user"
+	userID, ok := auth.Authorize(w, r, strings.TrimPrefix(r.URL.Path, "/api/nft/"))
+	if !ok {
+		return
+	}
 
 	nfts := []map[string]interface{}{
 		{
@@ -117,7 +153,16 @@ func claimNFT(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nftID := claimData["id"].(string)
+	nftID, _ := claimData["id"].(string)
+	if nftID == "" {
+		http.Error(w, "NFT ID required", http.StatusBadRequest)
+		return
+	}
+
+	// Coupons are claimed by their owner
+	if _, ok := auth.Authorize(w, r, ""); !ok {
+		return
+	}
 
 	claim := map[string]interface{}{
 		"id":         nftID,
@@ -1,8 +1,11 @@
 module recommendation-service
 
-go 1.20
+go 1.23.0
 
 require (
 	gofr.dev v1.44.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	personalized-dashboard v0.0.0-00010101000000-000000000000
 )
+
+replace personalized-dashboard => ../..
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,6 +11,13 @@ import (
 
 	"gofr.dev/pkg/gofr"
 	"github.com/patrickmn/go-cache"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type WolframResponse struct {
@@ -24,6 +32,15 @@ type WolframResponse struct {
 	} `json:"queryresult"`
 }
 
+// Recommendation is a content item ranked for a user. ContentType is the
+// service the item came from.
+type Recommendation struct {
+	models.ContentItem
+	ContentType         string  `json:"content_type"`
+	RecommendationScore float64 `json:"recommendation_score"`
+	Reason              string  `json:"reason"`
+}
+
 type RecommendationService struct {
 	wolframAPIKey string
 	cache         *cache.Cache
@@ -32,33 +49,60 @@ type RecommendationService struct {
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("recommendation-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	recommendationService := &RecommendationService{
 		wolframAPIKey: os.Getenv("WOLFRAM_API_KEY"),
 		cache:         cache.New(15*time.Minute, 30*time.Minute),
 	}
 
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "recommendation"}, nil
-	})
+	}))
+
+	// Readiness check: the cache (this service has no database yet), and the Wolfram key
+	readiness := health.NewReadiness("recommendation").
+		Add("database", health.Database(nil)).
+		Add("cache", health.Cache(recommendationService.cache)).
+		Providers(health.Provider{Name: "wolfram", Env: []string{"WOLFRAM_API_KEY"}})
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
+
+	// Take the user from the X-User-ID the gateway sets
+	app.UseMiddleware(auth.Identity)
 
 	// Get personalized recommendations
-	app.GET("/api/recommendations", recommendationService.GetRecommendations)
+	app.GET("/api/recommendations", metrics.Handle("/api/recommendations", recommendationService.GetRecommendations))
 	
 	// Get recommendations by category
-	app.GET("/api/recommendations/:category", recommendationService.GetRecommendationsByCategory)
+	app.GET("/api/recommendations/:category", metrics.Handle("/api/recommendations/:category", recommendationService.GetRecommendationsByCategory))
 
 	app.Run()
 }
 
 func (rs *RecommendationService) GetRecommendations(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("user_id")
-	if userID == "" {
-		userID = "default_user"
-	}
+	userID := rs.userID(ctx)
 
 	// Check cache first
-	if cached, found := rs.cache.Get("recommendations_" + userID); found {
+	if cached, found := metrics.CacheGet(rs.cache, "recommendation", "recommendations_"+userID); found {
 		return cached, nil
 	}
 
@@ -66,12 +110,12 @@ func (rs *RecommendationService) GetRecommendations(ctx *gofr.Context) (interfac
 	userProfile := rs.getUserProfile(userID)
 	
 	// Fetch content from all services
-	content := rs.fetchAllContent()
+	content := rs.fetchAllContent(ctx)
 	
 	// Use Wolfram to generate personalized recommendations
-	recommendations, err := rs.generateRecommendationsWithWolfram(userProfile, content)
+	recommendations, err := rs.generateRecommendationsWithWolfram(ctx, userProfile, content)
 	if err != nil {
-		log.Printf("Wolfram API failed, using fallback: %v", err)
+		tracing.Logf(ctx, "Wolfram API failed, using fallback: %v", err)
 		recommendations = rs.generateFallbackRecommendations(userProfile, content)
 	}
 
@@ -89,10 +133,7 @@ func (rs *RecommendationService) GetRecommendations(ctx *gofr.Context) (interfac
 }
 
 func (rs *RecommendationService) GetRecommendationsByCategory(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("user_id")
-	if userID == "" {
-		userID = "default_user"
-	}
+	userID := rs.userID(ctx)
 	
 	category := ctx.Param("category")
 	if category == "" {
@@ -101,7 +142,7 @@ func (rs *RecommendationService) GetRecommendationsByCategory(ctx *gofr.Context)
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("recommendations_%s_%s", userID, category)
-	if cached, found := rs.cache.Get(cacheKey); found {
+	if cached, found := metrics.CacheGet(rs.cache, "recommendation", cacheKey); found {
 		return cached, nil
 	}
 
@@ -109,12 +150,12 @@ func (rs *RecommendationService) GetRecommendationsByCategory(ctx *gofr.Context)
 	userProfile := rs.getUserProfile(userID)
 	
 	// Fetch content for specific category
-	content := rs.fetchContentByCategory(category)
+	content := rs.fetchContentByCategory(ctx, category)
 	
 	// Generate recommendations
-	recommendations, err := rs.generateRecommendationsWithWolfram(userProfile, content)
+	recommendations, err := rs.generateRecommendationsWithWolfram(ctx, userProfile, content)
 	if err != nil {
-		log.Printf("Wolfram API failed, using fallback: %v", err)
+		tracing.Logf(ctx, "Wolfram API failed, using fallback: %v", err)
 		recommendations = rs.generateFallbackRecommendations(userProfile, content)
 	}
 
@@ -132,6 +173,12 @@ func (rs *RecommendationService) GetRecommendationsByCategory(ctx *gofr.Context)
 	return result, nil
 }
 
+// userID is the authenticated user set by the gateway, or "" for anonymous
+// requests, which get recommendations for the default profile.
+func (rs *RecommendationService) userID(ctx *gofr.Context) string {
+	return auth.UserIDFrom(ctx)
+}
+
 func (rs *RecommendationService) getUserProfile(userID string) map[string]interface{} {
 	// In a real implementation, this would fetch from the database
 	// For demo purposes, we'll return a mock profile
@@ -149,30 +196,30 @@ func (rs *RecommendationService) getUserProfile(userID string) map[string]interf
 	}
 }
 
-func (rs *RecommendationService) fetchAllContent() map[string][]map[string]interface{} {
-	content := make(map[string][]map[string]interface{})
+func (rs *RecommendationService) fetchAllContent(ctx context.Context) map[string][]models.ContentItem {
+	content := make(map[string][]models.ContentItem)
 	
 	// Fetch from news service
-	newsContent := rs.fetchFromService("http://news-service:8000/api/news/trending", "news")
+	newsContent := rs.fetchFromService(ctx, "http://news-service:8000/api/news/trending")
 	content["news"] = newsContent
 	
 	// Fetch from jobs service
-	jobsContent := rs.fetchFromService("http://jobs-service:8000/api/jobs/trending", "jobs")
+	jobsContent := rs.fetchFromService(ctx, "http://jobs-service:8000/api/jobs/trending")
 	content["jobs"] = jobsContent
 	
 	// Fetch from videos service
-	videosContent := rs.fetchFromService("http://videos-service:8000/api/videos/trending", "videos")
+	videosContent := rs.fetchFromService(ctx, "http://videos-service:8000/api/videos/trending")
 	content["videos"] = videosContent
 	
 	// Fetch from deals service
-	dealsContent := rs.fetchFromService("http://deals-service:8000/api/deals/trending", "deals")
+	dealsContent := rs.fetchFromService(ctx, "http://deals-service:8000/api/deals/trending")
 	content["deals"] = dealsContent
 	
 	return content
 }
 
-func (rs *RecommendationService) fetchContentByCategory(category string) map[string][]map[string]interface{} {
-	content := make(map[string][]map[string]interface{})
+func (rs *RecommendationService) fetchContentByCategory(ctx context.Context, category string) map[string][]models.ContentItem {
+	content := make(map[string][]models.ContentItem)
 	
 	// Fetch from all services for the specific category
 	services := map[string]string{
@@ -183,73 +230,39 @@ func (rs *RecommendationService) fetchContentByCategory(category string) map[str
 	}
 	
 	for serviceType, url := range services {
-		serviceContent := rs.fetchFromService(url, serviceType)
+		serviceContent := rs.fetchFromService(ctx, url)
 		content[serviceType] = serviceContent
 	}
 	
 	return content
 }
 
-func (rs *RecommendationService) fetchFromService(url, contentType string) []map[string]interface{} {
-	resp, err := http.Get(url)
+func (rs *RecommendationService) fetchFromService(ctx context.Context, url string) []models.ContentItem {
+	resp, err := tracing.Get(ctx, url)
 	if err != nil {
-		log.Printf("Failed to fetch from %s: %v", url, err)
-		return []map[string]interface{}{}
+		tracing.Logf(ctx, "Failed to fetch from %s: %v", url, err)
+		return []models.ContentItem{}
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Service %s returned status: %d", url, resp.StatusCode)
-		return []map[string]interface{}{}
+		tracing.Logf(ctx, "Service %s returned status: %d", url, resp.StatusCode)
+		return []models.ContentItem{}
 	}
 	
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Printf("Failed to decode response from %s: %v", url, err)
-		return []map[string]interface{}{}
+	// Every content service returns its items in the shared envelope
+	var result struct {
+		Items []models.ContentItem `json:"items"`
 	}
-	
-	// Extract the content array based on service type
-	var contentArray []map[string]interface{}
-	switch contentType {
-	case "news":
-		if articles, ok := result["articles"].([]interface{}); ok {
-			for _, article := range articles {
-				if articleMap, ok := article.(map[string]interface{}); ok {
-					contentArray = append(contentArray, articleMap)
-				}
-			}
-		}
-	case "jobs":
-		if jobs, ok := result["jobs"].([]interface{}); ok {
-			for _, job := range jobs {
-				if jobMap, ok := job.(map[string]interface{}); ok {
-					contentArray = append(contentArray, jobMap)
-				}
-			}
-		}
-	case "videos":
-		if videos, ok := result["videos"].([]interface{}); ok {
-			for _, video := range videos {
-				if videoMap, ok := video.(map[string]interface{}); ok {
-					contentArray = append(contentArray, videoMap)
-				}
-			}
-		}
-	case "deals":
-		if deals, ok := result["deals"].([]interface{}); ok {
-			for _, deal := range deals {
-				if dealMap, ok := deal.(map[string]interface{}); ok {
-					contentArray = append(contentArray, dealMap)
-				}
-			}
-		}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		tracing.Logf(ctx, "Failed to decode response from %s: %v", url, err)
+		return []models.ContentItem{}
 	}
 	
-	return contentArray
+	return result.Items
 }
 
-func (rs *RecommendationService) generateRecommendationsWithWolfram(userProfile map[string]interface{}, content map[string][]map[string]interface{}) ([]map[string]interface{}, error) {
+func (rs *RecommendationService) generateRecommendationsWithWolfram(ctx context.Context, userProfile map[string]interface{}, content map[string][]models.ContentItem) ([]Recommendation, error) {
 	// Prepare data for Wolfram
 	interests := userProfile["explicit_interests"].([]string)
 	behavioralScores := userProfile["behavioral_scores"].(map[string]float64)
@@ -262,7 +275,7 @@ func (rs *RecommendationService) generateRecommendationsWithWolfram(userProfile
 	wolframURL := fmt.Sprintf("https://api.wolframalpha.com/v2/query?input=%s&appid=%s&output=json", 
 		query, rs.wolframAPIKey)
 	
-	resp, err := http.Get(wolframURL)
+	resp, err := tracing.Get(ctx, wolframURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call Wolfram API: %v", err)
 	}
@@ -283,8 +296,8 @@ func (rs *RecommendationService) generateRecommendationsWithWolfram(userProfile
 	return recommendations, nil
 }
 
-func (rs *RecommendationService) processWolframResponse(wolframResp WolframResponse, content map[string][]map[string]interface{}, userProfile map[string]interface{}) []map[string]interface{} {
-	recommendations := make([]map[string]interface{}, 0)
+func (rs *RecommendationService) processWolframResponse(wolframResp WolframResponse, content map[string][]models.ContentItem, userProfile map[string]interface{}) []Recommendation {
+	recommendations := make([]Recommendation, 0)
 	
 	// Extract insights from Wolfram response
 	insights := make([]string, 0)
@@ -316,20 +329,20 @@ func (rs *RecommendationService) processWolframResponse(wolframResp WolframRespo
 		}
 		
 		for i := 0; i < limit; i++ {
-			item := items[i]
-			item["content_type"] = contentType
-			item["recommendation_score"] = userScore * (1.0 - float64(i)*0.1) // Decreasing score
-			item["reason"] = fmt.Sprintf("Recommended based on your interest in %s", contentType)
-			
-			recommendations = append(recommendations, item)
+			recommendations = append(recommendations, Recommendation{
+				ContentItem:         items[i],
+				ContentType:         contentType,
+				RecommendationScore: userScore * (1.0 - float64(i)*0.1), // Decreasing score
+				Reason:              fmt.Sprintf("Recommended based on your interest in %s", contentType),
+			})
 		}
 	}
 	
 	return recommendations
 }
 
-func (rs *RecommendationService) generateFallbackRecommendations(userProfile map[string]interface{}, content map[string][]map[string]interface{}) []map[string]interface{} {
-	recommendations := make([]map[string]interface{}, 0)
+func (rs *RecommendationService) generateFallbackRecommendations(userProfile map[string]interface{}, content map[string][]models.ContentItem) []Recommendation {
+	recommendations := make([]Recommendation, 0)
 	
 	behavioralScores := userProfile["behavioral_scores"].(map[string]float64)
 	
@@ -349,12 +362,12 @@ func (rs *RecommendationService) generateFallbackRecommendations(userProfile map
 		}
 		
 		for i := 0; i < limit; i++ {
-			item := items[i]
-			item["content_type"] = contentType
-			item["recommendation_score"] = userScore
-			item["reason"] = fmt.Sprintf("Recommended based on your interest in %s", contentType)
-			
-			recommendations = append(recommendations, item)
+			recommendations = append(recommendations, Recommendation{
+				ContentItem:         items[i],
+				ContentType:         contentType,
+				RecommendationScore: userScore,
+				Reason:              fmt.Sprintf("Recommended based on your interest in %s", contentType),
+			})
 		}
 	}
 	
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,22 +22,34 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("recommendation-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "recommendation"})
 	})
 
+	// Readiness
+	http.Handle("/ready", health.NewReadiness("recommendation"))
+
 	http.HandleFunc("/api/recommendations", getRecommendations)
 
 	log.Printf("Recommendation service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(fields.Middleware(metrics.Mux(http.DefaultServeMux)))))))
 }
 
 func getRecommendations(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		userID = "default_user"
-	}
+	// Anonymous requests get the same recommendations, without a user
+	userID := auth.UserIDFrom(r.Context())
 
 	// Mock personalized recommendations
 	recommendations := []map[string]interface{}{
@@ -1,35 +1,92 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"gofr.dev/pkg/gofr"
 	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/audit"
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+	"personalized-dashboard/shared/tracing"
 )
 
 type UserService struct {
-	// Add database connection here
+	// users is nil when no database is configured; users are then mocked
+	users repository.UserRepository
+	audit *audit.Logger
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("user-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	userService := &UserService{}
 
+	auditLog, db, dbErr := audit.Open()
+	if dbErr != nil {
+		log.Printf("Warning: audit log disabled: %v", dbErr)
+	} else {
+		userService.audit = auditLog
+		userService.users = repository.New(db).Users
+	}
+
+	// Take the user and roles from the headers the gateway sets
+	app.UseMiddleware(auth.Identity)
+
+	// Record the actor and request ID of every request for the audit log
+	app.UseMiddleware(audit.Middleware)
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "user"}, nil
-	})
+	}))
+
+	// Readiness check: the user and audit log database
+	readiness := health.NewReadiness("user").
+		Add("database", health.OpenedDatabase(db, dbErr))
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// User endpoints
-	app.POST("/api/users", userService.CreateUser)
-	app.GET("/api/users/:id", userService.GetUser)
-	app.PUT("/api/users/:id", userService.UpdateUser)
-	app.POST("/api/users/:id/behavior", userService.TrackBehavior)
+	app.POST("/api/users", metrics.Handle("/api/users", userService.CreateUser))
+	app.GET("/api/users/:id", metrics.Handle("/api/users/:id", userService.GetUser))
+	app.PUT("/api/users/:id", metrics.Handle("/api/users/:id", userService.UpdateUser))
+	app.POST("/api/users/:id/behavior", metrics.Handle("/api/users/:id/behavior", userService.TrackBehavior))
+
+	// Audit log query for support investigations
+	app.GET("/api/audit", metrics.Handle("/api/audit", userService.QueryAudit))
 
 	app.Run()
 }
@@ -40,8 +97,13 @@ func (us *UserService) CreateUser(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("invalid request data: %v", err)
 	}
 
+	if us.users != nil {
+		return us.createStoredUser(ctx, userData)
+	}
+
+	userID := uuid.New().String()
 	user := map[string]interface{}{
-		"id":        uuid.New().String(),
+		"id":        userID,
 		"email":     userData["email"],
 		"name":      userData["name"],
 		"interests": userData["interests"],
@@ -49,11 +111,28 @@ func (us *UserService) CreateUser(ctx *gofr.Context) (interface{}, error) {
 		"updated_at": time.Now(),
 	}
 
+	if err := us.audit.Record(ctx, audit.Event{
+		Action:     "user.create",
+		TargetType: "user",
+		TargetID:   userID,
+		UserID:     userID,
+		After:      user,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
 	return user, nil
 }
 
 func (us *UserService) GetUser(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("id")
+	userID, err := auth.ActingUser(ctx, ctx.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	if us.users != nil {
+		return us.loadUser(ctx, userID)
+	}
 	
 	// Mock user data
 	user := map[string]interface{}{
@@ -69,13 +148,20 @@ func (us *UserService) GetUser(ctx *gofr.Context) (interface{}, error) {
 }
 
 func (us *UserService) UpdateUser(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("id")
+	userID, err := auth.ActingUser(ctx, ctx.Param("id"))
+	if err != nil {
+		return nil, err
+	}
 	
 	var updateData map[string]interface{}
 	if err := ctx.Bind(&updateData); err != nil {
 		return nil, fmt.Errorf("invalid request data: %v", err)
 	}
 
+	if us.users != nil {
+		return us.updateStoredUser(ctx, userID, updateData)
+	}
+
 	// Mock update
 	user := map[string]interface{}{
 		"id":        userID,
@@ -85,11 +171,24 @@ func (us *UserService) UpdateUser(ctx *gofr.Context) (interface{}, error) {
 		"updated_at": time.Now(),
 	}
 
+	if err := us.audit.Record(ctx, audit.Event{
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   userID,
+		UserID:     userID,
+		After:      user,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
 	return user, nil
 }
 
 func (us *UserService) TrackBehavior(ctx *gofr.Context) (interface{}, error) {
-	userID := ctx.Param("id")
+	userID, err := auth.ActingUser(ctx, ctx.Param("id"))
+	if err != nil {
+		return nil, err
+	}
 	
 	var behaviorData map[string]interface{}
 	if err := ctx.Bind(&behaviorData); err != nil {
@@ -107,3 +206,155 @@ func (us *UserService) TrackBehavior(ctx *gofr.Context) (interface{}, error) {
 
 	return behavior, nil
 }
+
+// createStoredUser creates the user in the database and records it.
+func (us *UserService) createStoredUser(ctx *gofr.Context, userData map[string]interface{}) (interface{}, error) {
+	user := &models.User{}
+	if err := applyUserData(user, userData); err != nil {
+		return nil, err
+	}
+
+	if err := us.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := us.audit.Record(ctx, audit.Event{
+		Action:     "user.create",
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		UserID:     user.ID.String(),
+		After:      user,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
+	return user, nil
+}
+
+func (us *UserService) loadUser(ctx context.Context, userID string) (*models.User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %q", userID)
+	}
+
+	user, err := us.users.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %v", err)
+	}
+	return user, nil
+}
+
+// updateStoredUser loads the current row before applying the update, so the
+// audit entry records what each field was changed from.
+func (us *UserService) updateStoredUser(ctx *gofr.Context, userID string, updateData map[string]interface{}) (interface{}, error) {
+	before, err := us.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := *before
+	if err := applyUserData(&user, updateData); err != nil {
+		return nil, err
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := us.users.Update(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	if err := us.audit.Record(ctx, audit.Event{
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   userID,
+		UserID:     userID,
+		Before:     before,
+		After:      &user,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %v", err)
+	}
+
+	return &user, nil
+}
+
+// applyUserData copies the email, name and interests present in data onto
+// user, leaving the other fields as they are.
+func applyUserData(user *models.User, data map[string]interface{}) error {
+	for name, field := range map[string]*string{"email": &user.Email, "name": &user.Name} {
+		if value, ok := data[name]; ok {
+			text, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid %s: must be a string", name)
+			}
+			*field = text
+		}
+	}
+
+	if value, ok := data["interests"]; ok {
+		list, ok := value.([]interface{})
+		if !ok && value != nil {
+			return fmt.Errorf("invalid interests: must be a list of strings")
+		}
+		interests := make(models.StringList, 0, len(list))
+		for _, item := range list {
+			interest, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("invalid interests: must be a list of strings")
+			}
+			interests = append(interests, interest)
+		}
+		user.Interests = interests
+	}
+	return nil
+}
+
+// QueryAudit lists audit entries, newest first. user_id filters by the user
+// a change concerns; since and until (RFC 3339) bound the time range. Only
+// support staff and admins may query other users' entries; everyone else
+// sees their own.
+func (us *UserService) QueryAudit(ctx *gofr.Context) (interface{}, error) {
+	caller := auth.UserIDFrom(ctx)
+	if caller == "" {
+		return nil, &auth.UnauthorizedError{Err: auth.ErrMissingToken}
+	}
+
+	filter := repository.AuditFilter{UserID: ctx.Param("user_id")}
+	if !auth.HasRole(ctx, auth.RoleSupport, auth.RoleAdmin) {
+		if filter.UserID != "" && filter.UserID != caller {
+			return nil, &auth.ForbiddenError{Err: errors.New("only support staff may query another user's audit entries")}
+		}
+		filter.UserID = caller
+	}
+
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := ctx.Param(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s parameter: %v", name, err)
+			}
+			*bound = parsed
+		}
+	}
+	for name, number := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := ctx.Param(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("invalid %s parameter: %q", name, value)
+			}
+			*number = parsed
+		}
+	}
+
+	entries, err := us.audit.Query(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+
+	return map[string]interface{}{
+		"user_id": filter.UserID,
+		"count":   len(entries),
+		"entries": entries,
+	}, nil
+}
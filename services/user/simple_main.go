@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/audit"
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
 )
 
 type User struct {
@@ -31,18 +39,42 @@ type UserPreferences struct {
 var users = make(map[string]User)
 var userPreferences = make(map[string]UserPreferences)
 
+// auditLog records user and preference mutations; nil without a database
+var auditLog *audit.Logger
+
 func main() {
 	port := "8006"
 	if p := os.Getenv("PORT"); p != "" {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("user-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	logger, auditDB, dbErr := audit.Open()
+	if dbErr != nil {
+		log.Printf("Warning: audit log disabled: %v", dbErr)
+	} else {
+		auditLog = logger
+	}
+
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "user"})
 	})
 
+	// Readiness of the audit database
+	http.Handle("/ready", health.NewReadiness("user").Add("database", health.OpenedDatabase(auditDB, dbErr)))
+
 	// User endpoints
 	http.HandleFunc("/api/users", createUser)
 	http.HandleFunc("/api/users/", getUser)
@@ -50,7 +82,7 @@ func main() {
 	http.HandleFunc("/api/users/preferences/update/", updateUserPreferences)
 
 	log.Printf("User service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(auth.Identity(audit.Middleware(fields.Middleware(metrics.Mux(http.DefaultServeMux))))))))
 }
 
 func createUser(w http.ResponseWriter, r *http.Request) {
@@ -79,8 +111,6 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now(),
 	}
 
-	users[userID] = user
-
 	// Set default preferences based on interests
 	preferences := UserPreferences{
 		NewsCategories:   getDefaultNewsCategories(userData.Interests),
@@ -92,6 +122,19 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 		PreferredSources: getDefaultSources(userData.Interests),
 	}
 
+	if err := auditLog.Record(r.Context(), audit.Event{
+		Action:     "user.create",
+		TargetType: "user",
+		TargetID:   userID,
+		UserID:     userID,
+		After:      map[string]interface{}{"user": user, "preferences": preferences},
+	}); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+		http.Error(w, "Failed to record audit entry", http.StatusInternalServerError)
+		return
+	}
+
+	users[userID] = user
 	userPreferences[userID] = preferences
 
 	response := map[string]interface{}{
@@ -110,6 +153,9 @@ func getUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
 	}
+	if _, ok := auth.Authorize(w, r, userID); !ok {
+		return
+	}
 
 	user, exists := users[userID]
 	if !exists {
@@ -127,6 +173,9 @@ func getUserPreferences(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
 	}
+	if _, ok := auth.Authorize(w, r, userID); !ok {
+		return
+	}
 
 	preferences, exists := userPreferences[userID]
 	if !exists {
@@ -149,6 +198,9 @@ func updateUserPreferences(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
 	}
+	if _, ok := auth.Authorize(w, r, userID); !ok {
+		return
+	}
 
 	var preferences UserPreferences
 	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
@@ -156,6 +208,24 @@ func updateUserPreferences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	previous, existed := userPreferences[userID]
+
+	event := audit.Event{
+		Action:     "preferences.update",
+		TargetType: "user_preferences",
+		TargetID:   userID,
+		UserID:     userID,
+		After:      preferences,
+	}
+	if existed {
+		event.Before = previous
+	}
+	if err := auditLog.Record(r.Context(), event); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+		http.Error(w, "Failed to record audit entry", http.StatusInternalServerError)
+		return
+	}
+
 	userPreferences[userID] = preferences
 
 	response := map[string]interface{}{
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,6 +13,13 @@ import (
 
 	"gofr.dev/pkg/gofr"
 	"github.com/patrickmn/go-cache"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/ingest"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 type YouTubeResponse struct {
@@ -47,32 +55,68 @@ type YouTubeResponse struct {
 type VideosService struct {
 	apiKey string
 	cache  *cache.Cache
+	store  *ingest.Ingester
 }
 
 func main() {
 	app := gofr.New()
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("videos-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Serve /metrics and record every request, then trace it
+	app.UseMiddleware(metrics.Middleware)
+	app.UseMiddleware(tracing.Middleware)
+
+	// Trim responses to the fields asked for with ?fields=
+	app.UseMiddleware(fields.Middleware)
+
 	videosService := &VideosService{
 		apiKey: os.Getenv("YOUTUBE_API_KEY"),
 		cache:  cache.New(5*time.Minute, 10*time.Minute),
 	}
 
+	// Keep every fetched video in the content store when a database is available
+	store, db, dbErr := ingest.Open()
+	if dbErr != nil {
+		log.Printf("Warning: content store disabled: %v", dbErr)
+	} else {
+		videosService.store = store
+	}
+
 	// Health check
-	app.GET("/health", func(ctx *gofr.Context) (interface{}, error) {
+	app.GET("/health", metrics.Handle("/health", func(ctx *gofr.Context) (interface{}, error) {
 		return map[string]string{"status": "healthy", "service": "videos"}, nil
-	})
+	}))
+
+	// Readiness check: database and cache, with pool stats, and the provider keys
+	readiness := health.NewReadiness("videos").
+		Add("database", health.OpenedDatabase(db, dbErr)).
+		Add("cache", health.Cache(videosService.cache)).
+		Providers(health.Provider{Name: "youtube", Env: []string{"YOUTUBE_API_KEY"}})
+	app.GET("/ready", metrics.Handle("/ready", func(ctx *gofr.Context) (interface{}, error) {
+		return readiness.Handle(ctx)
+	}))
 
 	// Get videos by category
-	app.GET("/api/videos", videosService.GetVideos)
+	app.GET("/api/videos", metrics.Handle("/api/videos", videosService.GetVideos))
 	
 	// Get trending videos
-	app.GET("/api/videos/trending", videosService.GetTrendingVideos)
+	app.GET("/api/videos/trending", metrics.Handle("/api/videos/trending", videosService.GetTrendingVideos))
 	
 	// Search videos
-	app.GET("/api/videos/search", videosService.SearchVideos)
+	app.GET("/api/videos/search", metrics.Handle("/api/videos/search", videosService.SearchVideos))
 	
 	// Get video details
-	app.GET("/api/videos/:id", videosService.GetVideoDetails)
+	app.GET("/api/videos/:id", metrics.Handle("/api/videos/:id", videosService.GetVideoDetails))
 
 	app.Run()
 }
@@ -83,8 +127,13 @@ func (vs *VideosService) GetVideos(ctx *gofr.Context) (interface{}, error) {
 		category = "technology"
 	}
 
+	// Serve from the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return vs.storedVideos(ctx, category, nil)
+	}
+
 	// Check cache first
-	if cached, found := vs.cache.Get("videos_" + category); found {
+	if cached, found := metrics.CacheGet(vs.cache, "videos", "videos_"+category); found {
 		return cached, nil
 	}
 
@@ -105,15 +154,17 @@ func (vs *VideosService) GetVideos(ctx *gofr.Context) (interface{}, error) {
 		searchTerm = category
 	}
 
-	videos, err := vs.fetchVideosFromYouTube(searchTerm, 20)
+	videos, err := vs.fetchVideosFromYouTube(ctx, searchTerm, 20)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch videos: %v", err)
+		return vs.storedVideos(ctx, category, fmt.Errorf("failed to fetch videos: %v", err))
 	}
+	vs.ingest(ctx, category, videos)
 
 	result := map[string]interface{}{
 		"category": category,
 		"count":    len(videos),
 		"videos":   videos,
+		"items":    models.ContentItemsFromMaps(models.ContentTypeVideo, videos),
 	}
 
 	// Cache the result
@@ -124,7 +175,7 @@ func (vs *VideosService) GetVideos(ctx *gofr.Context) (interface{}, error) {
 
 func (vs *VideosService) GetTrendingVideos(ctx *gofr.Context) (interface{}, error) {
 	// Check cache first
-	if cached, found := vs.cache.Get("trending_videos"); found {
+	if cached, found := metrics.CacheGet(vs.cache, "videos", "trending_videos"); found {
 		return cached, nil
 	}
 
@@ -141,17 +192,19 @@ func (vs *VideosService) GetTrendingVideos(ctx *gofr.Context) (interface{}, erro
 		}
 		
 		searchTerm := searchTerms[category]
-		videos, err := vs.fetchVideosFromYouTube(searchTerm, 5)
+		videos, err := vs.fetchVideosFromYouTube(ctx, searchTerm, 5)
 		if err != nil {
 			log.Printf("Failed to fetch %s videos: %v", category, err)
 			continue
 		}
+		vs.ingest(ctx, category, videos)
 		allVideos = append(allVideos, videos...)
 	}
 
 	result := map[string]interface{}{
 		"count":  len(allVideos),
 		"videos": allVideos,
+		"items":  models.ContentItemsFromMaps(models.ContentTypeVideo, allVideos),
 	}
 
 	// Cache the result
@@ -171,21 +224,28 @@ func (vs *VideosService) SearchVideos(ctx *gofr.Context) (interface{}, error) {
 		maxResults = "20"
 	}
 
+	// Search the stored corpus when asked to
+	if ctx.Param("source") == "stored" {
+		return vs.searchStoredVideos(ctx, query, ctx.Param("category"), nil)
+	}
+
 	// Check cache first
 	cacheKey := "search_videos_" + query + "_" + maxResults
-	if cached, found := vs.cache.Get(cacheKey); found {
+	if cached, found := metrics.CacheGet(vs.cache, "videos", cacheKey); found {
 		return cached, nil
 	}
 
-	videos, err := vs.fetchVideosFromYouTube(query, 20)
+	videos, err := vs.fetchVideosFromYouTube(ctx, query, 20)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search videos: %v", err)
+		return vs.searchStoredVideos(ctx, query, "", fmt.Errorf("failed to search videos: %v", err))
 	}
+	vs.ingest(ctx, "", videos)
 
 	result := map[string]interface{}{
 		"query":  query,
 		"count":  len(videos),
 		"videos": videos,
+		"items":  models.ContentItemsFromMaps(models.ContentTypeVideo, videos),
 	}
 
 	// Cache the result
@@ -201,14 +261,14 @@ func (vs *VideosService) GetVideoDetails(ctx *gofr.Context) (interface{}, error)
 	}
 
 	// Check cache first
-	if cached, found := vs.cache.Get("video_details_" + videoID); found {
+	if cached, found := metrics.CacheGet(vs.cache, "videos", "video_details_"+videoID); found {
 		return cached, nil
 	}
 
 	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/videos?id=%s&part=snippet,statistics,contentDetails&key=%s", 
 		videoID, vs.apiKey)
 	
-	resp, err := http.Get(url)
+	resp, err := tracing.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch video details: %v", err)
 	}
@@ -250,18 +310,21 @@ func (vs *VideosService) GetVideoDetails(ctx *gofr.Context) (interface{}, error)
 		"comments":    video.Statistics.CommentCount,
 	}
 
+	vs.ingest(ctx, "", []map[string]interface{}{result})
+	result["item"] = models.ContentItemFromMap(models.ContentTypeVideo, result)
+
 	// Cache the result
 	vs.cache.Set("video_details_"+videoID, result, cache.DefaultExpiration)
 
 	return result, nil
 }
 
-func (vs *VideosService) fetchVideosFromYouTube(query string, maxResults int) ([]map[string]interface{}, error) {
+func (vs *VideosService) fetchVideosFromYouTube(ctx context.Context, query string, maxResults int) ([]map[string]interface{}, error) {
 	// First, search for videos
 	searchURL := fmt.Sprintf("https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&type=video&maxResults=%d&order=relevance&key=%s", 
 		query, maxResults, vs.apiKey)
 	
-	resp, err := http.Get(searchURL)
+	resp, err := tracing.Get(ctx, searchURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search videos: %v", err)
 	}
@@ -307,7 +370,7 @@ func (vs *VideosService) fetchVideosFromYouTube(query string, maxResults int) ([
 	detailsURL := fmt.Sprintf("https://www.googleapis.com/youtube/v3/videos?id=%s&part=snippet,statistics,contentDetails&key=%s", 
 		strings.Join(videoIDs, ","), vs.apiKey)
 	
-	detailsResp, err := http.Get(detailsURL)
+	detailsResp, err := tracing.Get(ctx, detailsURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch video details: %v", err)
 	}
@@ -377,3 +440,80 @@ func (vs *VideosService) parseDuration(duration string) string {
 	}
 	return fmt.Sprintf("%d:%02d", minutes, seconds)
 }
+
+// ingest upserts fetched videos into the content store in the background,
+// so the live response does not wait for the database. Failures are only
+// logged.
+func (vs *VideosService) ingest(ctx context.Context, category string, videos []map[string]interface{}) {
+	if vs.store == nil {
+		return
+	}
+
+	ctx, cancel := ingest.Detach(ctx)
+	go func() {
+		defer cancel()
+		result, err := vs.store.Videos(ctx, category, videos)
+		if err != nil {
+			log.Printf("Failed to store videos: %v", err)
+			return
+		}
+		log.Printf("Stored videos (%s): %s", category, result)
+	}()
+}
+
+// storedVideos serves a category from the content store. cause is the
+// upstream error that triggered the fallback and is returned when there is
+// no store.
+func (vs *VideosService) storedVideos(ctx context.Context, category string, cause error) (interface{}, error) {
+	if vs.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Serving stored %s videos: %v", category, cause)
+	}
+
+	videos, err := vs.store.StoredVideos(ctx, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored videos: %v", err)
+	}
+
+	return map[string]interface{}{
+		"category": category,
+		"count":    len(videos),
+		"videos":   videos,
+		"items":    models.ContentItems(videos),
+		"source":   "stored",
+	}, nil
+}
+
+// searchStoredVideos runs the query against the content store, which keeps
+// search working when the YouTube key is missing or over quota.
+func (vs *VideosService) searchStoredVideos(ctx context.Context, query, category string, cause error) (interface{}, error) {
+	if vs.store == nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("content store is not configured")
+	}
+
+	if cause != nil {
+		log.Printf("Searching stored videos for %q: %v", query, cause)
+	}
+
+	videos, err := vs.store.SearchVideos(ctx, query, category, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stored videos: %v", err)
+	}
+
+	return map[string]interface{}{
+		"query":  query,
+		"count":  len(videos),
+		"videos": videos,
+		"items":  models.ContentItems(videos),
+		"source": "stored",
+	}, nil
+}
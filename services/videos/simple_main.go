@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"personalized-dashboard/shared/fields"
+	"personalized-dashboard/shared/health"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/tracing"
 )
 
 func main() {
@@ -15,17 +22,31 @@ func main() {
 		port = p
 	}
 
+	// Trace requests, continuing the trace the gateway started
+	traceConfig, err := tracing.ConfigFromEnv("videos-service")
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init(traceConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "videos"})
 	})
 
+	// Readiness, with the status of the provider keys
+	http.Handle("/ready", health.NewReadiness("videos").Providers(health.Provider{Name: "youtube", Env: []string{"YOUTUBE_API_KEY"}}))
+
 	http.HandleFunc("/api/videos", getVideos)
 	http.HandleFunc("/api/videos/trending", getTrendingVideos)
 	http.HandleFunc("/api/videos/search", searchVideos)
 
 	log.Printf("Videos service starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, metrics.Middleware(tracing.Middleware(fields.Middleware(metrics.Mux(http.DefaultServeMux))))))
 }
 
 func getVideos(w http.ResponseWriter, r *http.Request) {
@@ -60,7 +81,7 @@ func getVideos(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(errorMsg)
+		json.NewEncoder(w).Encode(withItems(errorMsg))
 		return
 	}
 
@@ -84,7 +105,7 @@ func getVideos(w http.ResponseWriter, r *http.Request) {
 	// Search for videos
 	searchURL := fmt.Sprintf("https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&type=video&maxResults=20&order=relevance&key=%s", searchTerm, apiKey)
 	
-	resp, err := http.Get(searchURL)
+	resp, err := tracing.Get(r.Context(), searchURL)
 	if err != nil {
 		http.Error(w, "Failed to search videos", http.StatusInternalServerError)
 		return
@@ -135,7 +156,7 @@ func getVideos(w http.ResponseWriter, r *http.Request) {
 	detailsURL := fmt.Sprintf("https://www.googleapis.com/youtube/v3/videos?id=%s&part=snippet,statistics,contentDetails&key=%s", 
 		fmt.Sprintf("%s", videoIDs[0]), apiKey)
 	
-	detailsResp, err := http.Get(detailsURL)
+	detailsResp, err := tracing.Get(r.Context(), detailsURL)
 	if err != nil {
 		http.Error(w, "Failed to fetch video details", http.StatusInternalServerError)
 		return
@@ -203,7 +224,7 @@ func getVideos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func getTrendingVideos(w http.ResponseWriter, r *http.Request) {
@@ -228,7 +249,7 @@ func getTrendingVideos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
 }
 
 func searchVideos(w http.ResponseWriter, r *http.Request) {
@@ -260,5 +281,13 @@ func searchVideos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withItems(result))
+}
+
+// withItems adds the shared content envelope next to the videos payload.
+func withItems(result map[string]interface{}) map[string]interface{} {
+	if videos, ok := result["videos"].([]map[string]interface{}); ok {
+		result["items"] = models.ContentItemsFromMaps(models.ContentTypeVideo, videos)
+	}
+	return result
 }
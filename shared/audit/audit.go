@@ -0,0 +1,124 @@
+// Package audit records user, preference and coupon mutations in the
+// append-only audit_log table: who changed what, when, in which request, and
+// the fields that changed.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+)
+
+// Event describes one mutation. Before is nil for created records; After is
+// nil for deleted ones. Both are diffed through their JSON form, so maps and
+// structs can be passed as they are returned to the client.
+type Event struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	UserID     string
+	Before     interface{}
+	After      interface{}
+}
+
+type Logger struct {
+	repo repository.AuditRepository
+}
+
+func New(repo repository.AuditRepository) *Logger {
+	return &Logger{repo: repo}
+}
+
+// Open connects to the configured database, applies migrations and returns
+// a Logger on top of it.
+func Open() (*Logger, *sql.DB, error) {
+	db, err := database.SetupDatabase()
+	if err != nil {
+		return nil, nil, err
+	}
+	return New(repository.New(db).Audit), db, nil
+}
+
+// Record appends event to the audit log, taking the actor and request ID
+// from ctx (see Middleware). A nil Logger records nothing, so services keep
+// working without a database.
+func (l *Logger) Record(ctx context.Context, event Event) error {
+	if l == nil {
+		return nil
+	}
+
+	changes, err := Diff(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+
+	metadata := MetadataFrom(ctx)
+	entry := &models.AuditEntry{
+		Actor:      metadata.Actor,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		UserID:     event.UserID,
+		RequestID:  metadata.RequestID,
+		Changes:    changes,
+	}
+	return l.repo.Append(ctx, entry)
+}
+
+// Query returns the entries matching filter, newest first.
+func (l *Logger) Query(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	if l == nil {
+		return nil, fmt.Errorf("audit log is not configured")
+	}
+	return l.repo.List(ctx, filter)
+}
+
+// Diff returns the top-level fields whose values differ between before and
+// after.
+func Diff(before, after interface{}) (models.AuditDiff, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(models.AuditDiff)
+	for name, value := range afterFields {
+		if old, ok := beforeFields[name]; !ok || !reflect.DeepEqual(old, value) {
+			diff[name] = models.AuditChange{Before: beforeFields[name], After: value}
+		}
+	}
+	for name, old := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			diff[name] = models.AuditChange{Before: old}
+		}
+	}
+	return diff, nil
+}
+
+// fields decodes the JSON form of v into its top-level fields.
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit value: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("audit values must encode to JSON objects: %v", err)
+	}
+	return decoded, nil
+}
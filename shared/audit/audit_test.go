@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+)
+
+func TestDiff(t *testing.T) {
+	type user struct {
+		Name      string   `json:"name"`
+		Interests []string `json:"interests"`
+	}
+
+	tests := []struct {
+		name          string
+		before, after interface{}
+		want          models.AuditDiff
+	}{
+		{
+			name:  "created",
+			after: user{Name: "Alice"},
+			want:  models.AuditDiff{"name": {After: "Alice"}, "interests": {}},
+		},
+		{
+			name:   "deleted",
+			before: map[string]interface{}{"name": "Alice"},
+			want:   models.AuditDiff{"name": {Before: "Alice"}},
+		},
+		{
+			name:   "only changed fields",
+			before: user{Name: "Alice", Interests: []string{"go"}},
+			after:  user{Name: "Alice", Interests: []string{"go", "rust"}},
+			want: models.AuditDiff{"interests": {
+				Before: []interface{}{"go"},
+				After:  []interface{}{"go", "rust"},
+			}},
+		},
+		{
+			name:   "struct against map",
+			before: user{Name: "Alice"},
+			after:  map[string]interface{}{"name": "Bob", "interests": nil},
+			want:   models.AuditDiff{"name": {Before: "Alice", After: "Bob"}},
+		},
+		{
+			name:   "unchanged",
+			before: &user{Name: "Alice"},
+			after:  user{Name: "Alice"},
+			want:   models.AuditDiff{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Diff(tc.before, tc.after)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Diff = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := Diff([]string{"not", "an", "object"}, nil); err == nil {
+		t.Error("Diff of a list succeeded")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	repos := repository.New(dbtest.SQLite(t))
+	logger := New(repos.Audit)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := logger.Record(r.Context(), Event{
+			Action:     "user.update",
+			TargetType: "user",
+			TargetID:   "u1",
+			UserID:     "u1",
+			Before:     map[string]string{"name": "Alice"},
+			After:      map[string]string{"name": "Bob"},
+		})
+		if err != nil {
+			t.Errorf("Record: %v", err)
+		}
+	}))
+
+	tests := []struct {
+		actor, requestID string
+		wantActor        string
+	}{
+		{"u1", "req-1", "u1"},
+		{"", "req-2", AnonymousActor},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/users/u1", nil)
+		if tc.actor != "" {
+			req.Header.Set(ActorHeader, tc.actor)
+		}
+		req.Header.Set(RequestIDHeader, tc.requestID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get(RequestIDHeader); got != tc.requestID {
+			t.Errorf("echoed request ID = %q, want %q", got, tc.requestID)
+		}
+	}
+
+	entries, err := logger.Query(context.Background(), repository.AuditFilter{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("Query returned %d entries, want %d", len(entries), len(tests))
+	}
+	for _, tc := range tests {
+		found := false
+		for _, entry := range entries {
+			if entry.RequestID == tc.requestID {
+				found = true
+				if entry.Actor != tc.wantActor || entry.Changes["name"].Before != "Alice" || entry.Changes["name"].After != "Bob" {
+					t.Errorf("entry for %s = %+v", tc.requestID, entry)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no entry for %s", tc.requestID)
+		}
+	}
+
+	var disabled *Logger
+	if err := disabled.Record(context.Background(), Event{Action: "user.update"}); err != nil {
+		t.Errorf("Record on a nil Logger = %v, want nil", err)
+	}
+	if _, err := disabled.Query(context.Background(), repository.AuditFilter{}); err == nil {
+		t.Error("Query on a nil Logger succeeded")
+	}
+}
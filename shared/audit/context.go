@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/tracing"
+)
+
+const (
+	// ActorHeader carries the authenticated user; the gateway sets it.
+	ActorHeader     = "X-User-ID"
+	RequestIDHeader = "X-Request-ID"
+
+	// AnonymousActor is recorded when a request has no authenticated user.
+	AnonymousActor = "anonymous"
+)
+
+// Metadata identifies who made a request and which request it was.
+type Metadata struct {
+	Actor     string
+	RequestID string
+}
+
+type contextKey struct{}
+
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, metadata)
+}
+
+// MetadataFrom returns the metadata stored in ctx, with the actor defaulting
+// to AnonymousActor.
+func MetadataFrom(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(contextKey{}).(Metadata)
+	if metadata.Actor == "" {
+		metadata.Actor = AnonymousActor
+	}
+	return metadata
+}
+
+// Middleware stores the actor and request ID of every request in its
+// context. The request ID is the one tracing.Middleware assigned, else the
+// X-Request-ID header; requests without either get a new one. It is echoed
+// in the response so clients can quote it to support.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := tracing.RequestIDFrom(r.Context())
+		if requestID == "" {
+			requestID = r.Header.Get(RequestIDHeader)
+		}
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := WithMetadata(r.Context(), Metadata{
+			Actor:     r.Header.Get(ActorHeader),
+			RequestID: requestID,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,250 @@
+// Package auth verifies the bearer tokens the gateway accepts and carries the
+// authenticated user to the services in a trusted X-User-ID header.
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+	ErrForbidden    = errors.New("not allowed to act for another user")
+)
+
+// Claims are the registered claims the gateway checks, plus the roles it
+// passes on. Audience may be a single string or a list in the token.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Config says which tokens are accepted. HS256 tokens need HMACSecret and
+// RS256 tokens need a key from the JWKS file; an algorithm without key
+// material is rejected. Issuer and Audience are only checked when set.
+type Config struct {
+	HMACSecret []byte
+	JWKSFile   string
+	Issuer     string
+	Audience   string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// Required rejects requests without a token instead of forwarding them
+	// anonymously. PublicPaths are exempt.
+	Required    bool
+	PublicPaths []string
+}
+
+// ConfigFromEnv reads JWT_HS256_SECRET, JWT_JWKS_FILE, JWT_ISSUER,
+// JWT_AUDIENCE, JWT_LEEWAY and AUTH_REQUIRED.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		HMACSecret:  []byte(os.Getenv("JWT_HS256_SECRET")),
+		JWKSFile:    os.Getenv("JWT_JWKS_FILE"),
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+		Leeway:      30 * time.Second,
+		PublicPaths: []string{"/health"},
+	}
+
+	if value := os.Getenv("JWT_LEEWAY"); value != "" {
+		leeway, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid JWT_LEEWAY: %v", err)
+		}
+		cfg.Leeway = leeway
+	}
+	if value := os.Getenv("AUTH_REQUIRED"); value != "" {
+		cfg.Required = value == "true" || value == "1"
+	}
+
+	return cfg, nil
+}
+
+// Verifier checks token signatures and claims.
+type Verifier struct {
+	cfg    Config
+	keys   map[string]*rsa.PublicKey
+	public func(*http.Request) bool
+	now    func() time.Time
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	v := &Verifier{cfg: cfg, now: time.Now}
+
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	}
+
+	if len(cfg.HMACSecret) == 0 && len(v.keys) == 0 && cfg.Required {
+		return nil, fmt.Errorf("AUTH_REQUIRED is set but neither JWT_HS256_SECRET nor JWT_JWKS_FILE is configured")
+	}
+	return v, nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks the signature of a compact JWS token and its exp, nbf, iss
+// and aud claims, and returns the claims. Tokens must have a subject.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, fmt.Errorf("%w: bad header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := v.verifySignature(hdr, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.checkClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) verifySignature(hdr header, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch hdr.Algorithm {
+	case "HS256":
+		if len(v.cfg.HMACSecret) == 0 {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.cfg.HMACSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+
+	case "RS256":
+		key, err := v.rsaKey(hdr.KeyID)
+		if err != nil {
+			return err
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, hdr.Algorithm)
+	}
+}
+
+// rsaKey finds the JWKS key for kid. Tokens without a kid are accepted only
+// when the JWKS holds a single key.
+func (v *Verifier) rsaKey(kid string) (*rsa.PublicKey, error) {
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("%w: RS256 tokens are not accepted", ErrInvalidToken)
+	}
+	if kid == "" {
+		if len(v.keys) == 1 {
+			for _, key := range v.keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: token has no kid", ErrInvalidToken)
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kid %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+func (v *Verifier) checkClaims(claims *Claims) error {
+	now := v.now()
+
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(v.cfg.Leeway)) {
+		return ErrExpiredToken
+	}
+	if claims.NotBefore != 0 && now.Add(v.cfg.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if v.cfg.Issuer != "" && claims.Issuer != v.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if v.cfg.Audience != "" && !claims.Audience.contains(v.cfg.Audience) {
+		return fmt.Errorf("%w: token is not meant for %q", ErrInvalidToken, v.cfg.Audience)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("test-secret")
+	testNow    = time.Unix(1700000000, 0)
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode token segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256Token(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	hdr := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if kid != "" {
+		hdr["kid"] = kid
+	}
+	signed := encodeSegment(t, hdr) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writeJWKS writes the public halves of keys, by kid, to a JWKS file.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey, extra ...map[string]string) string {
+	t.Helper()
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	set.Keys = append(set.Keys, extra...)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, _ := json.Marshal(set)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{"sub": "alice", "exp": testNow.Add(time.Hour).Unix()}
+	for name, value := range overrides {
+		if value == nil {
+			delete(c, name)
+			continue
+		}
+		c[name] = value
+	}
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	key, other := generateKey(t), generateKey(t)
+	cfg := Config{
+		HMACSecret: testSecret,
+		JWKSFile:   writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key}),
+		Issuer:     "https://issuer.example.com",
+		Audience:   "dashboard",
+		Leeway:     time.Minute,
+	}
+	verifier, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	verifier.now = func() time.Time { return testNow }
+
+	valid := claims(map[string]interface{}{"iss": cfg.Issuer, "aud": []string{"other", "dashboard"}, "roles": []string{"support"}})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"HS256", hs256Token(t, testSecret, valid), nil},
+		{"RS256 by kid", rs256Token(t, key, "k1", valid), nil},
+		{"RS256 without kid and a single key", rs256Token(t, key, "", valid), nil},
+		{"single audience", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard"})), nil},
+		{"expired within leeway", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard", "exp": testNow.Add(-30 * time.Second).Unix()})), nil},
+		{"expired", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard", "exp": testNow.Add(-2 * time.Minute).Unix()})), ErrExpiredToken},
+		{"not valid yet", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard", "nbf": testNow.Add(2 * time.Minute).Unix()})), ErrInvalidToken},
+		{"wrong secret", hs256Token(t, []byte("other"), valid), ErrInvalidToken},
+		{"wrong RSA key", rs256Token(t, other, "k1", valid), ErrInvalidToken},
+		{"unknown kid", rs256Token(t, key, "k2", valid), ErrInvalidToken},
+		{"wrong issuer", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": "https://evil.example.com", "aud": "dashboard"})), ErrInvalidToken},
+		{"wrong audience", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "other"})), ErrInvalidToken},
+		{"missing subject", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard", "sub": nil})), ErrInvalidToken},
+		{"missing expiry", hs256Token(t, testSecret, claims(map[string]interface{}{"iss": cfg.Issuer, "aud": "dashboard", "exp": nil})), ErrInvalidToken},
+		{"alg none", encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, valid) + ".", ErrInvalidToken},
+		{"malformed", "not-a-token", ErrInvalidToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := verifier.Verify(tc.token)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Verify err = %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && got.Subject != "alice" {
+				t.Errorf("Subject = %q, want alice", got.Subject)
+			}
+		})
+	}
+
+	got, err := verifier.Verify(hs256Token(t, testSecret, valid))
+	if err != nil || len(got.Roles) != 1 || got.Roles[0] != "support" {
+		t.Errorf("Roles = %v, %v; want [support]", got.Roles, err)
+	}
+}
+
+func TestVerifyWithoutKeyMaterial(t *testing.T) {
+	key := generateKey(t)
+	verifier, err := NewVerifier(Config{})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	verifier.now = func() time.Time { return testNow }
+
+	for name, token := range map[string]string{
+		"HS256": hs256Token(t, nil, claims(nil)),
+		"RS256": rs256Token(t, key, "k1", claims(nil)),
+	} {
+		if _, err := verifier.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s without key material: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	if _, err := NewVerifier(Config{Required: true}); err == nil {
+		t.Error("NewVerifier with Required and no keys succeeded")
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	key, second := generateKey(t), generateKey(t)
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		keys    map[string]*rsa.PrivateKey
+		extra   []map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{"two keys", map[string]*rsa.PrivateKey{"a": key, "b": second}, nil, []string{"a", "b"}, false},
+		{"skips other types and uses", map[string]*rsa.PrivateKey{"a": key}, []map[string]string{
+			{"kty": "EC", "kid": "ec"},
+			{"kty": "RSA", "kid": "enc", "use": "enc"},
+		}, []string{"a"}, false},
+		{"short modulus", map[string]*rsa.PrivateKey{"small": small}, nil, nil, true},
+		{"bad exponent", nil, []map[string]string{
+			{"kty": "RSA", "kid": "e1", "n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()), "e": "AQ"},
+		}, nil, true},
+		{"no signing keys", nil, []map[string]string{{"kty": "EC", "kid": "ec"}}, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := LoadJWKS(writeJWKS(t, tc.keys, tc.extra...))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadJWKS err = %v, want error %v", err, tc.wantErr)
+			}
+			if len(keys) != len(tc.want) {
+				t.Fatalf("LoadJWKS returned %d keys, want %v", len(keys), tc.want)
+			}
+			for _, kid := range tc.want {
+				if keys[kid] == nil {
+					t.Errorf("key %q missing", kid)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadJWKS of a missing file succeeded")
+	}
+}
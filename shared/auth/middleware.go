@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// UserIDHeader carries the authenticated user from the gateway to the
+// services. The gateway always replaces it, so services behind the gateway
+// can trust it.
+const UserIDHeader = "X-User-ID"
+
+// RolesHeader carries the comma-separated roles claim of the authenticated
+// user. Like X-User-ID, the gateway always replaces it.
+const RolesHeader = "X-User-Roles"
+
+// Roles that grant access beyond the caller's own data.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+type claimsKey struct{}
+type userIDKey struct{}
+type rolesKey struct{}
+
+// ClaimsFrom returns the verified claims of the request, or nil for
+// anonymous requests.
+func ClaimsFrom(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// Middleware is the gateway middleware. It strips any client-supplied
+// X-User-ID and X-User-Roles, verifies the bearer token when there is one
+// and sets X-User-ID to its subject and X-User-Roles to its roles. Bad
+// tokens are rejected with 401; requests without a token pass anonymously
+// unless the config requires a token.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(UserIDHeader)
+		r.Header.Del(RolesHeader)
+
+		token, err := bearerToken(r)
+		if err != nil {
+			if errors.Is(err, ErrMissingToken) && (!v.cfg.Required || v.isPublic(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			Unauthorized(w, err)
+			return
+		}
+
+		claims, err := v.Verify(token)
+		if err != nil {
+			Unauthorized(w, err)
+			return
+		}
+
+		r.Header.Set(UserIDHeader, claims.Subject)
+		if len(claims.Roles) > 0 {
+			r.Header.Set(RolesHeader, strings.Join(claims.Roles, ","))
+		}
+		ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+		ctx = context.WithValue(ctx, userIDKey{}, claims.Subject)
+		ctx = context.WithValue(ctx, rolesKey{}, claims.Roles)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AllowAnonymous exempts the requests for which public returns true from
+// Required, in addition to PublicPaths. Call it before serving requests.
+func (v *Verifier) AllowAnonymous(public func(*http.Request) bool) {
+	v.public = public
+}
+
+func (v *Verifier) isPublic(r *http.Request) bool {
+	for _, public := range v.cfg.PublicPaths {
+		if r.URL.Path == public {
+			return true
+		}
+	}
+	return v.public != nil && v.public(r)
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	value := r.Header.Get("Authorization")
+	if value == "" {
+		return "", ErrMissingToken
+	}
+
+	scheme, token, found := strings.Cut(value, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", errors.New("authorization header must use the Bearer scheme")
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// Unauthorized answers 401 with a WWW-Authenticate challenge describing err.
+func Unauthorized(w http.ResponseWriter, err error) {
+	description := strings.ReplaceAll(err.Error(), `"`, `'`)
+	if errors.Is(err, ErrMissingToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token", error_description="`+description+`"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Forbidden answers 403 with err.
+func Forbidden(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// UnauthorizedError is the 401 of a gofr handler, which answers errors
+// with their StatusCode.
+type UnauthorizedError struct {
+	Err error
+}
+
+func (e *UnauthorizedError) Error() string   { return e.Err.Error() }
+func (e *UnauthorizedError) Unwrap() error   { return e.Err }
+func (e *UnauthorizedError) StatusCode() int { return http.StatusUnauthorized }
+
+// ForbiddenError is the 403 of a gofr handler.
+type ForbiddenError struct {
+	Err error
+}
+
+func (e *ForbiddenError) Error() string   { return e.Err.Error() }
+func (e *ForbiddenError) Unwrap() error   { return e.Err }
+func (e *ForbiddenError) StatusCode() int { return http.StatusForbidden }
+
+// Identity is the service-side middleware: it makes the X-User-ID and
+// X-User-Roles set by the gateway available through UserIDFrom and HasRole.
+// Services must only be reachable through the gateway for the headers to be
+// trusted. Roles without a user are ignored.
+func Identity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get(UserIDHeader); userID != "" {
+			ctx := context.WithValue(r.Context(), userIDKey{}, userID)
+			if roles := r.Header.Get(RolesHeader); roles != "" {
+				ctx = context.WithValue(ctx, rolesKey{}, strings.Split(roles, ","))
+			}
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserIDFrom returns the authenticated user of the request, or "" for
+// anonymous requests.
+func UserIDFrom(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// HasRole reports whether the authenticated user of the request has one of
+// roles.
+func HasRole(ctx context.Context, roles ...string) bool {
+	granted, _ := ctx.Value(rolesKey{}).([]string)
+	for _, have := range granted {
+		for _, role := range roles {
+			if strings.TrimSpace(have) == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ActingUser returns the user a request acts for: target when the
+// authenticated user is target or has the support or admin role, else the
+// authenticated user when target is empty. Anonymous requests get an
+// UnauthorizedError wrapping ErrMissingToken and requests for someone else a
+// ForbiddenError wrapping ErrForbidden, so gofr handlers can return them as
+// they are.
+func ActingUser(ctx context.Context, target string) (string, error) {
+	userID := UserIDFrom(ctx)
+	switch {
+	case userID == "":
+		return "", &UnauthorizedError{Err: ErrMissingToken}
+	case target == "" || target == userID:
+		return userID, nil
+	case HasRole(ctx, RoleSupport, RoleAdmin):
+		return target, nil
+	default:
+		return "", &ForbiddenError{Err: ErrForbidden}
+	}
+}
+
+// Authorize resolves the user a plain net/http handler acts for with
+// ActingUser, answering 401 or 403 and returning false when it may not.
+func Authorize(w http.ResponseWriter, r *http.Request, target string) (string, bool) {
+	userID, err := ActingUser(r.Context(), target)
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		Unauthorized(w, err)
+		return "", false
+	case err != nil:
+		Forbidden(w, err)
+		return "", false
+	}
+	return userID, true
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	verifier, err := NewVerifier(Config{HMACSecret: testSecret, PublicPaths: []string{"/health"}})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	verifier.now = func() time.Time { return testNow }
+	required, err := NewVerifier(Config{HMACSecret: testSecret, Required: true, PublicPaths: []string{"/health"}})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	required.now = verifier.now
+
+	token := hs256Token(t, testSecret, claims(map[string]interface{}{"roles": []string{"support", "admin"}}))
+
+	tests := []struct {
+		name          string
+		verifier      *Verifier
+		path          string
+		authorization string
+		wantStatus    int
+		wantUser      string
+		wantRoles     string
+	}{
+		{"valid token", verifier, "/api/news", "Bearer " + token, http.StatusOK, "alice", "support,admin"},
+		{"lowercase scheme", verifier, "/api/news", "bearer " + token, http.StatusOK, "alice", "support,admin"},
+		{"anonymous", verifier, "/api/news", "", http.StatusOK, "", ""},
+		{"bad token", verifier, "/api/news", "Bearer " + token + "x", http.StatusUnauthorized, "", ""},
+		{"basic scheme", verifier, "/api/news", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized, "", ""},
+		{"required without token", required, "/api/news", "", http.StatusUnauthorized, "", ""},
+		{"required public path", required, "/health", "", http.StatusOK, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotUser, gotRoles, gotContextUser string
+			handler := tc.verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUser = r.Header.Get(UserIDHeader)
+				gotRoles = r.Header.Get(RolesHeader)
+				gotContextUser = UserIDFrom(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set(UserIDHeader, "mallory")
+			req.Header.Set(RolesHeader, "admin")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if tc.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+			if gotUser != tc.wantUser || gotContextUser != tc.wantUser || gotRoles != tc.wantRoles {
+				t.Errorf("forwarded user %q (context %q) and roles %q, want %q and %q", gotUser, gotContextUser, gotRoles, tc.wantUser, tc.wantRoles)
+			}
+		})
+	}
+}
+
+func TestIdentityAndActingUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		roles      string
+		target     string
+		want       string
+		wantErr    error
+		wantStatus int
+	}{
+		{"own user", "alice", "", "alice", "alice", nil, http.StatusOK},
+		{"no target", "alice", "", "", "alice", nil, http.StatusOK},
+		{"another user", "alice", "", "bob", "", ErrForbidden, http.StatusForbidden},
+		{"support for another user", "alice", "viewer, support", "bob", "bob", nil, http.StatusOK},
+		{"admin for another user", "alice", "admin", "bob", "bob", nil, http.StatusOK},
+		{"anonymous", "", "", "bob", "", ErrMissingToken, http.StatusUnauthorized},
+		{"roles without a user", "", "admin", "bob", "", ErrMissingToken, http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctx context.Context
+			rec := httptest.NewRecorder()
+			handler := Identity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx = r.Context()
+				if _, ok := Authorize(w, r, tc.target); ok {
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/users/"+tc.target, nil)
+			if tc.user != "" {
+				req.Header.Set(UserIDHeader, tc.user)
+			}
+			if tc.roles != "" {
+				req.Header.Set(RolesHeader, tc.roles)
+			}
+			handler.ServeHTTP(rec, req)
+
+			got, err := ActingUser(ctx, tc.target)
+			if got != tc.want || !errors.Is(err, tc.wantErr) {
+				t.Errorf("ActingUser = %q, %v; want %q, %v", got, err, tc.want, tc.wantErr)
+			}
+			// gofr answers errors with their StatusCode
+			var coded interface{ StatusCode() int }
+			if err != nil && (!errors.As(err, &coded) || coded.StatusCode() != tc.wantStatus) {
+				t.Errorf("ActingUser error %v has no StatusCode %d", err, tc.wantStatus)
+			}
+			if rec.Code != tc.wantStatus {
+				t.Errorf("Authorize status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package batch serves the gateway's /api/batch endpoint: a client sends
+// several sub-requests in one round trip and gets their responses back in
+// order. Every sub-request goes through the same authentication, rate
+// limiting and routing as a request of its own.
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"personalized-dashboard/shared/metrics"
+)
+
+// Path is where the endpoint is served.
+const Path = "/api/batch"
+
+// maxRequestBytes caps the size of a batch request.
+const maxRequestBytes = 1 << 20
+
+type Config struct {
+	// MaxRequests bounds the number of sub-requests in a batch.
+	MaxRequests int
+	// Concurrency bounds how many sub-requests of a batch run at once.
+	Concurrency int
+	// MaxResponseBytes bounds the body of a single sub-response.
+	MaxResponseBytes int64
+}
+
+func DefaultConfig() Config {
+	return Config{MaxRequests: 20, Concurrency: 4, MaxResponseBytes: 1 << 20}
+}
+
+// ConfigFromEnv reads BATCH_MAX_REQUESTS, BATCH_CONCURRENCY and
+// BATCH_MAX_RESPONSE_BYTES on top of the defaults.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	for name, target := range map[string]*int{
+		"BATCH_MAX_REQUESTS": &cfg.MaxRequests,
+		"BATCH_CONCURRENCY":  &cfg.Concurrency,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return cfg, fmt.Errorf("invalid %s: %q is not a positive number", name, value)
+			}
+			*target = parsed
+		}
+	}
+	if value := os.Getenv("BATCH_MAX_RESPONSE_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid BATCH_MAX_RESPONSE_BYTES: %q is not a byte count", value)
+		}
+		cfg.MaxResponseBytes = parsed
+	}
+
+	return cfg, nil
+}
+
+// Request is a sub-request. Path may carry a query string. Body is sent as
+// JSON. A request runs only after the requests named in DependsOn have
+// succeeded; it needs an ID only to be depended on.
+type Request struct {
+	ID        string            `json:"id,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	DependsOn []string          `json:"depends_on,omitempty"`
+}
+
+// Result is the response to a sub-request. Body is embedded as JSON when the
+// response is JSON and as a string otherwise.
+type Result struct {
+	ID      string          `json:"id,omitempty"`
+	Status  int             `json:"status"`
+	Headers http.Header     `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+type Batcher struct {
+	cfg Config
+}
+
+func New(cfg Config) *Batcher {
+	return &Batcher{cfg: cfg}
+}
+
+// Middleware serves the batch endpoint at Path and passes every other
+// request to next. Sub-requests are served by next too, so it belongs in
+// front of the authentication, rate limiting and routing middleware.
+func (b *Batcher) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+		b.serve(w, r, next)
+	})
+}
+
+func (b *Batcher) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var requests []Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&requests); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid batch: %v", err))
+		return
+	}
+	if err := b.validate(requests); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid batch: %v", err))
+		return
+	}
+
+	metrics.SetRoute(r.Context(), Path)
+	results := b.run(r, requests, next)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// validate checks the sub-requests and their dependencies, which must name
+// earlier requests, so there are no cycles.
+func (b *Batcher) validate(requests []Request) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("no requests")
+	}
+	if len(requests) > b.cfg.MaxRequests {
+		return fmt.Errorf("%d requests exceed the limit of %d", len(requests), b.cfg.MaxRequests)
+	}
+
+	seen := make(map[string]bool, len(requests))
+	for i := range requests {
+		req := &requests[i]
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+		req.Method = strings.ToUpper(req.Method)
+		if !strings.HasPrefix(req.Path, "/") {
+			return fmt.Errorf("request %d: path must start with /", i)
+		}
+		if strings.SplitN(req.Path, "?", 2)[0] == Path {
+			return fmt.Errorf("request %d: batches cannot be nested", i)
+		}
+		for _, dependency := range req.DependsOn {
+			if !seen[dependency] {
+				return fmt.Errorf("request %d: depends on %q, which is not an earlier request", i, dependency)
+			}
+		}
+		if req.ID != "" {
+			if seen[req.ID] {
+				return fmt.Errorf("request %d: duplicate id %q", i, req.ID)
+			}
+			seen[req.ID] = true
+		}
+	}
+	return nil
+}
+
+// run serves the sub-requests, at most Concurrency at a time, each once the
+// requests it depends on are done. A request whose dependency failed is not
+// sent and gets 424 Failed Dependency.
+func (b *Batcher) run(r *http.Request, requests []Request, next http.Handler) []Result {
+	results := make([]Result, len(requests))
+	done := make([]chan struct{}, len(requests))
+	index := make(map[string]int, len(requests))
+	for i, req := range requests {
+		done[i] = make(chan struct{})
+		if req.ID != "" {
+			index[req.ID] = i
+		}
+	}
+
+	slots := make(chan struct{}, b.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req Request) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, dependency := range req.DependsOn {
+				j := index[dependency]
+				<-done[j]
+				if status := results[j].Status; status >= 400 {
+					results[i] = failure(req.ID, http.StatusFailedDependency, fmt.Sprintf("dependency %q failed with status %d", dependency, status))
+					return
+				}
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-r.Context().Done():
+				results[i] = failure(req.ID, http.StatusServiceUnavailable, "batch cancelled")
+				return
+			}
+			defer func() { <-slots }()
+
+			results[i] = b.serveOne(r, req, next)
+		}(i, req)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// serveOne sends req through next as if the client had sent it on its own:
+// with the batch's headers and address, and its own method, path, body and
+// extra headers.
+func (b *Batcher) serveOne(r *http.Request, req Request, next http.Handler) Result {
+	// Sub-requests run concurrently and name their own routes
+	ctx := metrics.DetachRoute(r.Context())
+	sub, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return failure(req.ID, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	} else {
+		sub.Header.Del("Content-Type")
+	}
+	for name, value := range req.Headers {
+		sub.Header.Set(name, value)
+	}
+	sub.Host = r.Host
+	sub.RemoteAddr = r.RemoteAddr
+	sub.RequestURI = req.Path
+
+	rec := newRecorder(b.cfg.MaxResponseBytes)
+	next.ServeHTTP(rec, sub)
+	if rec.overflow {
+		return failure(req.ID, http.StatusBadGateway, fmt.Sprintf("response exceeds %d bytes", b.cfg.MaxResponseBytes))
+	}
+
+	return Result{ID: req.ID, Status: rec.status, Headers: rec.header, Body: encodeBody(rec.header, rec.body.Bytes())}
+}
+
+// encodeBody embeds JSON bodies as they are and everything else as a
+// string.
+func encodeBody(header http.Header, body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if strings.Contains(header.Get("Content-Type"), "json") && json.Valid(body) {
+		return bytes.TrimSpace(body)
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+func failure(id string, status int, message string) Result {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return Result{ID: id, Status: status, Body: body}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,223 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echo answers every sub-request with what it received; /fail/<status>
+// answers with that status.
+func echo(w http.ResponseWriter, r *http.Request) {
+	var status int
+	if _, err := fmt.Sscanf(r.URL.Path, "/fail/%d", &status); err == nil {
+		w.WriteHeader(status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"method":        r.Method,
+		"uri":           r.URL.RequestURI(),
+		"body":          string(body),
+		"authorization": r.Header.Get("Authorization"),
+		"x_trace":       r.Header.Get("X-Trace"),
+		"remote":        r.RemoteAddr,
+	})
+}
+
+func post(t *testing.T, handler http.Handler, body string) (*httptest.ResponseRecorder, []Result) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	req.RemoteAddr = "203.0.113.7:4000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var results []Result
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+			t.Fatalf("failed to decode results: %v", err)
+		}
+	}
+	return rec, results
+}
+
+func TestBatch(t *testing.T) {
+	handler := New(DefaultConfig()).Middleware(http.HandlerFunc(echo))
+
+	tests := []struct {
+		name         string
+		batch        string
+		wantStatuses []int
+	}{
+		{"single", `[{"path": "/api/news?limit=2"}]`, []int{200}},
+		{"dependency succeeded", `[{"id": "a", "path": "/api/news"}, {"path": "/api/jobs", "depends_on": ["a"]}]`, []int{200, 200}},
+		{"dependency failed", `[{"id": "a", "path": "/fail/500"}, {"id": "b", "path": "/api/jobs", "depends_on": ["a"]}, {"path": "/api/videos", "depends_on": ["b"]}]`, []int{500, 424, 424}},
+		{"independent of a failure", `[{"id": "a", "path": "/fail/404"}, {"path": "/api/jobs"}]`, []int{404, 200}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, results := post(t, handler, tc.batch)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if len(results) != len(tc.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(results), len(tc.wantStatuses))
+			}
+			for i, want := range tc.wantStatuses {
+				if results[i].Status != want {
+					t.Errorf("result %d: status = %d (%s), want %d", i, results[i].Status, results[i].Body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchSubRequests(t *testing.T) {
+	handler := New(DefaultConfig()).Middleware(http.HandlerFunc(echo))
+	_, results := post(t, handler, `[
+		{"id": "create", "method": "post", "path": "/api/users?x=1", "headers": {"X-Trace": "t1"}, "body": {"name": "a"}},
+		{"path": "/plain/text"}
+	]`)
+
+	var got map[string]string
+	if err := json.Unmarshal(results[0].Body, &got); err != nil {
+		t.Fatalf("JSON body not embedded: %s", results[0].Body)
+	}
+	want := map[string]string{
+		"method":        http.MethodPost,
+		"uri":           "/api/users?x=1",
+		"body":          `{"name": "a"}`,
+		"authorization": "Bearer token",
+		"x_trace":       "t1",
+		"remote":        "203.0.113.7:4000",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("sub-request %s = %q, want %q", key, got[key], value)
+		}
+	}
+	if results[0].ID != "create" {
+		t.Errorf("id = %q, want create", results[0].ID)
+	}
+}
+
+func TestBatchEncodesNonJSONBodies(t *testing.T) {
+	handler := New(DefaultConfig()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("not found\n"))
+	}))
+	_, results := post(t, handler, `[{"path": "/x"}]`)
+	if string(results[0].Body) != `"not found\n"` {
+		t.Errorf("body = %s, want a JSON string", results[0].Body)
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	cfg := Config{MaxRequests: 3, Concurrency: 2, MaxResponseBytes: 64}
+
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	order := []string{}
+	handler := New(cfg).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/big" {
+			w.Write([]byte(strings.Repeat("x", 65)))
+		}
+	}))
+
+	_, results := post(t, handler, `[{"id": "a", "path": "/a"}, {"path": "/big"}, {"path": "/c", "depends_on": ["a"]}]`)
+	if peak.Load() > 2 {
+		t.Errorf("%d sub-requests ran at once, want at most 2", peak.Load())
+	}
+	if results[1].Status != http.StatusBadGateway {
+		t.Errorf("oversized response: status = %d, want 502", results[1].Status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if last := order[len(order)-1]; last != "/c" {
+		t.Errorf("order = %v, want /c after the request it depends on", order)
+	}
+}
+
+func TestBatchValidation(t *testing.T) {
+	handler := New(Config{MaxRequests: 2, Concurrency: 1, MaxResponseBytes: 1 << 10}).Middleware(http.HandlerFunc(echo))
+
+	tests := []struct {
+		name    string
+		batch   string
+		wantErr string
+	}{
+		{"empty", `[]`, "no requests"},
+		{"too many", `[{"path": "/a"}, {"path": "/b"}, {"path": "/c"}]`, "exceed the limit of 2"},
+		{"relative path", `[{"path": "api/news"}]`, "path must start with /"},
+		{"nested", `[{"path": "/api/batch?x=1"}]`, "cannot be nested"},
+		{"unknown dependency", `[{"path": "/a", "depends_on": ["b"]}]`, "depends on"},
+		{"forward dependency", `[{"path": "/a", "depends_on": ["b"]}, {"id": "b", "path": "/b"}]`, "not an earlier request"},
+		{"self dependency", `[{"id": "a", "path": "/a", "depends_on": ["a"]}]`, "not an earlier request"},
+		{"duplicate id", `[{"id": "a", "path": "/a"}, {"id": "a", "path": "/b"}]`, "duplicate id"},
+		{"unknown field", `[{"path": "/a", "url": "/b"}]`, "unknown field"},
+		{"not an array", `{"path": "/a"}`, "invalid batch"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, _ := post(t, handler, tc.batch)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.wantErr) {
+				t.Errorf("got %d %s, want 400 mentioning %q", rec.Code, rec.Body, tc.wantErr)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET %s = %d, want 405 with Allow: POST", Path, rec.Code)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{"defaults", nil, DefaultConfig(), false},
+		{"overrides", map[string]string{"BATCH_MAX_REQUESTS": "5", "BATCH_CONCURRENCY": "2", "BATCH_MAX_RESPONSE_BYTES": "100"}, Config{MaxRequests: 5, Concurrency: 2, MaxResponseBytes: 100}, false},
+		{"zero concurrency", map[string]string{"BATCH_CONCURRENCY": "0"}, Config{}, true},
+		{"bad size", map[string]string{"BATCH_MAX_RESPONSE_BYTES": "1MB"}, Config{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"BATCH_MAX_REQUESTS", "BATCH_CONCURRENCY", "BATCH_MAX_RESPONSE_BYTES"} {
+				t.Setenv(name, tc.env[name])
+			}
+			cfg, err := ConfigFromEnv()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && cfg != tc.want {
+				t.Errorf("config = %+v, want %+v", cfg, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+var errTooLarge = errors.New("response too large")
+
+// recorder captures a sub-response, keeping at most limit bytes of its body.
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int64
+	overflow    bool
+}
+
+// newRecorder starts at 200, the status of a handler that never sets one.
+func newRecorder(limit int64) *recorder {
+	return &recorder{header: http.Header{}, status: http.StatusOK, limit: limit}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	if int64(r.body.Len()+len(p)) > r.limit {
+		r.overflow = true
+		return 0, errTooLarge
+	}
+	return r.body.Write(p)
+}
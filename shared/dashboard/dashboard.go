@@ -0,0 +1,314 @@
+// Package dashboard builds the composite /api/dashboard document: it calls
+// every content vertical concurrently, each under its own deadline, and
+// returns whatever came back. A slow or failing section is reported in the
+// document instead of failing the page.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/tracing"
+)
+
+// Section statuses.
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
+	StatusSkipped = "skipped"
+)
+
+// maxSectionBytes caps how much of a section response is read.
+const maxSectionBytes = 10 << 20
+
+// Section is one vertical of the dashboard.
+type Section struct {
+	Name string
+	// BaseURL returns the current base URL of the section's service.
+	BaseURL func() string
+	// Path may contain {user_id}, which is filled in with the current user.
+	Path string
+	// Params are the query parameters forwarded to the section. Clients pass
+	// them as <section>.<param>, e.g. news.category=technology.
+	Params []string
+	// NeedsUser skips the section for anonymous requests.
+	NeedsUser bool
+	Timeout   time.Duration
+}
+
+// DefaultTimeout is the deadline of a section without a timeout of its own.
+const DefaultTimeout = 3 * time.Second
+
+// SectionsFromEnv returns all verticals, with the service URLs taken from
+// upstreams, such as the router's UpstreamURLs, and the deadlines from
+// DASHBOARD_TIMEOUT and DASHBOARD_SECTION_TIMEOUTS (e.g.
+// "recommendations=5s,nfts=2s").
+func SectionsFromEnv(upstreams func() map[string]string) ([]Section, error) {
+	baseURL := func(name string) func() string {
+		return func() string { return upstreams()[name] }
+	}
+	sections := []Section{
+		{Name: "news", BaseURL: baseURL("news"), Path: "/api/news", Params: []string{"category", "source"}},
+		{Name: "jobs", BaseURL: baseURL("jobs"), Path: "/api/jobs", Params: []string{"category", "location", "source"}},
+		{Name: "videos", BaseURL: baseURL("videos"), Path: "/api/videos", Params: []string{"category", "source"}},
+		{Name: "deals", BaseURL: baseURL("deals"), Path: "/api/deals", Params: []string{"category", "source"}},
+		{Name: "movies", BaseURL: baseURL("movies"), Path: "/api/movies", Params: []string{"category"}},
+		{Name: "food", BaseURL: baseURL("food"), Path: "/api/food", Params: []string{"category"}},
+		// Recommendations fan out to the verticals themselves, so they get longer
+		{Name: "recommendations", BaseURL: baseURL("recommendation"), Path: "/api/recommendations", Timeout: 5 * time.Second},
+		{Name: "nfts", BaseURL: baseURL("nft"), Path: "/api/nft/{user_id}", NeedsUser: true},
+	}
+
+	timeout := DefaultTimeout
+	if value := os.Getenv("DASHBOARD_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DASHBOARD_TIMEOUT: %v", err)
+		}
+		timeout = parsed
+	}
+	for i := range sections {
+		if sections[i].Timeout == 0 {
+			sections[i].Timeout = timeout
+		}
+	}
+
+	if value := os.Getenv("DASHBOARD_SECTION_TIMEOUTS"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			name, durationValue, found := strings.Cut(strings.TrimSpace(pair), "=")
+			duration, err := time.ParseDuration(durationValue)
+			if !found || err != nil {
+				return nil, fmt.Errorf("invalid DASHBOARD_SECTION_TIMEOUTS entry %q", pair)
+			}
+
+			known := false
+			for i := range sections {
+				if sections[i].Name == name {
+					sections[i].Timeout = duration
+					known = true
+				}
+			}
+			if !known {
+				return nil, fmt.Errorf("invalid DASHBOARD_SECTION_TIMEOUTS entry %q: unknown section", pair)
+			}
+		}
+	}
+
+	return sections, nil
+}
+
+// Request carries what the sections need from the client request. Param
+// reads a query parameter.
+type Request struct {
+	UserID    string
+	RequestID string
+	Param     func(string) string
+}
+
+// SectionResult is the outcome of one section. Data is the section's JSON
+// response as the service returned it.
+type SectionResult struct {
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	DurationMS int64       `json:"duration_ms"`
+}
+
+// Document is the /api/dashboard response. Partial is set when any section
+// is missing.
+type Document struct {
+	UserID      string                   `json:"user_id,omitempty"`
+	GeneratedAt time.Time                `json:"generated_at"`
+	Partial     bool                     `json:"partial"`
+	Sections    map[string]SectionResult `json:"sections"`
+}
+
+// InvalidSectionError rejects a ?sections= list naming an unknown section.
+type InvalidSectionError struct {
+	Name string
+}
+
+func (e *InvalidSectionError) Error() string {
+	return fmt.Sprintf("unknown dashboard section: %s", e.Name)
+}
+
+func (e *InvalidSectionError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+type Aggregator struct {
+	sections []Section
+	client   *http.Client
+}
+
+func New(sections []Section) *Aggregator {
+	return &Aggregator{sections: sections, client: &http.Client{Transport: tracing.NewTransport(http.DefaultTransport)}}
+}
+
+// Build fetches the sections listed in the sections parameter, or all of
+// them, concurrently and waits for each to answer or reach its deadline.
+func (a *Aggregator) Build(ctx context.Context, req Request) (*Document, error) {
+	sections, err := a.selected(req.Param("sections"))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		UserID:      req.UserID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]SectionResult, len(sections)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, section := range sections {
+		wg.Add(1)
+		go func(section Section) {
+			defer wg.Done()
+			result := a.fetch(ctx, section, req)
+
+			mu.Lock()
+			defer mu.Unlock()
+			doc.Sections[section.Name] = result
+			if result.Status != StatusOK {
+				doc.Partial = true
+			}
+		}(section)
+	}
+	wg.Wait()
+
+	return doc, nil
+}
+
+func (a *Aggregator) selected(names string) ([]Section, error) {
+	if names == "" {
+		return a.sections, nil
+	}
+
+	var sections []Section
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, section := range a.sections {
+			if section.Name == name {
+				sections = append(sections, section)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &InvalidSectionError{Name: name}
+		}
+	}
+	return sections, nil
+}
+
+func (a *Aggregator) fetch(ctx context.Context, section Section, req Request) SectionResult {
+	start := time.Now()
+	result := a.call(ctx, section, req)
+	result.DurationMS = time.Since(start).Milliseconds()
+	if result.Status == StatusError || result.Status == StatusTimeout {
+		tracing.Logf(ctx, "Warning: dashboard section %s failed: %s", section.Name, result.Error)
+	}
+	return result
+}
+
+func (a *Aggregator) call(ctx context.Context, section Section, req Request) SectionResult {
+	if section.NeedsUser && req.UserID == "" {
+		return SectionResult{Status: StatusSkipped, Error: "requires a signed-in user"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, section.Timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, section.url(req), nil)
+	if err != nil {
+		return SectionResult{Status: StatusError, Error: fmt.Sprintf("failed to create request: %v", err)}
+	}
+	if req.UserID != "" {
+		httpReq.Header.Set(auth.UserIDHeader, req.UserID)
+	}
+	if req.RequestID != "" {
+		httpReq.Header.Set("X-Request-ID", req.RequestID)
+	}
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return SectionResult{Status: StatusTimeout, Error: fmt.Sprintf("no response within %s", section.Timeout)}
+		}
+		return SectionResult{Status: StatusError, Error: fmt.Sprintf("failed to make request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSectionBytes))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return SectionResult{Status: StatusTimeout, Error: fmt.Sprintf("no response within %s", section.Timeout)}
+		}
+		return SectionResult{Status: StatusError, Error: fmt.Sprintf("failed to read response: %v", err)}
+	}
+	if resp.StatusCode >= 400 {
+		return SectionResult{Status: StatusError, StatusCode: resp.StatusCode, Error: upstreamError(resp.StatusCode, body)}
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return SectionResult{Status: StatusError, StatusCode: resp.StatusCode, Error: fmt.Sprintf("failed to parse response: %v", err)}
+	}
+	return SectionResult{Status: StatusOK, Data: data}
+}
+
+// url fills in the path and forwards the section's parameters. The user only
+// travels in X-User-ID, never in the query.
+func (s Section) url(req Request) string {
+	path := strings.ReplaceAll(s.Path, "{user_id}", url.PathEscape(req.UserID))
+
+	query := url.Values{}
+	for _, param := range s.Params {
+		if value := req.Param(s.Name + "." + param); value != "" {
+			query.Set(param, value)
+		}
+	}
+	if len(query) == 0 {
+		return s.BaseURL() + path
+	}
+	return s.BaseURL() + path + "?" + query.Encode()
+}
+
+// upstreamError picks the error message out of a service error response.
+func upstreamError(status int, body []byte) string {
+	var payload struct {
+		Error   interface{} `json:"error"`
+		Message string      `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		switch e := payload.Error.(type) {
+		case string:
+			return e
+		case map[string]interface{}:
+			if message, ok := e["message"].(string); ok {
+				return message
+			}
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && len(text) <= 200 {
+		return text
+	}
+	return http.StatusText(status)
+}
@@ -0,0 +1,218 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+)
+
+// upstream records the requests of every section it serves. /slow sleeps
+// past any test deadline and /broken answers 502.
+type upstream struct {
+	mu       sync.Mutex
+	requests map[string]*http.Request
+}
+
+func newUpstream(t *testing.T) (*upstream, string) {
+	t.Helper()
+	u := &upstream{requests: make(map[string]*http.Request)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.mu.Lock()
+		u.requests[r.URL.Path] = r
+		u.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error": "provider down"}`))
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"path": r.URL.Path})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return u, server.URL
+}
+
+func (u *upstream) request(path string) *http.Request {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.requests[path]
+}
+
+func testSections(baseURL string) []Section {
+	base := func() string { return baseURL }
+	return []Section{
+		{Name: "news", BaseURL: base, Path: "/api/news", Params: []string{"category"}, Timeout: time.Second},
+		{Name: "nfts", BaseURL: base, Path: "/api/nft/{user_id}", NeedsUser: true, Timeout: time.Second},
+		{Name: "slow", BaseURL: base, Path: "/slow", Timeout: 20 * time.Millisecond},
+		{Name: "broken", BaseURL: base, Path: "/broken", Timeout: time.Second},
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		query      string
+		wantStatus int
+		want       map[string]string
+	}{
+		{
+			name:       "signed in",
+			user:       "alice",
+			query:      "?news.category=tech",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"news": StatusOK, "nfts": StatusOK, "slow": StatusTimeout, "broken": StatusError},
+		},
+		{
+			name:       "anonymous ignores user_id",
+			query:      "?user_id=alice",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"news": StatusOK, "nfts": StatusSkipped, "slow": StatusTimeout, "broken": StatusError},
+		},
+		{
+			name:       "selected sections",
+			user:       "alice",
+			query:      "?sections=news,+nfts",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"news": StatusOK, "nfts": StatusOK},
+		},
+		{
+			name:       "unknown section",
+			query:      "?sections=news,weather",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, baseURL := newUpstream(t)
+			handler := auth.Identity(New(testSections(baseURL)))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/dashboard"+tc.query, nil)
+			if tc.user != "" {
+				req.Header.Set(auth.UserIDHeader, tc.user)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.want == nil {
+				return
+			}
+
+			var doc Document
+			if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("failed to decode document: %v", err)
+			}
+			if doc.UserID != tc.user {
+				t.Errorf("user_id = %q, want %q", doc.UserID, tc.user)
+			}
+			if len(doc.Sections) != len(tc.want) {
+				t.Errorf("got %d sections, want %d", len(doc.Sections), len(tc.want))
+			}
+			partial := false
+			for name, status := range tc.want {
+				if got := doc.Sections[name].Status; got != status {
+					t.Errorf("section %s: status = %q (%s), want %q", name, got, doc.Sections[name].Error, status)
+				}
+				partial = partial || status != StatusOK
+			}
+			if doc.Partial != partial {
+				t.Errorf("partial = %v, want %v", doc.Partial, partial)
+			}
+			if broken, ok := doc.Sections["broken"]; ok && (broken.StatusCode != http.StatusBadGateway || broken.Error != "provider down") {
+				t.Errorf("broken section = %+v, want the upstream status and error", broken)
+			}
+
+			news := upstream.request("/api/news")
+			if news == nil {
+				t.Fatal("news section was not requested")
+			}
+			if news.URL.Query().Get("user_id") != "" {
+				t.Errorf("news query = %q, want no user_id", news.URL.RawQuery)
+			}
+			if got := news.Header.Get(auth.UserIDHeader); got != tc.user {
+				t.Errorf("news X-User-ID = %q, want %q", got, tc.user)
+			}
+			if strings.Contains(tc.query, "news.category") && news.URL.Query().Get("category") != "tech" {
+				t.Errorf("news query = %q, want category=tech", news.URL.RawQuery)
+			}
+			if tc.user != "" && upstream.request("/api/nft/"+tc.user) == nil {
+				t.Errorf("nfts section did not request /api/nft/%s", tc.user)
+			}
+		})
+	}
+}
+
+func TestSectionURL(t *testing.T) {
+	section := Section{BaseURL: func() string { return "http://nft" }, Path: "/api/nft/{user_id}", Params: []string{"status"}, Name: "nfts"}
+	params := map[string]string{"nfts.status": "minted", "status": "ignored"}
+
+	got := section.url(Request{UserID: "a/b", Param: func(name string) string { return params[name] }})
+	if want := "http://nft/api/nft/a%2Fb?status=minted"; got != want {
+		t.Errorf("url = %q, want %q", got, want)
+	}
+}
+
+func TestSectionsFromEnv(t *testing.T) {
+	t.Setenv("DASHBOARD_TIMEOUT", "2s")
+	t.Setenv("DASHBOARD_SECTION_TIMEOUTS", "videos=1s")
+
+	upstreams := map[string]string{"news": "http://news", "recommendation": "http://recommendation", "nft": "http://nft"}
+	sections, err := SectionsFromEnv(func() map[string]string { return upstreams })
+	if err != nil {
+		t.Fatalf("SectionsFromEnv: %v", err)
+	}
+	want := map[string]time.Duration{"news": 2 * time.Second, "videos": time.Second, "recommendations": 5 * time.Second}
+	wantURLs := map[string]string{"news": "http://news", "recommendations": "http://recommendation", "nfts": "http://nft"}
+	for _, section := range sections {
+		if timeout, ok := want[section.Name]; ok && section.Timeout != timeout {
+			t.Errorf("section %s: timeout = %v, want %v", section.Name, section.Timeout, timeout)
+		}
+		if baseURL, ok := wantURLs[section.Name]; ok && section.BaseURL() != baseURL {
+			t.Errorf("section %s: base URL = %q, want %q", section.Name, section.BaseURL(), baseURL)
+		}
+	}
+
+	// A reloaded route table moves the sections with it
+	upstreams = map[string]string{"news": "http://news-2"}
+	if got := sections[0].BaseURL(); got != "http://news-2" {
+		t.Errorf("news base URL after reload = %q, want http://news-2", got)
+	}
+
+	for _, value := range []string{"weather=1s", "videos", "videos=soon"} {
+		t.Setenv("DASHBOARD_SECTION_TIMEOUTS", value)
+		if _, err := SectionsFromEnv(func() map[string]string { return upstreams }); err == nil {
+			t.Errorf("SectionsFromEnv with DASHBOARD_SECTION_TIMEOUTS=%s succeeded", value)
+		}
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	_, baseURL := newUpstream(t)
+	aggregator := New(testSections(baseURL)[:1])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doc, err := aggregator.Build(ctx, Request{Param: func(string) string { return "" }})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if doc.Sections["news"].Status == StatusOK || !doc.Partial {
+		t.Errorf("Build with a cancelled context = %+v, want a failed section", doc)
+	}
+}
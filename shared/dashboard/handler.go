@@ -0,0 +1,35 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/tracing"
+)
+
+// ServeHTTP serves /api/dashboard for net/http entrypoints. The user comes
+// from the auth middleware; anonymous requests skip the sections that need
+// one.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	doc, err := a.Build(r.Context(), Request{
+		UserID:    auth.UserIDFrom(r.Context()),
+		RequestID: tracing.RequestIDFrom(r.Context()),
+		Param:     query.Get,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status := http.StatusInternalServerError
+		var invalid *InvalidSectionError
+		if errors.As(err, &invalid) {
+			status = invalid.StatusCode()
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(doc)
+}
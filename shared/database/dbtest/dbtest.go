@@ -0,0 +1,26 @@
+// Package dbtest opens throwaway databases for tests.
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"personalized-dashboard/shared/database"
+)
+
+// SQLite opens a migrated SQLite database in a file of its own, closed and
+// removed when the test ends. It sets the DATABASE_* variables for the test,
+// so tests using it cannot run in parallel.
+func SQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	t.Setenv("DATABASE_DRIVER", "sqlite")
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "test.db"))
+	db, err := database.SetupDatabase()
+	if err != nil {
+		t.Fatalf("failed to set up SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
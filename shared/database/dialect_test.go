@@ -0,0 +1,48 @@
+package database
+
+import "testing"
+
+func TestParseDialect(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Dialect
+		wantErr bool
+	}{
+		{"", Postgres, false},
+		{"postgresql", Postgres, false},
+		{"PG", Postgres, false},
+		{"sqlite3", SQLite, false},
+		{"SQLite", SQLite, false},
+		{"mysql", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDialect(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDialect(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{"postgres unchanged", Postgres, "SELECT $1, $2", "SELECT $1, $2"},
+		{"sqlite placeholders", SQLite, "SELECT * FROM t WHERE a = $1 AND b = $12", "SELECT * FROM t WHERE a = ?1 AND b = ?12"},
+		{"quoted dollar kept", SQLite, "SELECT '$1 off' WHERE a = $1", "SELECT '$1 off' WHERE a = ?1"},
+		{"lone dollar kept", SQLite, "SELECT '$' || $1, $x", "SELECT '$' || ?1, $x"},
+		{"no placeholders", SQLite, "SELECT 1", "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Rebind(tt.query); got != tt.want {
+				t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		err      string
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"m/0010_late.up.sql":    {Data: []byte("late")},
+				"m/0002_early.up.sql":   {Data: []byte("early")},
+				"m/0002_early.down.sql": {Data: []byte("undo early")},
+				"m/README.md":           {Data: []byte("ignored")},
+			},
+			versions: []int64{2, 10},
+		},
+		{
+			name:  "name without version",
+			files: fstest.MapFS{"m/initial.up.sql": {Data: []byte("x")}},
+			err:   "invalid migration file name",
+		},
+		{
+			name:  "version not a number",
+			files: fstest.MapFS{"m/one_initial.up.sql": {Data: []byte("x")}},
+			err:   "invalid migration version",
+		},
+		{
+			name: "version used twice",
+			files: fstest.MapFS{
+				"m/0001_a.up.sql": {Data: []byte("x")},
+				"m/0001_b.up.sql": {Data: []byte("y")},
+			},
+			err: "used by both",
+		},
+		{
+			name:  "down without up",
+			files: fstest.MapFS{"m/0001_a.down.sql": {Data: []byte("x")}},
+			err:   "has no up file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files, "m")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations: %v", err)
+			}
+
+			var versions []int64
+			for _, migration := range migrations {
+				versions = append(versions, migration.Version)
+			}
+			if len(versions) != len(tt.versions) {
+				t.Fatalf("versions = %v, want %v", versions, tt.versions)
+			}
+			for i := range versions {
+				if versions[i] != tt.versions[i] {
+					t.Fatalf("versions = %v, want %v", versions, tt.versions)
+				}
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations checks that both dialects ship an up and a down
+// file for every migration.
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, SQLite} {
+		migrations, err := loadMigrations(migrationFiles, "migrations/"+string(dialect))
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		for _, migration := range migrations {
+			if migration.Down == "" {
+				t.Errorf("%s: migration %d_%s has no down file", dialect, migration.Version, migration.Name)
+			}
+		}
+	}
+}
+
+func openSQLite(t *testing.T, path string) *Migrator {
+	t.Helper()
+
+	t.Setenv("DATABASE_DRIVER", "sqlite")
+	t.Setenv("DATABASE_URL", path)
+	db, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	return migrator
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	migrator := openSQLite(t, filepath.Join(t.TempDir(), "migrate.db"))
+	total := len(migrator.migrations)
+
+	steps := []struct {
+		name    string
+		run     func() (int, error)
+		changed int
+		applied int
+	}{
+		{"up from scratch", func() (int, error) { return migrator.Up(ctx) }, total, total},
+		{"up again", func() (int, error) { return migrator.Up(ctx) }, 0, total},
+		{"down one", func() (int, error) { return migrator.Down(ctx, 1) }, 1, total - 1},
+		{"up the rest", func() (int, error) { return migrator.Up(ctx) }, 1, total},
+		{"down all", func() (int, error) { return migrator.Down(ctx, total+1) }, total, 0},
+		{"up after down", func() (int, error) { return migrator.Up(ctx) }, total, total},
+	}
+
+	for _, step := range steps {
+		changed, err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if changed != step.changed {
+			t.Errorf("%s: changed %d migrations, want %d", step.name, changed, step.changed)
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("%s: Status: %v", step.name, err)
+		}
+		applied := 0
+		for i, status := range statuses {
+			if status.Applied {
+				applied++
+				if status.AppliedAt == nil {
+					t.Errorf("%s: migration %d applied without a time", step.name, status.Version)
+				}
+			}
+			// Applied migrations are always a prefix
+			if status.Applied != (i < step.applied) {
+				t.Errorf("%s: migration %d applied = %v", step.name, status.Version, status.Applied)
+			}
+		}
+		if applied != step.applied {
+			t.Errorf("%s: %d migrations applied, want %d", step.name, applied, step.applied)
+		}
+	}
+}
+
+// TestMigratorConcurrentUp runs several migrators against one database at
+// once; the lock must let exactly one of them apply each migration.
+func TestMigratorConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "concurrent.db")
+
+	migrators := make([]*Migrator, 4)
+	for i := range migrators {
+		migrators[i] = openSQLite(t, path)
+	}
+
+	var wg sync.WaitGroup
+	applied := make([]int, len(migrators))
+	errs := make([]error, len(migrators))
+	for i, migrator := range migrators {
+		wg.Add(1)
+		go func(i int, migrator *Migrator) {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up(ctx)
+		}(i, migrator)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range migrators {
+		if errs[i] != nil {
+			t.Fatalf("migrator %d: %v", i, errs[i])
+		}
+		total += applied[i]
+	}
+	if want := len(migrators[0].migrations); total != want {
+		t.Errorf("migrations applied in total = %d, want %d", total, want)
+	}
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS nft_activities;
+DROP TABLE IF EXISTS nft_coupons;
+DROP TABLE IF EXISTS recommendations;
+DROP TABLE IF EXISTS deals;
+DROP TABLE IF EXISTS videos;
+DROP TABLE IF EXISTS job_listings;
+DROP TABLE IF EXISTS news_articles;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS user_behaviors;
+DROP TABLE IF EXISTS users;
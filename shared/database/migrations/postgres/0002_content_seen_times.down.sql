@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_deals_last_seen;
+DROP INDEX IF EXISTS idx_videos_last_seen;
+DROP INDEX IF EXISTS idx_jobs_last_seen;
+DROP INDEX IF EXISTS idx_news_last_seen;
+
+ALTER TABLE deals DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS first_seen_at;
+ALTER TABLE videos DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE videos DROP COLUMN IF EXISTS first_seen_at;
+ALTER TABLE job_listings DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE job_listings DROP COLUMN IF EXISTS first_seen_at;
+ALTER TABLE news_articles DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE news_articles DROP COLUMN IF EXISTS first_seen_at;
@@ -0,0 +1,23 @@
+-- first_seen_at records when ingestion first stored a URL; last_seen_at is
+-- refreshed every time a provider returns it again.
+ALTER TABLE news_articles ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE news_articles ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE job_listings ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE job_listings ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE news_articles SET first_seen_at = created_at, last_seen_at = created_at WHERE created_at IS NOT NULL;
+UPDATE job_listings SET first_seen_at = created_at, last_seen_at = created_at WHERE created_at IS NOT NULL;
+UPDATE videos SET first_seen_at = created_at, last_seen_at = created_at WHERE created_at IS NOT NULL;
+UPDATE deals SET first_seen_at = created_at, last_seen_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_news_last_seen ON news_articles(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_last_seen ON job_listings(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_videos_last_seen ON videos(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_deals_last_seen ON deals(last_seen_at);
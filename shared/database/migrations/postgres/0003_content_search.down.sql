@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_deals_search;
+DROP INDEX IF EXISTS idx_videos_search;
+DROP INDEX IF EXISTS idx_jobs_search;
+DROP INDEX IF EXISTS idx_news_search;
+
+ALTER TABLE deals DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE videos DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE job_listings DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE news_articles DROP COLUMN IF EXISTS search_vector;
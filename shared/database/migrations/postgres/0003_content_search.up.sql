@@ -0,0 +1,30 @@
+-- Title matches rank above description matches. The vectors are generated
+-- columns, so ingestion keeps them current without triggers.
+ALTER TABLE news_articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+	) STORED;
+
+ALTER TABLE job_listings ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+	) STORED;
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+	) STORED;
+
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+		setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_news_search ON news_articles USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_jobs_search ON job_listings USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_videos_search ON videos USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_deals_search ON deals USING GIN (search_vector);
@@ -0,0 +1,17 @@
+-- Deals past valid_until are archived by the retention sweeper rather than
+-- deleted; archived rows are hidden from listings and search.
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+-- Daily per-user counts of behavior events old enough to be swept.
+CREATE TABLE IF NOT EXISTS user_behavior_aggregates (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	action VARCHAR(50) NOT NULL,
+	category VARCHAR(100) NOT NULL,
+	day DATE NOT NULL,
+	events INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, action, category, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deals_valid_until ON deals(valid_until);
+CREATE INDEX IF NOT EXISTS idx_nft_coupons_expires_at ON nft_coupons(expires_at);
+CREATE INDEX IF NOT EXISTS idx_user_behaviors_timestamp ON user_behaviors(timestamp);
@@ -0,0 +1,27 @@
+-- Append-only record of user, preference and coupon mutations. user_id and
+-- target_id are free text because not every service uses UUIDs yet.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	actor VARCHAR(255) NOT NULL,
+	action VARCHAR(100) NOT NULL,
+	target_type VARCHAR(50) NOT NULL,
+	target_id VARCHAR(255) NOT NULL,
+	user_id VARCHAR(255),
+	request_id VARCHAR(255),
+	changes JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_occurred ON audit_log(user_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred ON audit_log(occurred_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+	BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,10 @@
+-- Gateway rate limit buckets shared by all replicas. tat is the Unix time
+-- (in seconds) at which the bucket is full again; allowed reports whether
+-- the last request took a token.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+	key TEXT PRIMARY KEY,
+	tat DOUBLE PRECISION NOT NULL,
+	allowed INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_tat ON rate_limit_buckets(tat);
@@ -0,0 +1,124 @@
+-- SQLite has no UUID, array or JSONB types: ids are generated by the
+-- application, TEXT[] columns hold Postgres-style array literals and JSONB
+-- columns hold JSON text.
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY NOT NULL,
+	email VARCHAR(255) UNIQUE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	interests TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_behaviors (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+	action VARCHAR(50) NOT NULL,
+	content_id VARCHAR(255) NOT NULL,
+	category VARCHAR(100) NOT NULL,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+	user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	explicit_interests TEXT,
+	behavioral_score TEXT,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS news_articles (
+	id TEXT PRIMARY KEY NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	url TEXT UNIQUE NOT NULL,
+	source VARCHAR(255),
+	category VARCHAR(100),
+	published_at TIMESTAMP,
+	image_url TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS job_listings (
+	id TEXT PRIMARY KEY NOT NULL,
+	title TEXT NOT NULL,
+	company VARCHAR(255),
+	location VARCHAR(255),
+	description TEXT,
+	url TEXT UNIQUE NOT NULL,
+	category VARCHAR(100),
+	posted_at TIMESTAMP,
+	salary VARCHAR(100),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS videos (
+	id TEXT PRIMARY KEY NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	url TEXT UNIQUE NOT NULL,
+	channel VARCHAR(255),
+	category VARCHAR(100),
+	published_at TIMESTAMP,
+	thumbnail TEXT,
+	duration VARCHAR(20),
+	views BIGINT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS deals (
+	id TEXT PRIMARY KEY NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	url TEXT UNIQUE NOT NULL,
+	platform VARCHAR(100),
+	category VARCHAR(100),
+	price REAL,
+	original_price REAL,
+	discount REAL,
+	image_url TEXT,
+	valid_until TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recommendations (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+	content_type VARCHAR(50) NOT NULL,
+	content_id TEXT NOT NULL,
+	score REAL,
+	reason TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS nft_coupons (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+	token_id VARCHAR(255),
+	contract_address VARCHAR(255),
+	title VARCHAR(255),
+	description TEXT,
+	discount REAL,
+	category VARCHAR(100),
+	status VARCHAR(50) DEFAULT 'minted',
+	minted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	claimed_at TIMESTAMP,
+	expires_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS nft_activities (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+	action VARCHAR(100) NOT NULL,
+	points INTEGER DEFAULT 0,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_user_behaviors_user_id ON user_behaviors(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_behaviors_category ON user_behaviors(category);
+CREATE INDEX IF NOT EXISTS idx_news_category ON news_articles(category);
+CREATE INDEX IF NOT EXISTS idx_jobs_category ON job_listings(category);
+CREATE INDEX IF NOT EXISTS idx_videos_category ON videos(category);
+CREATE INDEX IF NOT EXISTS idx_deals_category ON deals(category);
+CREATE INDEX IF NOT EXISTS idx_recommendations_user_id ON recommendations(user_id);
+CREATE INDEX IF NOT EXISTS idx_nft_coupons_user_id ON nft_coupons(user_id);
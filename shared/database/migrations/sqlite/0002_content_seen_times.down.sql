@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_deals_last_seen;
+DROP INDEX IF EXISTS idx_videos_last_seen;
+DROP INDEX IF EXISTS idx_jobs_last_seen;
+DROP INDEX IF EXISTS idx_news_last_seen;
+
+ALTER TABLE deals DROP COLUMN last_seen_at;
+ALTER TABLE deals DROP COLUMN first_seen_at;
+ALTER TABLE videos DROP COLUMN last_seen_at;
+ALTER TABLE videos DROP COLUMN first_seen_at;
+ALTER TABLE job_listings DROP COLUMN last_seen_at;
+ALTER TABLE job_listings DROP COLUMN first_seen_at;
+ALTER TABLE news_articles DROP COLUMN last_seen_at;
+ALTER TABLE news_articles DROP COLUMN first_seen_at;
@@ -0,0 +1,23 @@
+-- SQLite only allows constant defaults in ADD COLUMN, so existing rows are
+-- backfilled from created_at and the repositories always set both columns.
+ALTER TABLE news_articles ADD COLUMN first_seen_at TIMESTAMP;
+ALTER TABLE news_articles ADD COLUMN last_seen_at TIMESTAMP;
+
+ALTER TABLE job_listings ADD COLUMN first_seen_at TIMESTAMP;
+ALTER TABLE job_listings ADD COLUMN last_seen_at TIMESTAMP;
+
+ALTER TABLE videos ADD COLUMN first_seen_at TIMESTAMP;
+ALTER TABLE videos ADD COLUMN last_seen_at TIMESTAMP;
+
+ALTER TABLE deals ADD COLUMN first_seen_at TIMESTAMP;
+ALTER TABLE deals ADD COLUMN last_seen_at TIMESTAMP;
+
+UPDATE news_articles SET first_seen_at = created_at, last_seen_at = created_at;
+UPDATE job_listings SET first_seen_at = created_at, last_seen_at = created_at;
+UPDATE videos SET first_seen_at = created_at, last_seen_at = created_at;
+UPDATE deals SET first_seen_at = created_at, last_seen_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_news_last_seen ON news_articles(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_last_seen ON job_listings(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_videos_last_seen ON videos(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_deals_last_seen ON deals(last_seen_at);
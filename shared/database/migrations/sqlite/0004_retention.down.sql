@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_behaviors_timestamp;
+DROP INDEX IF EXISTS idx_nft_coupons_expires_at;
+DROP INDEX IF EXISTS idx_deals_valid_until;
+
+DROP TABLE IF EXISTS user_behavior_aggregates;
+
+ALTER TABLE deals DROP COLUMN archived_at;
@@ -0,0 +1,28 @@
+-- Append-only record of user, preference and coupon mutations. user_id and
+-- target_id are free text because not every service uses UUIDs yet.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id TEXT PRIMARY KEY NOT NULL,
+	occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	actor VARCHAR(255) NOT NULL,
+	action VARCHAR(100) NOT NULL,
+	target_type VARCHAR(50) NOT NULL,
+	target_id VARCHAR(255) NOT NULL,
+	user_id VARCHAR(255),
+	request_id VARCHAR(255),
+	changes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_occurred ON audit_log(user_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred ON audit_log(occurred_at);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update
+	BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete
+	BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,14 @@
+-- Responses stored by the gateway for Idempotency-Key retries, shared by all
+-- replicas. key hashes the client, path and Idempotency-Key; status is 0
+-- while the first request is being served. expires_at is Unix time in
+-- seconds.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key TEXT PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	header TEXT NOT NULL DEFAULT '',
+	body BLOB,
+	expires_at REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/database"
+)
+
+// TestSQLiteFile sets up a single file database, writes times through the
+// driver and checks that SQLite itself can read them back.
+func TestSQLiteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.db")
+	t.Setenv("DATABASE_DRIVER", "sqlite")
+	t.Setenv("DATABASE_URL", path)
+
+	db, err := database.SetupDatabase()
+	if err != nil {
+		t.Fatalf("SetupDatabase: %v", err)
+	}
+	if got := database.DialectOf(db); got != database.SQLite {
+		t.Fatalf("DialectOf = %q, want %q", got, database.SQLite)
+	}
+
+	ctx := context.Background()
+	day := time.Date(2025, 1, 26, 23, 30, 0, 0, time.UTC)
+	if _, err := db.ExecContext(ctx, database.SQLite.Rebind(`INSERT INTO users (id, email, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $4)`),
+		"u1", "u1@example.com", "User One", day); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		want  string
+	}{
+		{"date function", `SELECT date(created_at) FROM users WHERE id = 'u1'`, nil, "2025-01-26"},
+		{"earlier cutoff", `SELECT count(*) FROM users WHERE created_at < $1`, []interface{}{day.Add(-time.Second)}, "0"},
+		{"later cutoff", `SELECT count(*) FROM users WHERE created_at < $1`, []interface{}{day.Add(time.Second)}, "1"},
+		{"next day", `SELECT count(*) FROM users WHERE created_at < $1`, []interface{}{day.Add(time.Hour)}, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if err := db.QueryRowContext(ctx, database.SQLite.Rebind(tt.query), tt.args...).Scan(&got); err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var createdAt time.Time
+	if err := db.QueryRowContext(ctx, `SELECT created_at FROM users WHERE id = 'u1'`).Scan(&createdAt); err != nil {
+		t.Fatalf("scan created_at: %v", err)
+	}
+	if !createdAt.Equal(day) {
+		t.Errorf("created_at = %v, want %v", createdAt, day)
+	}
+
+	// The data is in the file, not in the connection
+	db.Close()
+	db, err = database.SetupDatabase()
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	var users int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM users`).Scan(&users); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	if users != 1 {
+		t.Errorf("users after reopening = %d, want 1", users)
+	}
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql/driver"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+func isSQLiteDriver(d driver.Driver) bool {
+	_, ok := d.(*sqlite.Driver)
+	return ok
+}
+
+// sqliteDSN turns a plain file path into a DSN with the settings every
+// connection needs: enforced foreign keys (for ON DELETE CASCADE), WAL so
+// readers don't block the writer, a busy timeout instead of SQLITE_BUSY, and
+// times written in SQLite's own format so that the date functions and time
+// comparisons work on them. Settings already in the path are kept.
+func sqliteDSN(path string) string {
+	path = strings.TrimPrefix(path, "sqlite://")
+
+	var params []string
+	if !strings.Contains(path, "_pragma=") {
+		params = append(params, "_pragma=foreign_keys(1)", "_pragma=journal_mode(WAL)", "_pragma=busy_timeout(5000)")
+	}
+	if !strings.Contains(path, "_time_format=") {
+		params = append(params, "_time_format=sqlite")
+	}
+	if len(params) == 0 {
+		return path
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(params, "&")
+}
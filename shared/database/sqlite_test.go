@@ -0,0 +1,27 @@
+package database
+
+import "testing"
+
+func TestSQLiteDSN(t *testing.T) {
+	defaults := "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"plain path", "dashboard.db", "dashboard.db?" + defaults},
+		{"sqlite scheme", "sqlite://data/dashboard.db", "data/dashboard.db?" + defaults},
+		{"existing query", "file:dashboard.db?mode=rwc", "file:dashboard.db?mode=rwc&" + defaults},
+		{"own pragmas", "dashboard.db?_pragma=foreign_keys(1)", "dashboard.db?_pragma=foreign_keys(1)&_time_format=sqlite"},
+		{"own time format", "dashboard.db?_time_format=sqlite&_pragma=busy_timeout(1)", "dashboard.db?_time_format=sqlite&_pragma=busy_timeout(1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqliteDSN(tt.path); got != tt.want {
+				t.Errorf("sqliteDSN(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
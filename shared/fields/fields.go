@@ -0,0 +1,192 @@
+// Package fields trims JSON responses to the fields a client asks for with
+// ?fields=, e.g. ?fields=title,url,source.name. Paths apply to every item of
+// a vertical's list (articles, jobs, videos, deals, movies, recipes and the
+// other collections below), so a widget gets the same shape from every
+// service and through the gateway; other responses are trimmed at the top.
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Param is the query parameter listing the fields.
+const Param = "fields"
+
+// collections are the response keys holding lists of items. Paths apply to
+// their items; the other keys of such a response, e.g. category and count,
+// are kept as they are.
+var collections = map[string]bool{
+	"articles": true, "jobs": true, "videos": true, "deals": true, "movies": true, "recipes": true,
+	"items": true, "recommendations": true, "nfts": true,
+}
+
+// Tree is a parsed fields parameter: each key maps to the fields wanted
+// below it, or to nil when the whole value is wanted.
+type Tree map[string]Tree
+
+// Parse reads a comma-separated list of dot-separated paths. Naming a field
+// selects all of it, even if some of its subfields are named too.
+func Parse(value string) Tree {
+	root := Tree{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := root
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			child, seen := node[segment]
+			if seen && child == nil {
+				// Already selected whole
+				break
+			}
+			if i == len(segments)-1 {
+				node[segment] = nil
+				break
+			}
+			if child == nil {
+				child = Tree{}
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// Project returns the fields of value selected by t. Lists are projected
+// item by item; fields that are not there, or are asked for below a value
+// that is not an object, are left out.
+func Project(value interface{}, t Tree) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(t))
+		for key, subtree := range t {
+			field, ok := v[key]
+			if !ok {
+				continue
+			}
+			if subtree == nil {
+				projected[key] = field
+				continue
+			}
+			switch field.(type) {
+			case map[string]interface{}, []interface{}:
+				projected[key] = Project(field, subtree)
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, item := range v {
+			projected[i] = Project(item, t)
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+// projectResponse applies t to a decoded response body: inside the gofr
+// {"data": ...} envelope, to the items of the collections of a response
+// that has any, and to the response itself otherwise.
+func projectResponse(body interface{}, t Tree) interface{} {
+	object, ok := body.(map[string]interface{})
+	if !ok {
+		return Project(body, t)
+	}
+	if data, ok := object["data"]; ok && len(object) == 1 {
+		return map[string]interface{}{"data": projectResponse(data, t)}
+	}
+
+	found := false
+	for key, value := range object {
+		if list, ok := value.([]interface{}); ok && collections[key] {
+			object[key] = Project(list, t)
+			found = true
+		}
+	}
+	if found {
+		return object
+	}
+	return Project(object, t)
+}
+
+// Middleware trims successful JSON responses to GET requests that carry
+// ?fields=. Other responses, and compressed ones, are passed on unchanged.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.URL.Query().Get(Param)
+		if value == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t := Parse(value)
+		if len(t) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if projected, ok := project(rec, body, t); ok {
+			body = projected
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		w.WriteHeader(rec.status)
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+	})
+}
+
+// project re-encodes body trimmed to t, if it is a JSON response that can
+// be trimmed.
+func project(rec *recorder, body []byte, t Tree) ([]byte, bool) {
+	header := rec.Header()
+	if rec.status != http.StatusOK || header.Get("Content-Encoding") != "" ||
+		!strings.Contains(header.Get("Content-Type"), "json") {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	projected, err := json.Marshal(projectResponse(decoded, t))
+	if err != nil {
+		return nil, false
+	}
+	return append(projected, '\n'), true
+}
+
+// recorder holds the response back so that it can be trimmed.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(p)
+}
@@ -0,0 +1,154 @@
+package fields
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Tree
+	}{
+		{"title,url", Tree{"title": nil, "url": nil}},
+		{" title , , url ", Tree{"title": nil, "url": nil}},
+		{"source.name,source.id", Tree{"source": Tree{"name": nil, "id": nil}}},
+		{"source,source.name", Tree{"source": nil}},
+		{"source.name,source", Tree{"source": nil}},
+		{"a.b.c,a.d", Tree{"a": Tree{"b": Tree{"c": nil}, "d": nil}}},
+		{",", Tree{}},
+	}
+	for _, tc := range tests {
+		if got := Parse(tc.value); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		fields string
+		want   string
+	}{
+		{"top level", `{"title": "a", "url": "u", "body": "b"}`, "title,url", `{"title": "a", "url": "u"}`},
+		{"nested", `{"source": {"id": 1, "name": "n"}, "title": "a"}`, "source.name", `{"source": {"name": "n"}}`},
+		{"list items", `[{"title": "a", "url": "u"}, {"title": "b"}]`, "title", `[{"title": "a"}, {"title": "b"}]`},
+		{"list below a key", `{"tags": [{"id": 1, "name": "x"}]}`, "tags.name", `{"tags": [{"name": "x"}]}`},
+		{"missing fields", `{"title": "a"}`, "url,source.name", `{}`},
+		{"path below a scalar", `{"title": "a"}`, "title.length", `{}`},
+		{"scalar", `"text"`, "title", `"text"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var value, want interface{}
+			json.Unmarshal([]byte(tc.value), &value)
+			json.Unmarshal([]byte(tc.want), &want)
+			if got := Project(value, Parse(tc.fields)); !reflect.DeepEqual(got, want) {
+				t.Errorf("Project = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		status      int
+		contentType string
+		encoding    string
+		body        string
+		want        string
+	}{
+		{
+			name: "collection items", method: "GET", target: "/api/news?fields=title", status: 200, contentType: "application/json",
+			body: `{"articles": [{"title": "a", "url": "u"}], "category": "tech", "count": 1}`,
+			want: `{"articles":[{"title":"a"}],"category":"tech","count":1}`,
+		},
+		{
+			name: "gofr envelope", method: "GET", target: "/api/jobs?fields=title", status: 200, contentType: "application/json; charset=utf-8",
+			body: `{"data": {"jobs": [{"title": "a", "salary": 12345678901234567890}]}}`,
+			want: `{"data":{"jobs":[{"title":"a"}]}}`,
+		},
+		{
+			name: "large numbers kept exact", method: "GET", target: "/api/users/1?fields=id", status: 200, contentType: "application/json",
+			body: `{"id": 12345678901234567890, "name": "a"}`,
+			want: `{"id":12345678901234567890}`,
+		},
+		{
+			name: "plain object", method: "GET", target: "/api/users/1?fields=name,preferences.theme", status: 200, contentType: "application/json",
+			body: `{"id": 1, "name": "a", "preferences": {"theme": "dark", "lang": "en"}}`,
+			want: `{"name":"a","preferences":{"theme":"dark"}}`,
+		},
+		{
+			name: "no fields", method: "GET", target: "/api/news", status: 200, contentType: "application/json",
+			body: `{"articles": [{"title": "a", "url": "u"}]}`,
+			want: `{"articles": [{"title": "a", "url": "u"}]}`,
+		},
+		{
+			name: "error responses", method: "GET", target: "/api/news?fields=title", status: 502, contentType: "application/json",
+			body: `{"error": "upstream failed"}`,
+			want: `{"error": "upstream failed"}`,
+		},
+		{
+			name: "not JSON", method: "GET", target: "/api/news?fields=title", status: 200, contentType: "text/plain",
+			body: `title,url`,
+			want: `title,url`,
+		},
+		{
+			name: "compressed", method: "GET", target: "/api/news?fields=title", status: 200, contentType: "application/json", encoding: "gzip",
+			body: `{"title": "a"}`,
+			want: `{"title": "a"}`,
+		},
+		{
+			name: "POST", method: "POST", target: "/api/users?fields=id", status: 200, contentType: "application/json",
+			body: `{"id": 1, "name": "a"}`,
+			want: `{"id": 1, "name": "a"}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				if tc.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.encoding)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.want {
+				t.Errorf("body = %s, want %s", got, tc.want)
+			}
+			if length := rec.Header().Get("Content-Length"); length != "" && length != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("Content-Length = %s for a %d byte body", length, rec.Body.Len())
+			}
+		})
+	}
+}
+
+func TestMiddlewareHead(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title": "a", "url": "u"}`))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/api/news?fields=title", nil))
+
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != strconv.Itoa(len(`{"title":"a"}`+"\n")) {
+		t.Errorf("HEAD: body %q, Content-Length %s; want no body and the projected length", rec.Body, rec.Header().Get("Content-Length"))
+	}
+}
@@ -0,0 +1,382 @@
+// Package fixtures builds a deterministic data set for demos and integration
+// tests from the mock data the services return when no provider key is set.
+// Every ID is derived from a stable key and every timestamp is an offset from
+// a reference time, so seeding twice with the same reference time produces
+// the same rows.
+package fixtures
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/models"
+)
+
+// Epoch is a fixed reference time for reproducible fixture sets. It is in
+// the past, so its deals and coupons have lapsed; cmd/seed uses the current
+// time unless told otherwise.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// namespace seeds the name-based UUIDs of the fixture rows.
+var namespace = uuid.MustParse("6f1c3b9e-5a2d-4f7e-9c41-2d8e0b7a3f15")
+
+// ID returns the UUID of the fixture row of the given kind and key, e.g.
+// ID("user", "demo"), so tests can refer to seeded rows directly.
+func ID(kind, key string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(kind+"/"+key))
+}
+
+// Set is one complete fixture data set.
+type Set struct {
+	Users      []models.User         `json:"users"`
+	Profiles   []models.UserProfile  `json:"profiles"`
+	Behaviors  []models.UserBehavior `json:"behaviors"`
+	News       []models.NewsArticle  `json:"news"`
+	Jobs       []models.JobListing   `json:"jobs"`
+	Videos     []models.Video        `json:"videos"`
+	Deals      []models.Deal         `json:"deals"`
+	Coupons    []models.NFTCoupon    `json:"coupons"`
+	Activities []models.NFTActivity  `json:"activities"`
+}
+
+const day = 24 * time.Hour
+
+// Build returns the fixture set with all timestamps relative to at.
+func Build(at time.Time) *Set {
+	at = at.UTC()
+	set := &Set{}
+
+	set.addUsers(at)
+	set.addNews(at)
+	set.addJobs(at)
+	set.addVideos(at)
+	set.addDeals(at)
+	set.addBehaviors(at)
+	set.addCoupons(at)
+
+	return set
+}
+
+type fixtureUser struct {
+	key       string
+	email     string
+	name      string
+	interests []string
+	scores    models.ScoreMap
+	age       time.Duration
+}
+
+var fixtureUsers = []fixtureUser{
+	{
+		key:       "demo",
+		email:     "user@example.com",
+		name:      "Demo User",
+		interests: []string{"technology", "ai", "business"},
+		scores: models.ScoreMap{
+			"technology":    0.8,
+			"ai":            0.9,
+			"business":      0.7,
+			"entertainment": 0.3,
+			"fashion":       0.2,
+		},
+		age: 30 * day,
+	},
+	{
+		key:       "shopper",
+		email:     "shopper@example.com",
+		name:      "Priya Sharma",
+		interests: []string{"fashion", "home", "books"},
+		scores: models.ScoreMap{
+			"fashion":    0.9,
+			"home":       0.6,
+			"books":      0.5,
+			"technology": 0.2,
+		},
+		age: 14 * day,
+	},
+	{
+		key:       "engineer",
+		email:     "engineer@example.com",
+		name:      "Alex Chen",
+		interests: []string{"cloud", "technology", "entertainment"},
+		scores: models.ScoreMap{
+			"cloud":         0.9,
+			"technology":    0.7,
+			"entertainment": 0.4,
+		},
+		age: 3 * day,
+	},
+}
+
+func (s *Set) addUsers(at time.Time) {
+	for _, u := range fixtureUsers {
+		id := ID("user", u.key)
+		created := at.Add(-u.age)
+
+		s.Users = append(s.Users, models.User{
+			ID:        id,
+			Email:     u.email,
+			Name:      u.name,
+			Interests: u.interests,
+			CreatedAt: created,
+			UpdatedAt: created,
+		})
+		s.Profiles = append(s.Profiles, models.UserProfile{
+			UserID:            id,
+			ExplicitInterests: u.interests,
+			BehavioralScore:   u.scores,
+			LastUpdated:       at,
+		})
+	}
+}
+
+func (s *Set) addNews(at time.Time) {
+	articles := []struct {
+		title, description, source, category string
+		age                                  time.Duration
+	}{
+		{"AI Breakthrough in Healthcare", "New AI technology promises to revolutionize medical diagnosis.", "Tech News", "technology", 2 * time.Hour},
+		{"Open-Source Language Models Close the Gap", "Community-trained models now match commercial systems on common benchmarks.", "AI Weekly", "ai", 5 * time.Hour},
+		{"Startups Shift Budgets to Cloud Cost Control", "Founders report that infrastructure spend is now their second-largest expense.", "Business Daily", "business", 9 * time.Hour},
+		{"Chipmakers Announce Next-Generation Laptop Processors", "The new processors promise longer battery life and faster on-device AI.", "Tech News", "technology", day},
+		{"Streaming Services Bet on Live Sports", "Platforms are bidding for broadcast rights to keep subscribers engaged.", "Entertainment Now", "entertainment", 2 * day},
+		{"Sustainable Fabrics Go Mainstream", "Major retailers commit to recycled materials across their autumn collections.", "Style Journal", "fashion", 3 * day},
+	}
+
+	for i, a := range articles {
+		key := fmt.Sprintf("%s-%d", a.category, i+1)
+		published := at.Add(-a.age)
+		s.News = append(s.News, models.NewsArticle{
+			ID:          ID("news", key),
+			Title:       a.title,
+			Description: a.description,
+			URL:         "https://example.com/news/" + key,
+			Source:      a.source,
+			Category:    a.category,
+			PublishedAt: published,
+			ImageURL:    "https://via.placeholder.com/300x200",
+			FirstSeenAt: published,
+			LastSeenAt:  at,
+		})
+	}
+}
+
+func (s *Set) addJobs(at time.Time) {
+	jobs := []struct {
+		title, company, location, description, category, salary, listing string
+		age                                                              time.Duration
+	}{
+		{"Senior Software Engineer", "TechCorp Inc.", "San Francisco, CA", "We are looking for a talented software engineer to join our team...", "technology", "$120,000 - $180,000", "123456", 24 * time.Hour},
+		{"AI/ML Engineer", "AI Startup Co.", "Remote", "Join our AI team to build cutting-edge machine learning solutions...", "ai", "$100,000 - $150,000", "123457", 12 * time.Hour},
+		{"Cloud Solutions Architect", "CloudTech Solutions", "New York, NY", "Design and implement cloud infrastructure solutions...", "cloud", "$130,000 - $200,000", "123458", 6 * time.Hour},
+		{"Product Analyst", "Market Insights Ltd.", "Austin, TX", "Turn product usage data into decisions for our business teams...", "business", "$85,000 - $110,000", "123459", 3 * day},
+	}
+
+	for _, j := range jobs {
+		posted := at.Add(-j.age)
+		s.Jobs = append(s.Jobs, models.JobListing{
+			ID:          ID("job", j.listing),
+			Title:       j.title,
+			Company:     j.company,
+			Location:    j.location,
+			Description: j.description,
+			URL:         "https://linkedin.com/jobs/view/" + j.listing,
+			Category:    j.category,
+			PostedAt:    posted,
+			Salary:      j.salary,
+			FirstSeenAt: posted,
+			LastSeenAt:  at,
+		})
+	}
+}
+
+func (s *Set) addVideos(at time.Time) {
+	videos := []struct {
+		title, description, channel, category, duration, videoID string
+		views                                                    int64
+		age                                                      time.Duration
+	}{
+		{"Go Concurrency Patterns Explained", "Goroutines, channels and the patterns that tie them together.", "Code Academy", "technology", "PT18M42S", "fx1goconc01", 125000, day},
+		{"Building a Neural Network from Scratch", "A step-by-step walkthrough of backpropagation in plain Python.", "AI Explained", "ai", "PT32M10S", "fx1nnscratch", 480000, 4 * day},
+		{"Kubernetes in 15 Minutes", "Pods, deployments and services for developers new to Kubernetes.", "Cloud Native TV", "cloud", "PT15M03S", "fx1k8s15min", 910000, 7 * day},
+		{"How Startups Price Their Products", "Founders share how they chose and changed their pricing.", "Founder Stories", "business", "PT24M55S", "fx1pricing01", 64000, 2 * day},
+	}
+
+	for _, v := range videos {
+		published := at.Add(-v.age)
+		s.Videos = append(s.Videos, models.Video{
+			ID:          ID("video", v.videoID),
+			Title:       v.title,
+			Description: v.description,
+			URL:         "https://www.youtube.com/watch?v=" + v.videoID,
+			Channel:     v.channel,
+			Category:    v.category,
+			PublishedAt: published,
+			Thumbnail:   "https://i.ytimg.com/vi/" + v.videoID + "/hqdefault.jpg",
+			Duration:    v.duration,
+			Views:       v.views,
+			FirstSeenAt: published,
+			LastSeenAt:  at,
+		})
+	}
+}
+
+// dealProducts and dealPlatforms mirror generateMockDeals in the deals
+// service.
+var dealProducts = map[string][]string{
+	"electronics": {"Smartphone", "Laptop", "Headphones", "Tablet", "Smart Watch"},
+	"fashion":     {"T-Shirt", "Jeans", "Shoes", "Dress", "Jacket"},
+	"home":        {"Coffee Maker", "Vacuum Cleaner", "Air Purifier", "Blender", "Microwave"},
+	"books":       {"Programming Book", "Novel", "Biography", "Cookbook", "Self-Help"},
+}
+
+var dealPlatforms = []string{"Amazon", "Flipkart", "Myntra", "Nykaa", "BigBasket"}
+
+func (s *Set) addDeals(at time.Time) {
+	featured := []models.Deal{
+		{Title: "Wireless Bluetooth Headphones", Description: "High-quality wireless headphones with noise cancellation", URL: "https://amazon.com/dp/B08XYZ123", Platform: "Amazon", Category: "electronics", Price: 79.99, OriginalPrice: 129.99, Discount: 38.46, ImageURL: "https://images.amazon.com/headphones.jpg", ValidUntil: at.Add(7 * day)},
+		{Title: "Smart Watch Series 8", Description: "Latest smartwatch with health monitoring features", URL: "https://amazon.com/dp/B08XYZ124", Platform: "Amazon", Category: "electronics", Price: 299.99, OriginalPrice: 399.99, Discount: 25.0, ImageURL: "https://images.amazon.com/smartwatch.jpg", ValidUntil: at.Add(5 * day)},
+		{Title: "Laptop Gaming Pro", Description: "High-performance gaming laptop with RTX graphics", URL: "https://flipkart.com/laptop-gaming-pro", Platform: "Flipkart", Category: "electronics", Price: 89999.0, OriginalPrice: 119999.0, Discount: 25.0, ImageURL: "https://images.flipkart.com/laptop.jpg", ValidUntil: at.Add(3 * day)},
+		{Title: "Smartphone Galaxy S23", Description: "Latest flagship smartphone with advanced camera", URL: "https://flipkart.com/galaxy-s23", Platform: "Flipkart", Category: "electronics", Price: 69999.0, OriginalPrice: 89999.0, Discount: 22.22, ImageURL: "https://images.flipkart.com/galaxy-s23.jpg", ValidUntil: at.Add(2 * day)},
+	}
+	for _, deal := range featured {
+		deal.ID = ID("deal", deal.URL)
+		deal.FirstSeenAt = at
+		deal.LastSeenAt = at
+		s.Deals = append(s.Deals, deal)
+	}
+
+	// Two generated deals per category, priced like generateMockDeals
+	for _, category := range []string{"electronics", "fashion", "home", "books"} {
+		products := dealProducts[category]
+		for i := 0; i < 2; i++ {
+			platform := dealPlatforms[i%len(dealPlatforms)]
+			product := products[i%len(products)]
+
+			originalPrice := float64(1000 + (i * 500))
+			discount := float64(10 + (i * 5))
+			url := fmt.Sprintf("https://%s.com/%s/product-%d", strings.ToLower(platform), category, i)
+
+			s.Deals = append(s.Deals, models.Deal{
+				ID:            ID("deal", url),
+				Title:         fmt.Sprintf("%s - %s", product, category),
+				Description:   fmt.Sprintf("High-quality %s for %s category", product, category),
+				URL:           url,
+				Platform:      platform,
+				Category:      category,
+				Price:         originalPrice * (1 - discount/100),
+				OriginalPrice: originalPrice,
+				Discount:      discount,
+				ImageURL:      fmt.Sprintf("https://images.%s.com/%s/product-%d.jpg", strings.ToLower(platform), category, i),
+				ValidUntil:    at.Add(time.Duration(1+i) * day),
+				FirstSeenAt:   at,
+				LastSeenAt:    at,
+			})
+		}
+	}
+}
+
+type contentRef struct {
+	id, category string
+}
+
+// addBehaviors gives every user a history of clicks, bookmarks, shares and
+// searches on the content matching their interests.
+func (s *Set) addBehaviors(at time.Time) {
+	actions := []string{"click", "bookmark", "share"}
+
+	for _, user := range s.Users {
+		interests := make(map[string]bool, len(user.Interests))
+		for _, interest := range user.Interests {
+			interests[interest] = true
+		}
+
+		var content []contentRef
+		for _, article := range s.News {
+			if interests[article.Category] {
+				content = append(content, contentRef{article.ID.String(), article.Category})
+			}
+		}
+		for _, job := range s.Jobs {
+			if interests[job.Category] {
+				content = append(content, contentRef{job.ID.String(), job.Category})
+			}
+		}
+		for _, video := range s.Videos {
+			if interests[video.Category] {
+				content = append(content, contentRef{video.ID.String(), video.Category})
+			}
+		}
+		for _, deal := range s.Deals {
+			if interests[deal.Category] {
+				content = append(content, contentRef{deal.ID.String(), deal.Category})
+			}
+		}
+
+		for i, item := range content {
+			action := actions[i%len(actions)]
+			s.Behaviors = append(s.Behaviors, models.UserBehavior{
+				ID:        ID("behavior", fmt.Sprintf("%s/%s/%s", user.ID, action, item.id)),
+				UserID:    user.ID,
+				Action:    action,
+				ContentID: item.id,
+				Category:  item.category,
+				Timestamp: at.Add(-time.Duration(i+1) * time.Hour),
+			})
+		}
+
+		for i, interest := range user.Interests {
+			s.Behaviors = append(s.Behaviors, models.UserBehavior{
+				ID:        ID("behavior", fmt.Sprintf("%s/search/%s", user.ID, interest)),
+				UserID:    user.ID,
+				Action:    "search",
+				ContentID: interest,
+				Category:  interest,
+				Timestamp: at.Add(-time.Duration(i+1) * day),
+			})
+		}
+	}
+}
+
+// addCoupons gives every user the two coupons GetUserNFTs returns, one
+// minted and one claimed, plus the activities that earned them.
+func (s *Set) addCoupons(at time.Time) {
+	const contractAddress = "0x1234567890abcdef"
+
+	for i, user := range s.Users {
+		claimedAt := at.Add(-2 * day)
+		coupons := []models.NFTCoupon{
+			{Title: "Technology Discount Coupon", Description: "Get 15% off on technology items", Discount: 15.0, Category: "technology", Status: "minted", MintedAt: at.Add(-5 * day), ExpiresAt: at.Add(25 * day)},
+			{Title: "Fashion Discount Coupon", Description: "Get 20% off on fashion items", Discount: 20.0, Category: "fashion", Status: "claimed", MintedAt: at.Add(-10 * day), ClaimedAt: &claimedAt, ExpiresAt: at.Add(20 * day)},
+		}
+		for j, coupon := range coupons {
+			coupon.TokenID = fmt.Sprintf("%d", 12345+i*len(coupons)+j)
+			coupon.ID = ID("coupon", coupon.TokenID)
+			coupon.UserID = user.ID
+			coupon.ContractAddress = contractAddress
+			s.Coupons = append(s.Coupons, coupon)
+		}
+
+		activities := []struct {
+			action string
+			points int
+			age    time.Duration
+		}{
+			{"engagement", 10, 12 * day},
+			{"milestone", 50, 10 * day},
+			{"reward", 25, 5 * day},
+		}
+		for _, activity := range activities {
+			s.Activities = append(s.Activities, models.NFTActivity{
+				ID:        ID("activity", fmt.Sprintf("%s/%s", user.ID, activity.action)),
+				UserID:    user.ID,
+				Action:    activity.action,
+				Points:    activity.points,
+				Timestamp: at.Add(-activity.age),
+			})
+		}
+	}
+}
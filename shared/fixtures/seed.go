@@ -0,0 +1,165 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"personalized-dashboard/shared/repository"
+)
+
+// Summary counts the rows Seed wrote per table.
+type Summary struct {
+	Users      int `json:"users"`
+	Profiles   int `json:"profiles"`
+	Behaviors  int `json:"behaviors"`
+	News       int `json:"news"`
+	Jobs       int `json:"jobs"`
+	Videos     int `json:"videos"`
+	Deals      int `json:"deals"`
+	Coupons    int `json:"coupons"`
+	Activities int `json:"activities"`
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d users, %d profiles, %d behaviors, %d articles, %d jobs, %d videos, %d deals, %d coupons, %d activities",
+		s.Users, s.Profiles, s.Behaviors, s.News, s.Jobs, s.Videos, s.Deals, s.Coupons, s.Activities)
+}
+
+// Seed writes set through repos in one transaction, so a failure leaves the
+// database as it was. Fixture users and content already in the database,
+// matched by ID, email or URL, are deleted first, so seeding can be repeated;
+// deleting a user cascades to their behaviors, coupons and activities.
+func Seed(ctx context.Context, repos *repository.Repositories, set *Set) (Summary, error) {
+	var summary Summary
+	err := repos.InTx(ctx, func(tx *repository.Repositories) error {
+		summary = Summary{}
+		return seed(ctx, tx, set, &summary)
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+func seed(ctx context.Context, repos *repository.Repositories, set *Set, summary *Summary) error {
+	for i := range set.Users {
+		user := &set.Users[i]
+		if err := ignoreNotFound(repos.Users.Delete(ctx, user.ID)); err != nil {
+			return err
+		}
+		if existing, err := repos.Users.GetByEmail(ctx, user.Email); err == nil {
+			if err := repos.Users.Delete(ctx, existing.ID); err != nil {
+				return err
+			}
+		} else if err := ignoreNotFound(err); err != nil {
+			return fmt.Errorf("failed to look up user %s: %v", user.Email, err)
+		}
+
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		summary.Users++
+	}
+
+	for i := range set.Profiles {
+		if err := repos.Profiles.Upsert(ctx, &set.Profiles[i]); err != nil {
+			return err
+		}
+		summary.Profiles++
+	}
+
+	for i := range set.News {
+		article := &set.News[i]
+		if existing, err := repos.News.GetByURL(ctx, article.URL); err == nil {
+			if err := repos.News.Delete(ctx, existing.ID); err != nil {
+				return err
+			}
+		} else if err := ignoreNotFound(err); err != nil {
+			return err
+		}
+
+		if err := repos.News.Create(ctx, article); err != nil {
+			return err
+		}
+		summary.News++
+	}
+
+	for i := range set.Jobs {
+		job := &set.Jobs[i]
+		if existing, err := repos.Jobs.GetByURL(ctx, job.URL); err == nil {
+			if err := repos.Jobs.Delete(ctx, existing.ID); err != nil {
+				return err
+			}
+		} else if err := ignoreNotFound(err); err != nil {
+			return err
+		}
+
+		if err := repos.Jobs.Create(ctx, job); err != nil {
+			return err
+		}
+		summary.Jobs++
+	}
+
+	for i := range set.Videos {
+		video := &set.Videos[i]
+		if existing, err := repos.Videos.GetByURL(ctx, video.URL); err == nil {
+			if err := repos.Videos.Delete(ctx, existing.ID); err != nil {
+				return err
+			}
+		} else if err := ignoreNotFound(err); err != nil {
+			return err
+		}
+
+		if err := repos.Videos.Create(ctx, video); err != nil {
+			return err
+		}
+		summary.Videos++
+	}
+
+	for i := range set.Deals {
+		deal := &set.Deals[i]
+		if existing, err := repos.Deals.GetByURL(ctx, deal.URL); err == nil {
+			if err := repos.Deals.Delete(ctx, existing.ID); err != nil {
+				return err
+			}
+		} else if err := ignoreNotFound(err); err != nil {
+			return err
+		}
+
+		if err := repos.Deals.Create(ctx, deal); err != nil {
+			return err
+		}
+		summary.Deals++
+	}
+
+	for i := range set.Behaviors {
+		if err := repos.Behaviors.Create(ctx, &set.Behaviors[i]); err != nil {
+			return err
+		}
+		summary.Behaviors++
+	}
+
+	for i := range set.Coupons {
+		if err := repos.NFTCoupons.Create(ctx, &set.Coupons[i]); err != nil {
+			return err
+		}
+		summary.Coupons++
+	}
+
+	for i := range set.Activities {
+		if err := repos.NFTActivities.Create(ctx, &set.Activities[i]); err != nil {
+			return err
+		}
+		summary.Activities++
+	}
+
+	return nil
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
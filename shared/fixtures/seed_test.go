@@ -0,0 +1,94 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+	"personalized-dashboard/shared/retention"
+)
+
+func TestBuildIsDeterministic(t *testing.T) {
+	first, second := Build(Epoch), Build(Epoch)
+	if len(first.Users) == 0 || len(first.Deals) == 0 || len(first.Coupons) == 0 {
+		t.Fatalf("Build returned an incomplete set: %d users, %d deals, %d coupons", len(first.Users), len(first.Deals), len(first.Coupons))
+	}
+	for i := range first.Users {
+		if first.Users[i].ID != second.Users[i].ID || !first.Users[i].CreatedAt.Equal(second.Users[i].CreatedAt) {
+			t.Errorf("user %d differs between builds", i)
+		}
+	}
+	if first.Users[0].ID != ID("user", "demo") {
+		t.Errorf("demo user ID = %s, want ID(user, demo)", first.Users[0].ID)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.SQLite(t)
+	repos := repository.New(db)
+	at := time.Now().UTC().Truncate(time.Second)
+
+	for run := 1; run <= 2; run++ {
+		set := Build(at)
+		summary, err := Seed(ctx, repos, set)
+		if err != nil {
+			t.Fatalf("Seed run %d: %v", run, err)
+		}
+		if summary.Users != len(set.Users) || summary.Deals != len(set.Deals) || summary.Coupons != len(set.Coupons) {
+			t.Errorf("Seed run %d = %s", run, summary)
+		}
+	}
+
+	set := Build(at)
+	user, err := repos.Users.GetByID(ctx, set.Users[0].ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !user.UpdatedAt.Equal(set.Users[0].UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want the fixture's %v", user.UpdatedAt, set.Users[0].UpdatedAt)
+	}
+	profile, err := repos.Profiles.Get(ctx, set.Users[0].ID)
+	if err != nil {
+		t.Fatalf("Profiles.Get: %v", err)
+	}
+	if !profile.LastUpdated.Equal(at) {
+		t.Errorf("LastUpdated = %v, want %v", profile.LastUpdated, at)
+	}
+	coupons, err := repos.NFTCoupons.ListByUser(ctx, set.Users[0].ID)
+	if err != nil || len(coupons) == 0 {
+		t.Fatalf("ListByUser = %d coupons, %v", len(coupons), err)
+	}
+
+	// Seeded relative to now, nothing has lapsed yet
+	report, err := retention.New(db, retention.DefaultPolicies()).Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	for _, change := range report.Changes {
+		if change.Rows != 0 {
+			t.Errorf("sweep after seeding: %s %s %d rows, want 0", change.Table, change.Action, change.Rows)
+		}
+	}
+}
+
+func TestSeedRollsBack(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.New(dbtest.SQLite(t))
+
+	set := Build(Epoch)
+	// A behavior of an unknown user fails after the users were written
+	set.Behaviors = append(set.Behaviors, models.UserBehavior{UserID: uuid.New(), Action: "click", ContentID: "c1"})
+
+	if _, err := Seed(ctx, repos, set); err == nil {
+		t.Fatal("Seed with a dangling behavior succeeded")
+	}
+	if _, err := repos.Users.GetByID(ctx, set.Users[0].ID); err != repository.ErrNotFound {
+		t.Errorf("GetByID after a failed seed = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Query cost weights: every field costs fieldCost, fields that call a
+// service fetchCost more, and the selections of a list field count once for
+// every item its limit lets through.
+const (
+	fieldCost    = 1
+	fetchCost    = 10
+	defaultLimit = 10
+	// maxCounted keeps the estimate of absurd queries from overflowing.
+	maxCounted = 1 << 40
+)
+
+// fetchFields are the fields whose resolvers call a service.
+var fetchFields = map[string]bool{
+	"news": true, "jobs": true, "videos": true, "deals": true, "movies": true, "food": true,
+	"recommendations": true, "me": true, "user": true, "nfts": true,
+}
+
+// listFields are the fields that return up to limit items.
+var listFields = map[string]bool{
+	"news": true, "jobs": true, "videos": true, "deals": true, "movies": true, "food": true,
+	"recommendations": true, "nfts": true,
+}
+
+// queryCost estimates the cost of the operation of query before it runs. A
+// query whose operation cannot be found is an error rather than free, so
+// that nothing runs without an estimate. The estimate stops once it passes
+// maxCost; the cost returned then is only known to exceed maxCost.
+func queryCost(query, operationName string, variables map[string]interface{}, maxCost int64) (int64, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	var op *ast.OperationDefinition
+	switch {
+	case operationName != "":
+		op = doc.Operations.ForName(operationName)
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	default:
+		return 0, fmt.Errorf("operationName is required for a document with %d operations", len(doc.Operations))
+	}
+	if op == nil {
+		return 0, fmt.Errorf("no operation named %q", operationName)
+	}
+
+	e := estimator{doc: doc, op: op, variables: variables, maxCost: maxCost, fragments: map[string]int64{}, visiting: map[string]bool{}}
+	return e.cost(op.SelectionSet), nil
+}
+
+type estimator struct {
+	doc       *ast.QueryDocument
+	op        *ast.OperationDefinition
+	variables map[string]interface{}
+	maxCost   int64
+	// fragments holds the cost of every fragment walked so far, so that a
+	// fragment spread many times is walked once.
+	fragments map[string]int64
+	// visiting guards against fragments that spread themselves.
+	visiting map[string]bool
+}
+
+func (e *estimator) cost(selections ast.SelectionSet) int64 {
+	var total int64
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			cost := int64(fieldCost)
+			if fetchFields[s.Name] {
+				cost += fetchCost
+			}
+			children := e.cost(s.SelectionSet)
+			if listFields[s.Name] {
+				children *= e.limit(s)
+			}
+			total += cost + min(children, maxCounted)
+		case *ast.FragmentSpread:
+			total += e.fragmentCost(s.Name)
+		case *ast.InlineFragment:
+			total += e.cost(s.SelectionSet)
+		}
+		total = min(total, maxCounted)
+		if total > e.maxCost {
+			return total
+		}
+	}
+	return total
+}
+
+// fragmentCost is the cost of the named fragment, walked on its first
+// spread only.
+func (e *estimator) fragmentCost(name string) int64 {
+	if cost, ok := e.fragments[name]; ok {
+		return cost
+	}
+	fragment := e.doc.Fragments.ForName(name)
+	if fragment == nil || e.visiting[name] {
+		return 0
+	}
+
+	e.visiting[name] = true
+	cost := e.cost(fragment.SelectionSet)
+	delete(e.visiting, name)
+	e.fragments[name] = cost
+	return cost
+}
+
+// limit is the limit argument of field, or the default.
+func (e *estimator) limit(field *ast.Field) int64 {
+	argument := field.Arguments.ForName("limit")
+	if argument == nil {
+		return defaultLimit
+	}
+
+	value := argument.Value
+	if value.Kind == ast.Variable {
+		if variable, ok := e.variables[value.Raw]; ok {
+			return clampLimit(variable)
+		}
+		definition := e.op.VariableDefinitions.ForName(value.Raw)
+		if definition == nil || definition.DefaultValue == nil {
+			return defaultLimit
+		}
+		value = definition.DefaultValue
+	}
+
+	parsed, err := value.Value(nil)
+	if err != nil {
+		return defaultLimit
+	}
+	return clampLimit(parsed)
+}
+
+func clampLimit(value interface{}) int64 {
+	var limit int64
+	switch v := value.(type) {
+	case int64:
+		limit = v
+	case float64:
+		limit = int64(min(v, 1<<20))
+	default:
+		return defaultLimit
+	}
+	return max(0, min(limit, 1<<20))
+}
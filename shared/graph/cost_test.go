@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryCost(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		want          int64
+		wantErr       bool
+	}{
+		{name: "list with the default limit", query: `{ news { title } }`, want: 11 + 10*1},
+		{name: "explicit limit", query: `{ news(limit: 2) { title url } }`, want: 11 + 2*2},
+		{name: "variable default", query: `query($n: Int = 3) { news(limit: $n) { title } }`, want: 11 + 3},
+		{name: "variable value", query: `query($n: Int = 3) { news(limit: $n) { title } }`, variables: map[string]interface{}{"n": float64(5)}, want: 11 + 5},
+		{name: "negative limit", query: `{ news(limit: -4) { title } }`, want: 11},
+		{name: "nested lists multiply", query: `{ me { recommendations(limit: 5) { id } } }`, want: 11 + 11 + 5*1},
+		{name: "fragments", query: `{ ...F } fragment F on Query { me { id } }`, want: 12},
+		{name: "self-spreading fragment", query: `{ ...F } fragment F on Query { ...F me { id } }`, want: 12},
+		{name: "inline fragment", query: `{ ... on Query { me { id } } }`, want: 12},
+		{name: "huge limits are capped", query: `{ news(limit: 1000000000) { title } }`, want: 11 + 1<<20},
+		{name: "named operation", query: `query A { me { id } } query B { news { title } }`, operationName: "B", want: 21},
+		{name: "syntax error", query: `{ news { title }`, wantErr: true},
+		{name: "ambiguous operation", query: `query A { me { id } } query B { news { title } }`, wantErr: true},
+		{name: "unknown operation", query: `query A { me { id } }`, operationName: "B", wantErr: true},
+		{name: "no operation", query: `fragment F on Query { me { id } }`, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := queryCost(tc.query, tc.operationName, tc.variables, maxCounted)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("queryCost error = %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("queryCost = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// fragmentChain returns a query of depth fragments, each spreading the one
+// below it twice, so that walking every spread doubles with each level.
+func fragmentChain(depth int) string {
+	var query strings.Builder
+	fmt.Fprintf(&query, "{ ...F%d }\nfragment F0 on Query { me { id } }\n", depth)
+	for i := 1; i <= depth; i++ {
+		fmt.Fprintf(&query, "fragment F%d on Query { ...F%d ...F%d }\n", i, i-1, i-1)
+	}
+	return query.String()
+}
+
+func TestQueryCostFragmentChain(t *testing.T) {
+	query := fragmentChain(30)
+
+	start := time.Now()
+	got, err := queryCost(query, "", nil, maxCounted)
+	if err != nil {
+		t.Fatalf("queryCost: %v", err)
+	}
+	if want := int64(12) << 30; got != want {
+		t.Errorf("queryCost = %d, want %d", got, want)
+	}
+
+	got, err = queryCost(query, "", nil, 1000)
+	if err != nil || got <= 1000 {
+		t.Errorf("queryCost with a limit = %d, %v; want more than the limit", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("estimating took %v, want fragments walked once", elapsed)
+	}
+}
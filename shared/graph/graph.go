@@ -0,0 +1,181 @@
+// Package graph serves the gateway's GraphQL endpoint. The schema types every
+// vertical, recommendations, NFTs and the user; resolvers call the services
+// concurrently and share the responses they have in common. Queries nested
+// too deep or estimated to cost too much are rejected before they run.
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	graphqlotel "github.com/graph-gophers/graphql-go/trace/otel"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
+)
+
+// Path is where the endpoint is served.
+const Path = "/graphql"
+
+// maxRequestBytes caps the size of a POSTed query.
+const maxRequestBytes = 1 << 20
+
+type Config struct {
+	// Services returns the base URL of each service by name, such as the
+	// router's UpstreamURLs.
+	Services func() map[string]string
+	// MaxDepth bounds how deeply fields may be nested, introspection
+	// included; the default fits the introspection query GraphQL tools send.
+	MaxDepth int
+	// MaxCost bounds the estimated cost of a query; see queryCost.
+	MaxCost int64
+	// Timeout bounds a whole query, including the service calls.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv takes the service URLs from services and reads the limits
+// from GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COST and GRAPHQL_TIMEOUT.
+func ConfigFromEnv(services func() map[string]string) (Config, error) {
+	cfg := Config{
+		Services: services,
+		MaxDepth: 12,
+		MaxCost:  1000,
+		Timeout:  10 * time.Second,
+	}
+
+	if value := os.Getenv("GRAPHQL_MAX_DEPTH"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid GRAPHQL_MAX_DEPTH: %q is not a positive number", value)
+		}
+		cfg.MaxDepth = parsed
+	}
+	if value := os.Getenv("GRAPHQL_MAX_COST"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid GRAPHQL_MAX_COST: %q is not a positive number", value)
+		}
+		cfg.MaxCost = parsed
+	}
+	if value := os.Getenv("GRAPHQL_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid GRAPHQL_TIMEOUT: %v", err)
+		}
+		cfg.Timeout = parsed
+	}
+
+	return cfg, nil
+}
+
+type Server struct {
+	cfg    Config
+	schema *graphql.Schema
+	client *http.Client
+}
+
+func New(cfg Config) (*Server, error) {
+	parsed, err := graphql.ParseSchema(schema, &resolver{services: cfg.Services},
+		graphql.MaxDepth(cfg.MaxDepth),
+		graphql.Tracer(graphqlotel.DefaultTracer()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GraphQL schema: %v", err)
+	}
+	return &Server{cfg: cfg, schema: parsed, client: tracing.Client}, nil
+}
+
+// request is a GraphQL request, POSTed as JSON or passed as GET parameters.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes the query of a GET or POST request. Errors in the query
+// are reported in the response's errors, as GraphQL clients expect; only
+// requests that carry no query get a 4xx.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics.SetRoute(r.Context(), Path)
+
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid variables: %v", err))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// Queries the executor rejects never run, so they need no estimate
+	if errs := s.schema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		json.NewEncoder(w).Encode(&graphql.Response{Errors: errs})
+		return
+	}
+	cost, err := queryCost(req.Query, req.OperationName, req.Variables, s.cfg.MaxCost)
+	if err != nil {
+		writeQueryError(w, fmt.Sprintf("failed to estimate query cost: %v", err))
+		return
+	}
+	if cost > s.cfg.MaxCost {
+		writeQueryError(w, fmt.Sprintf("query cost exceeds the limit of %d", s.cfg.MaxCost))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+	defer cancel()
+	ctx = withLoader(ctx, &loader{client: s.client, userID: auth.UserIDFrom(ctx), roles: r.Header.Get(auth.RolesHeader), calls: map[string]*call{}})
+
+	json.NewEncoder(w).Encode(s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// Middleware serves the endpoint at Path and passes every other request to
+// next, for entrypoints that cannot mount handlers themselves.
+func (s *Server) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == Path {
+			s.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// writeQueryError reports a rejected query the way execution errors are
+// reported.
+func writeQueryError(w http.ResponseWriter, message string) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeHTTP(t *testing.T) {
+	var calls int
+	news := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"items": [{"id": "n1", "type": "news", "title": "Hello"}]}`))
+	}))
+	defer news.Close()
+
+	server, err := New(Config{Services: func() map[string]string { return map[string]string{"news": news.URL} }, MaxDepth: 12, MaxCost: 100, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		wantData      string
+		wantError     string
+		wantCalls     int
+	}{
+		{name: "runs", query: `{ news(limit: 1) { title } }`, wantData: `{"news":[{"title":"Hello"}]}`, wantCalls: 1},
+		{name: "over the cost limit", query: `{ news(limit: 100) { title } }`, wantError: "exceeds the limit of 100"},
+		{name: "syntax error", query: `{ news { title }`, wantError: "syntax error"},
+		{name: "unknown field", query: `{ secrets }`, wantError: `Cannot query field "secrets"`},
+		{name: "ambiguous operation", query: `query A { news(limit: 1) { title } } query B { news(limit: 1) { url } }`, wantError: "operationName is required"},
+		{name: "unknown operation", query: `query A { news(limit: 1) { title } }`, operationName: "B", wantError: `no operation named "B"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = 0
+			params := url.Values{"query": {tc.query}, "operationName": {tc.operationName}}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"?"+params.Encode(), nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 with GraphQL errors", rec.Code)
+			}
+			var resp struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if tc.wantError == "" {
+				if len(resp.Errors) > 0 || string(resp.Data) != tc.wantData {
+					t.Errorf("response = %s %v, want data %s", resp.Data, resp.Errors, tc.wantData)
+				}
+			} else if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tc.wantError) {
+				t.Errorf("errors = %v, want one containing %q", resp.Errors, tc.wantError)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("service called %d times, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRequests(t *testing.T) {
+	server, err := New(Config{MaxDepth: 12, MaxCost: 100, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"no query", http.MethodGet, Path, "", http.StatusBadRequest},
+		{"invalid variables", http.MethodGet, Path + "?query=%7Bme%7Bid%7D%7D&variables=%7B", "", http.StatusBadRequest},
+		{"invalid body", http.MethodPost, Path, "{", http.StatusBadRequest},
+		{"method", http.MethodPut, Path, "", http.StatusMethodNotAllowed},
+		{"anonymous me", http.MethodPost, Path, `{"query": "{ me { id } }"}`, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServeHTTPFragmentChain(t *testing.T) {
+	server, err := New(Config{MaxDepth: 12, MaxCost: 1000, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	start := time.Now()
+	body, _ := json.Marshal(map[string]string{"query": fragmentChain(40)})
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, strings.NewReader(string(body))))
+
+	if !strings.Contains(rec.Body.String(), "exceeds the limit of 1000") {
+		t.Errorf("response = %s, want the query rejected for its cost", rec.Body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("rejecting the query took %v", elapsed)
+	}
+}
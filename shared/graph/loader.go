@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"personalized-dashboard/shared/auth"
+)
+
+// maxResponseBytes caps how much of a service response is read.
+const maxResponseBytes = 10 << 20
+
+// loader fetches the service responses of one GraphQL query. Resolvers run
+// concurrently and often need the same response, e.g. recommendations both
+// at the top level and under me, so every URL is fetched once and the
+// resolvers asking for it share the result.
+type loader struct {
+	client *http.Client
+	// userID is the signed-in user, passed on as X-User-ID, and roles are
+	// their roles, passed on as X-User-Roles.
+	userID string
+	roles  string
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done chan struct{}
+	data json.RawMessage
+	err  error
+}
+
+type loaderKey struct{}
+
+func withLoader(ctx context.Context, l *loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+func loaderFrom(ctx context.Context) *loader {
+	return ctx.Value(loaderKey{}).(*loader)
+}
+
+// load decodes the response of url into v. The first resolver to ask for a
+// URL fetches it; the others wait for that fetch.
+func (l *loader) load(ctx context.Context, url string, v interface{}) error {
+	l.mu.Lock()
+	c, found := l.calls[url]
+	if !found {
+		c = &call{done: make(chan struct{})}
+		l.calls[url] = c
+	}
+	l.mu.Unlock()
+
+	if found {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	} else {
+		c.data, c.err = l.fetch(ctx, url)
+		close(c.done)
+	}
+
+	if c.err != nil {
+		return c.err
+	}
+	if err := json.Unmarshal(c.data, v); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
+
+func (l *loader) fetch(ctx context.Context, url string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if l.userID != "" {
+		req.Header.Set(auth.UserIDHeader, l.userID)
+	}
+	if l.roles != "" {
+		req.Header.Set(auth.RolesHeader, l.roles)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("service returned status %d: %s", resp.StatusCode, serviceError(resp.StatusCode, body))
+	}
+	return unwrap(body), nil
+}
+
+// unwrap returns the payload of a gofr {"data": ...} response, or body
+// itself for the net/http services.
+func unwrap(body []byte) json.RawMessage {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope) == 1 {
+		if data, ok := envelope["data"]; ok {
+			return data
+		}
+	}
+	return bytes.TrimSpace(body)
+}
+
+// serviceError picks the error message out of a service error response.
+func serviceError(status int, body []byte) string {
+	var payload struct {
+		Error   interface{} `json:"error"`
+		Message string      `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		switch e := payload.Error.(type) {
+		case string:
+			return e
+		case map[string]interface{}:
+			if message, ok := e["message"].(string); ok {
+				return message
+			}
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && len(text) <= 200 {
+		return text
+	}
+	return http.StatusText(status)
+}
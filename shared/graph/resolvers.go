@@ -0,0 +1,371 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/models"
+)
+
+// errNotSignedIn rejects user fields that only the user themselves may see.
+var errNotSignedIn = errors.New("only available for the signed-in user")
+
+// resolver is the root Query resolver.
+type resolver struct {
+	services func() map[string]string
+}
+
+type contentArgs struct {
+	Category *string
+	Source   *string
+	Limit    int32
+}
+
+type jobArgs struct {
+	Category *string
+	Location *string
+	Source   *string
+	Limit    int32
+}
+
+type categoryArgs struct {
+	Category *string
+	Limit    int32
+}
+
+func (r *resolver) News(ctx context.Context, args contentArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "news", "/api/news", query("category", args.Category, "source", args.Source), args.Limit)
+}
+
+func (r *resolver) Jobs(ctx context.Context, args jobArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "jobs", "/api/jobs", query("category", args.Category, "location", args.Location, "source", args.Source), args.Limit)
+}
+
+func (r *resolver) Videos(ctx context.Context, args contentArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "videos", "/api/videos", query("category", args.Category, "source", args.Source), args.Limit)
+}
+
+func (r *resolver) Deals(ctx context.Context, args contentArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "deals", "/api/deals", query("category", args.Category, "source", args.Source), args.Limit)
+}
+
+func (r *resolver) Movies(ctx context.Context, args categoryArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "movies", "/api/movies", query("category", args.Category), args.Limit)
+}
+
+func (r *resolver) Food(ctx context.Context, args categoryArgs) ([]*contentResolver, error) {
+	return r.content(ctx, "food", "/api/food", query("category", args.Category), args.Limit)
+}
+
+// content lists the items of a vertical. The limit is applied here rather
+// than by the service, so queries asking for different limits share a fetch.
+func (r *resolver) content(ctx context.Context, service, path string, params url.Values, limit int32) ([]*contentResolver, error) {
+	var payload struct {
+		Items []models.ContentItem `json:"items"`
+	}
+	if err := loaderFrom(ctx).load(ctx, r.url(service, path, params), &payload); err != nil {
+		return nil, err
+	}
+
+	items := truncate(payload.Items, limit)
+	resolvers := make([]*contentResolver, 0, len(items))
+	for _, item := range items {
+		resolvers = append(resolvers, &contentResolver{item: item})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) Recommendations(ctx context.Context, args categoryArgs) ([]*recommendationResolver, error) {
+	return r.recommendations(ctx, args)
+}
+
+func (r *resolver) recommendations(ctx context.Context, args categoryArgs) ([]*recommendationResolver, error) {
+	path := "/api/recommendations"
+	if args.Category != nil && *args.Category != "" {
+		path += "/" + url.PathEscape(*args.Category)
+	}
+
+	// The recommendation service takes the user from X-User-ID
+	var payload struct {
+		Recommendations []recommendation `json:"recommendations"`
+	}
+	if err := loaderFrom(ctx).load(ctx, r.url("recommendation", path, nil), &payload); err != nil {
+		return nil, err
+	}
+
+	recommendations := truncate(payload.Recommendations, args.Limit)
+	resolvers := make([]*recommendationResolver, 0, len(recommendations))
+	for _, rec := range recommendations {
+		resolvers = append(resolvers, &recommendationResolver{rec: rec})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	userID := auth.UserIDFrom(ctx)
+	if userID == "" {
+		return nil, nil
+	}
+	return r.user(ctx, userID)
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	return r.user(ctx, string(args.ID))
+}
+
+func (r *resolver) user(ctx context.Context, userID string) (*userResolver, error) {
+	var u user
+	if err := loaderFrom(ctx).load(ctx, r.url("user", "/api/users/"+url.PathEscape(userID), nil), &u); err != nil {
+		return nil, err
+	}
+	if u.ID == "" {
+		u.ID = userID
+	}
+	return &userResolver{root: r, user: u}, nil
+}
+
+func (r *resolver) nfts(ctx context.Context, userID string, limit int32) ([]*nftResolver, error) {
+	var payload struct {
+		NFTs []nft `json:"nfts"`
+	}
+	if err := loaderFrom(ctx).load(ctx, r.url("nft", "/api/nft/"+url.PathEscape(userID), nil), &payload); err != nil {
+		return nil, err
+	}
+
+	nfts := truncate(payload.NFTs, limit)
+	resolvers := make([]*nftResolver, 0, len(nfts))
+	for _, nft := range nfts {
+		resolvers = append(resolvers, &nftResolver{nft: nft})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) url(service, path string, params url.Values) string {
+	base := r.services()[service]
+	if len(params) == 0 {
+		return base + path
+	}
+	return base + path + "?" + params.Encode()
+}
+
+// query builds the query parameters from name/value pairs, leaving out the
+// values that are unset.
+func query(pairs ...interface{}) url.Values {
+	params := url.Values{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if value, ok := pairs[i+1].(*string); ok && value != nil && *value != "" {
+			params.Set(pairs[i].(string), *value)
+		}
+	}
+	return params
+}
+
+func truncate[T any](items []T, limit int32) []T {
+	if limit <= 0 {
+		return items[:0]
+	}
+	if int(limit) < len(items) {
+		return items[:limit]
+	}
+	return items
+}
+
+// contentResolver resolves a content item as the Content interface and as
+// each of the vertical types; the type of the item picks the one that
+// applies.
+type contentResolver struct {
+	item models.ContentItem
+}
+
+func (c *contentResolver) ID() graphql.ID             { return graphql.ID(c.item.ID) }
+func (c *contentResolver) Type() string               { return c.item.Type }
+func (c *contentResolver) Title() string              { return c.item.Title }
+func (c *contentResolver) Summary() string            { return c.item.Summary }
+func (c *contentResolver) URL() string                { return c.item.URL }
+func (c *contentResolver) Image() string              { return c.item.Image }
+func (c *contentResolver) Category() string           { return c.item.Category }
+func (c *contentResolver) Source() string             { return c.item.Source }
+func (c *contentResolver) PublishedAt() *graphql.Time { return timeValue(c.item.PublishedAt) }
+
+func (c *contentResolver) Location() *string       { return c.extraString("location") }
+func (c *contentResolver) Salary() *string         { return c.extraString("salary") }
+func (c *contentResolver) Duration() *string       { return c.extraString("duration") }
+func (c *contentResolver) Views() *float64         { return c.extraFloat("views") }
+func (c *contentResolver) Price() *float64         { return c.extraFloat("price") }
+func (c *contentResolver) OriginalPrice() *float64 { return c.extraFloat("original_price") }
+func (c *contentResolver) Discount() *float64      { return c.extraFloat("discount") }
+func (c *contentResolver) Rating() *float64        { return c.extraFloat("vote_average") }
+func (c *contentResolver) ReadyInMinutes() *int32  { return c.extraInt("readyInMinutes") }
+func (c *contentResolver) Servings() *int32        { return c.extraInt("servings") }
+func (c *contentResolver) HealthScore() *int32     { return c.extraInt("healthScore") }
+
+func (c *contentResolver) ValidUntil() *graphql.Time {
+	value := c.extraString("valid_until")
+	if value == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+// An item of an unknown type is served as an Article, which has no fields
+// beyond the envelope.
+func (c *contentResolver) ToArticle() (*contentResolver, bool) {
+	switch c.item.Type {
+	case models.ContentTypeJob, models.ContentTypeVideo, models.ContentTypeDeal, models.ContentTypeMovie, models.ContentTypeRecipe:
+		return nil, false
+	}
+	return c, true
+}
+
+func (c *contentResolver) ToJob() (*contentResolver, bool) {
+	return c, c.item.Type == models.ContentTypeJob
+}
+func (c *contentResolver) ToVideo() (*contentResolver, bool) {
+	return c, c.item.Type == models.ContentTypeVideo
+}
+func (c *contentResolver) ToDeal() (*contentResolver, bool) {
+	return c, c.item.Type == models.ContentTypeDeal
+}
+func (c *contentResolver) ToMovie() (*contentResolver, bool) {
+	return c, c.item.Type == models.ContentTypeMovie
+}
+func (c *contentResolver) ToRecipe() (*contentResolver, bool) {
+	return c, c.item.Type == models.ContentTypeRecipe
+}
+
+func (c *contentResolver) extraString(key string) *string {
+	switch value := c.item.Extras[key].(type) {
+	case string:
+		if value != "" {
+			return &value
+		}
+	}
+	return nil
+}
+
+func (c *contentResolver) extraFloat(key string) *float64 {
+	if value, ok := c.item.Extras[key].(float64); ok {
+		return &value
+	}
+	return nil
+}
+
+func (c *contentResolver) extraInt(key string) *int32 {
+	if value, ok := c.item.Extras[key].(float64); ok {
+		n := int32(value)
+		return &n
+	}
+	return nil
+}
+
+// recommendation is an item of the recommendation service response.
+type recommendation struct {
+	models.ContentItem
+	RecommendationScore float64 `json:"recommendation_score"`
+	Reason              string  `json:"reason"`
+}
+
+type recommendationResolver struct {
+	rec recommendation
+}
+
+func (r *recommendationResolver) Item() *contentResolver {
+	return &contentResolver{item: r.rec.ContentItem}
+}
+func (r *recommendationResolver) Score() float64 { return r.rec.RecommendationScore }
+func (r *recommendationResolver) Reason() string { return r.rec.Reason }
+
+// user is the user service's user.
+type user struct {
+	ID        string     `json:"id"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	Interests []string   `json:"interests"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type userResolver struct {
+	root *resolver
+	user user
+}
+
+func (u *userResolver) ID() graphql.ID           { return graphql.ID(u.user.ID) }
+func (u *userResolver) Email() string            { return u.user.Email }
+func (u *userResolver) Name() string             { return u.user.Name }
+func (u *userResolver) CreatedAt() *graphql.Time { return timeValue(u.user.CreatedAt) }
+func (u *userResolver) UpdatedAt() *graphql.Time { return timeValue(u.user.UpdatedAt) }
+
+func (u *userResolver) Interests() []string {
+	if u.user.Interests == nil {
+		return []string{}
+	}
+	return u.user.Interests
+}
+
+// signedIn reports whether u is the user making the request.
+func (u *userResolver) signedIn(ctx context.Context) bool {
+	userID := auth.UserIDFrom(ctx)
+	return userID != "" && userID == u.user.ID
+}
+
+func (u *userResolver) Recommendations(ctx context.Context, args categoryArgs) ([]*recommendationResolver, error) {
+	if !u.signedIn(ctx) {
+		return nil, errNotSignedIn
+	}
+	return u.root.recommendations(ctx, args)
+}
+
+func (u *userResolver) Nfts(ctx context.Context, args struct{ Limit int32 }) ([]*nftResolver, error) {
+	if !u.signedIn(ctx) {
+		return nil, errNotSignedIn
+	}
+	return u.root.nfts(ctx, u.user.ID, args.Limit)
+}
+
+// nft is a coupon of the NFT service response.
+type nft struct {
+	ID              string     `json:"id"`
+	TokenID         string     `json:"token_id"`
+	ContractAddress string     `json:"contract_address"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	Discount        float64    `json:"discount"`
+	Category        string     `json:"category"`
+	Status          string     `json:"status"`
+	MintedAt        *time.Time `json:"minted_at"`
+	ClaimedAt       *time.Time `json:"claimed_at"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+type nftResolver struct {
+	nft nft
+}
+
+func (n *nftResolver) ID() graphql.ID           { return graphql.ID(n.nft.ID) }
+func (n *nftResolver) TokenID() string          { return n.nft.TokenID }
+func (n *nftResolver) ContractAddress() string  { return n.nft.ContractAddress }
+func (n *nftResolver) Title() string            { return n.nft.Title }
+func (n *nftResolver) Description() string      { return n.nft.Description }
+func (n *nftResolver) Discount() float64        { return n.nft.Discount }
+func (n *nftResolver) Category() string         { return n.nft.Category }
+func (n *nftResolver) Status() string           { return n.nft.Status }
+func (n *nftResolver) MintedAt() *graphql.Time  { return timeValue(n.nft.MintedAt) }
+func (n *nftResolver) ClaimedAt() *graphql.Time { return timeValue(n.nft.ClaimedAt) }
+func (n *nftResolver) ExpiresAt() *graphql.Time { return timeValue(n.nft.ExpiresAt) }
+
+func timeValue(t *time.Time) *graphql.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
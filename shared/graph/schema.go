@@ -0,0 +1,155 @@
+package graph
+
+// schema is the GraphQL schema served at /graphql. Every vertical has its own
+// type; the fields they share are in the Content interface, which is also
+// what a recommendation points at.
+const schema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+type Query {
+	news(category: String, source: String, limit: Int = 10): [Article!]!
+	jobs(category: String, location: String, source: String, limit: Int = 10): [Job!]!
+	videos(category: String, source: String, limit: Int = 10): [Video!]!
+	deals(category: String, source: String, limit: Int = 10): [Deal!]!
+	movies(category: String, limit: Int = 10): [Movie!]!
+	food(category: String, limit: Int = 10): [Recipe!]!
+	# Recommendations for the signed-in user.
+	recommendations(category: String, limit: Int = 10): [Recommendation!]!
+	# The signed-in user, or null for anonymous requests.
+	me: User
+	user(id: ID!): User
+}
+
+interface Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+}
+
+type Article implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+}
+
+type Job implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+	location: String
+	salary: String
+}
+
+type Video implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+	duration: String
+	views: Float
+}
+
+type Deal implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+	price: Float
+	originalPrice: Float
+	discount: Float
+	validUntil: Time
+}
+
+type Movie implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+	rating: Float
+}
+
+type Recipe implements Content {
+	id: ID!
+	type: String!
+	title: String!
+	summary: String!
+	url: String!
+	image: String!
+	category: String!
+	source: String!
+	publishedAt: Time
+	readyInMinutes: Int
+	servings: Int
+	healthScore: Int
+}
+
+type Recommendation {
+	item: Content!
+	score: Float!
+	reason: String!
+}
+
+type User {
+	id: ID!
+	email: String!
+	name: String!
+	interests: [String!]!
+	createdAt: Time
+	updatedAt: Time
+	# Only available for the signed-in user.
+	recommendations(category: String, limit: Int = 10): [Recommendation!]!
+	# Only available for the signed-in user.
+	nfts(limit: Int = 10): [NFTCoupon!]!
+}
+
+type NFTCoupon {
+	id: ID!
+	tokenId: String!
+	contractAddress: String!
+	title: String!
+	description: String!
+	discount: Float!
+	category: String!
+	status: String!
+	mintedAt: Time
+	claimedAt: Time
+	expiresAt: Time
+}
+`
@@ -0,0 +1,371 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/tracing"
+)
+
+// Verdicts of the deep health report.
+const (
+	VerdictHealthy = "healthy"
+	// VerdictDegraded means every service is up but one is not ready or
+	// cannot use a provider's key.
+	VerdictDegraded  = "degraded"
+	VerdictUnhealthy = "unhealthy"
+)
+
+// StatusNotReady is a service that is up but whose /ready fails.
+const StatusNotReady = "not_ready"
+
+// probeTimeout bounds each /health and /ready call of the deep check.
+const probeTimeout = 3 * time.Second
+
+// maxProbeBytes caps how much of a probe response is read.
+const maxProbeBytes = 1 << 20
+
+// reportTTL is how long a deep report is served before the services are
+// probed again, so that frequent polling does not fan out to every service.
+const reportTTL = 5 * time.Second
+
+// ProbeResult is the outcome of calling a service's /health or /ready. A
+// service without a /ready probe reports it disabled.
+type ProbeResult struct {
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Latency    string `json:"latency,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ServiceHealth is one upstream in the deep health report. Checks and
+// Providers come from its /ready report.
+type ServiceHealth struct {
+	Status    string                    `json:"status"`
+	URL       string                    `json:"url"`
+	Health    ProbeResult               `json:"health"`
+	Ready     ProbeResult               `json:"ready"`
+	Checks    map[string]CheckResult    `json:"checks,omitempty"`
+	Providers map[string]ProviderStatus `json:"providers,omitempty"`
+}
+
+// DeepReport is the /health/deep response. Problems lists what keeps the
+// verdict from being healthy, one line each.
+type DeepReport struct {
+	Status    string                   `json:"status"`
+	Problems  []string                 `json:"problems"`
+	Services  map[string]ServiceHealth `json:"services"`
+	CheckedAt time.Time                `json:"checked_at"`
+}
+
+// DeepSummary is the /health/deep response for callers that may not see
+// the upstream URLs, probe errors and provider details of the full report.
+type DeepSummary struct {
+	Status    string            `json:"status"`
+	Services  map[string]string `json:"services"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+func (r *DeepReport) Summary() *DeepSummary {
+	summary := &DeepSummary{Status: r.Status, Services: make(map[string]string, len(r.Services)), CheckedAt: r.CheckedAt}
+	for name, service := range r.Services {
+		summary.Services[name] = service.Status
+	}
+	return summary
+}
+
+// UnhealthyError is returned by the gofr handler together with the report;
+// gofr uses StatusCode for the response status. Problem is only set for
+// callers allowed to see the full report.
+type UnhealthyError struct {
+	Problem string
+}
+
+func (e *UnhealthyError) Error() string {
+	if e.Problem != "" {
+		return "unhealthy: " + e.Problem
+	}
+	return "unhealthy"
+}
+
+func (e *UnhealthyError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+// Deep checks the services behind the gateway. upstreams returns the base
+// URL of every service by name, so route file reloads are picked up.
+type Deep struct {
+	upstreams func() map[string]string
+	client    *http.Client
+	ttl       time.Duration
+	now       func() time.Time
+
+	// mu is held while the services are probed, so that concurrent
+	// callers share one round of probes.
+	mu     sync.Mutex
+	report *DeepReport
+}
+
+func NewDeep(upstreams func() map[string]string) *Deep {
+	return &Deep{upstreams: upstreams, client: tracing.Client, ttl: reportTTL, now: time.Now}
+}
+
+// Check returns the latest report, probing every service's /health and
+// /ready concurrently when it is older than the report TTL. The report is
+// shared between callers and must not be modified.
+func (d *Deep) Check(ctx context.Context) *DeepReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.report == nil || d.now().Sub(d.report.CheckedAt) >= d.ttl {
+		// A caller going away must not leave its failed probes cached
+		d.report = d.check(context.WithoutCancel(ctx))
+	}
+	return d.report
+}
+
+func (d *Deep) check(ctx context.Context) *DeepReport {
+	upstreams := d.upstreams()
+	report := &DeepReport{
+		Status:    VerdictHealthy,
+		Problems:  []string{},
+		Services:  make(map[string]ServiceHealth, len(upstreams)),
+		CheckedAt: d.now(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, baseURL := range upstreams {
+		wg.Add(1)
+		go func(name, baseURL string) {
+			defer wg.Done()
+			service := d.checkService(ctx, baseURL)
+
+			mu.Lock()
+			report.Services[name] = service
+			mu.Unlock()
+		}(name, baseURL)
+	}
+	wg.Wait()
+
+	names := make([]string, 0, len(report.Services))
+	for name := range report.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := report.Services[name]
+		switch service.Status {
+		case StatusDown:
+			report.Status = VerdictUnhealthy
+			report.Problems = append(report.Problems, fmt.Sprintf("%s is down: %s", name, service.Health.Error))
+		case StatusNotReady:
+			report.degrade()
+			report.Problems = append(report.Problems, fmt.Sprintf("%s is not ready: %s", name, service.Ready.Error))
+		}
+
+		providers := make([]string, 0, len(service.Providers))
+		for provider := range service.Providers {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			if key := service.Providers[provider].Key; keyProblem(key) {
+				report.degrade()
+				report.Problems = append(report.Problems, fmt.Sprintf("%s: %s key is %s", name, provider, key))
+			}
+		}
+	}
+
+	return report
+}
+
+func (r *DeepReport) degrade() {
+	if r.Status == VerdictHealthy {
+		r.Status = VerdictDegraded
+	}
+}
+
+func (d *Deep) checkService(ctx context.Context, baseURL string) ServiceHealth {
+	service := ServiceHealth{Status: StatusUp, URL: baseURL}
+
+	var wg sync.WaitGroup
+	var readyBody []byte
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		service.Health, _ = d.probe(ctx, baseURL+"/health")
+	}()
+	go func() {
+		defer wg.Done()
+		service.Ready, readyBody = d.probe(ctx, baseURL+"/ready")
+	}()
+	wg.Wait()
+
+	if service.Ready.StatusCode == http.StatusNotFound {
+		service.Ready = ProbeResult{Status: StatusDisabled, StatusCode: http.StatusNotFound}
+	}
+
+	var ready Report
+	if err := json.Unmarshal(unwrap(readyBody), &ready); err == nil {
+		service.Checks = ready.Checks
+		service.Providers = ready.Providers
+		// Name the failing check rather than the status
+		if service.Ready.Status == StatusDown {
+			for name, check := range ready.Checks {
+				if check.Status == StatusDown {
+					service.Ready.Error = fmt.Sprintf("%s %s", name, check.Error)
+					break
+				}
+			}
+		}
+	}
+
+	switch {
+	case service.Health.Status == StatusDown:
+		service.Status = StatusDown
+	case service.Ready.Status == StatusDown:
+		service.Status = StatusNotReady
+	}
+	return service
+}
+
+// probe GETs url and returns the outcome with the response body.
+func (d *Deep) probe(ctx context.Context, url string) (ProbeResult, []byte) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := ProbeResult{Status: StatusUp}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ProbeResult{Status: StatusDown, Error: fmt.Sprintf("failed to create request: %v", err)}, nil
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return ProbeResult{Status: StatusDown, Latency: time.Since(start).String(), Error: fmt.Sprintf("failed to make request: %v", err)}, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBytes))
+	result.StatusCode = resp.StatusCode
+	result.Latency = time.Since(start).String()
+	switch {
+	case err != nil:
+		result.Status = StatusDown
+		result.Error = fmt.Sprintf("failed to read response: %v", err)
+	case resp.StatusCode >= 400:
+		result.Status = StatusDown
+		result.Error = http.StatusText(resp.StatusCode)
+	}
+	return result, body
+}
+
+// unwrap returns the payload of a gofr {"data": ...} response, or body
+// itself for the net/http services.
+func unwrap(body []byte) []byte {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		if data, ok := envelope["data"]; ok {
+			return data
+		}
+	}
+	return body
+}
+
+// Handle is the gofr-style handler for /health/deep. The report is returned
+// even when unhealthy so the failing service is visible; callers other than
+// admins and internal ones get the summary.
+func (d *Deep) Handle(ctx context.Context) (interface{}, error) {
+	report := d.Check(ctx)
+	detailed := Detailed(ctx)
+
+	var body interface{} = report.Summary()
+	if detailed {
+		body = report
+	}
+	if report.Status == VerdictUnhealthy {
+		err := &UnhealthyError{}
+		if detailed && len(report.Problems) > 0 {
+			err.Problem = report.Problems[0]
+		}
+		return body, err
+	}
+	return body, nil
+}
+
+// ServeHTTP serves /health/deep for the net/http entrypoints.
+func (d *Deep) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := d.Check(r.Context())
+
+	var body interface{} = report.Summary()
+	if Detailed(r.Context()) || internalCaller(r) {
+		body = report
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == VerdictUnhealthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+type internalKey struct{}
+
+// Internal marks the requests of internal callers for Handle, which cannot
+// see the request itself.
+func Internal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if internalCaller(r) {
+			r = r.WithContext(context.WithValue(r.Context(), internalKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Detailed reports whether the caller of ctx may see the full deep report:
+// an admin, or an internal caller marked by Internal.
+func Detailed(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalKey{}).(bool)
+	return internal || auth.HasRole(ctx, auth.RoleAdmin)
+}
+
+// ErrRestricted is the 403 reason for admin endpoints other callers asked for.
+var ErrRestricted = errors.New("only admins and internal callers may see this")
+
+// Restricted serves next only to admins and internal callers, the callers
+// Detailed trusts, and answers everyone else with 403.
+func Restricted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Detailed(r.Context()) && !internalCaller(r) {
+			auth.Forbidden(w, ErrRestricted)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// internalCaller reports whether r comes straight from the host or its
+// private network, such as a monitoring probe, rather than through a proxy.
+func internalCaller(r *http.Request) bool {
+	if r.Header.Get("X-Forwarded-For") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
@@ -0,0 +1,239 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+)
+
+// fakeService answers /health and /ready with the given statuses and counts
+// the probes it gets.
+func fakeService(t *testing.T, health, ready int, probes *atomic.Int32) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probes != nil {
+			probes.Add(1)
+		}
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(health)
+		case "/ready":
+			w.WriteHeader(ready)
+			json.NewEncoder(w).Encode(Report{Status: StatusUp, Providers: map[string]ProviderStatus{"newsapi": {Key: "configured"}}})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestDeepCheck(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	tests := []struct {
+		name         string
+		upstreams    map[string]string
+		wantStatus   string
+		wantServices map[string]string
+	}{
+		{
+			name:         "healthy",
+			upstreams:    map[string]string{"news": fakeService(t, 200, 200, nil), "jobs": fakeService(t, 200, 404, nil)},
+			wantStatus:   VerdictHealthy,
+			wantServices: map[string]string{"news": StatusUp, "jobs": StatusUp},
+		},
+		{
+			name:         "not ready",
+			upstreams:    map[string]string{"news": fakeService(t, 200, 200, nil), "jobs": fakeService(t, 200, 503, nil)},
+			wantStatus:   VerdictDegraded,
+			wantServices: map[string]string{"news": StatusUp, "jobs": StatusNotReady},
+		},
+		{
+			name:         "down",
+			upstreams:    map[string]string{"news": downURL, "jobs": fakeService(t, 200, 503, nil)},
+			wantStatus:   VerdictUnhealthy,
+			wantServices: map[string]string{"news": StatusDown, "jobs": StatusNotReady},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			deep := NewDeep(func() map[string]string { return tc.upstreams })
+			report := deep.Check(context.Background())
+
+			if report.Status != tc.wantStatus {
+				t.Errorf("status = %q (%v), want %q", report.Status, report.Problems, tc.wantStatus)
+			}
+			if summary := report.Summary(); len(summary.Services) != len(tc.wantServices) {
+				t.Fatalf("services = %v, want %v", summary.Services, tc.wantServices)
+			}
+			for name, want := range tc.wantServices {
+				if got := report.Summary().Services[name]; got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeepCheckCachesReport(t *testing.T) {
+	var probes atomic.Int32
+	url := fakeService(t, 200, 200, &probes)
+	deep := NewDeep(func() map[string]string { return map[string]string{"news": url} })
+	now := time.Unix(1700000000, 0)
+	deep.now = func() time.Time { return now }
+
+	first := deep.Check(context.Background())
+	if probes.Load() != 2 {
+		t.Fatalf("probes = %d, want /health and /ready", probes.Load())
+	}
+
+	now = now.Add(reportTTL - time.Millisecond)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if deep.Check(cancelled) != first || probes.Load() != 2 {
+		t.Errorf("probes = %d, want the report reused within the TTL", probes.Load())
+	}
+
+	// A cancelled caller still gets, and caches, real probe results
+	now = now.Add(time.Millisecond)
+	report := deep.Check(cancelled)
+	if report == first || probes.Load() != 4 || report.Status != VerdictHealthy {
+		t.Errorf("probes = %d, status = %q; want a fresh healthy report after the TTL", probes.Load(), report.Status)
+	}
+}
+
+func TestDeepServeHTTP(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+	deep := NewDeep(func() map[string]string { return map[string]string{"news": downURL} })
+	handler := auth.Identity(deep)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwarded    string
+		roles        string
+		wantDetailed bool
+	}{
+		{"public client", "203.0.113.7:4000", "", "", false},
+		{"public client with a user role", "203.0.113.7:4000", "", "support", false},
+		{"admin", "203.0.113.7:4000", "", "admin", true},
+		{"loopback", "127.0.0.1:4000", "", "", true},
+		{"private network", "10.1.2.3:4000", "", "", true},
+		{"forwarded through a local proxy", "127.0.0.1:4000", "203.0.113.7", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health/deep", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.roles != "" {
+				req.Header.Set(auth.UserIDHeader, "u1")
+				req.Header.Set(auth.RolesHeader, tc.roles)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want 503", rec.Code)
+			}
+			body := rec.Body.String()
+			if detailed := strings.Contains(body, downURL); detailed != tc.wantDetailed {
+				t.Errorf("body = %s, want detailed %v", body, tc.wantDetailed)
+			}
+			if !strings.Contains(body, `"news":`) || !strings.Contains(body, VerdictUnhealthy) {
+				t.Errorf("body = %s, want the verdict and the service", body)
+			}
+		})
+	}
+}
+
+func TestRestricted(t *testing.T) {
+	handler := auth.Identity(Restricted(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upstreams"))
+	})))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		roles      string
+		wantStatus int
+	}{
+		{"public client", "203.0.113.7:4000", "", "", http.StatusForbidden},
+		{"support", "203.0.113.7:4000", "", "support", http.StatusForbidden},
+		{"admin", "203.0.113.7:4000", "", "admin", http.StatusOK},
+		{"loopback", "127.0.0.1:4000", "", "", http.StatusOK},
+		{"forwarded through a local proxy", "127.0.0.1:4000", "203.0.113.7", "", http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/upstreams", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.roles != "" {
+				req.Header.Set(auth.UserIDHeader, "u1")
+				req.Header.Set(auth.RolesHeader, tc.roles)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if leaked := strings.Contains(rec.Body.String(), "upstreams"); leaked != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("body = %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeepHandle(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+	deep := NewDeep(func() map[string]string { return map[string]string{"news": downURL} })
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		wantDetailed bool
+	}{
+		{"internal", "127.0.0.1:4000", true},
+		{"external", "203.0.113.7:4000", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body interface{}
+			var err error
+			req := httptest.NewRequest(http.MethodGet, "/health/deep", nil)
+			req.RemoteAddr = tc.remoteAddr
+			Internal(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err = deep.Handle(r.Context())
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			var unhealthy *UnhealthyError
+			if !errors.As(err, &unhealthy) {
+				t.Fatalf("error = %v, want an UnhealthyError", err)
+			}
+			_, detailed := body.(*DeepReport)
+			if detailed != tc.wantDetailed || (unhealthy.Problem != "") != tc.wantDetailed {
+				t.Errorf("body %T, problem %q; want detailed %v", body, unhealthy.Problem, tc.wantDetailed)
+			}
+		})
+	}
+}
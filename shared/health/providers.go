@@ -0,0 +1,79 @@
+package health
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/metrics"
+)
+
+// Key statuses of a third-party provider.
+const (
+	KeyOK          = "ok"
+	KeyMissing     = "missing"
+	KeyInvalid     = "invalid"
+	KeyRateLimited = "rate_limited"
+	// KeyFailing means the provider's last call failed for another reason:
+	// no response, a server error or a rejected request.
+	KeyFailing = "failing"
+	// KeyUnchecked is a configured key the service has not used yet.
+	KeyUnchecked = "unchecked"
+)
+
+// Provider is a third-party API a service calls. Name is the provider as
+// metrics.Provider names it, e.g. newsapi; Env lists the variables its
+// credentials are read from.
+type Provider struct {
+	Name string
+	Env  []string
+}
+
+// ProviderStatus is the state of a provider's key: whether it is configured
+// and how the provider answered the last call made with it.
+type ProviderStatus struct {
+	Key        string     `json:"key"`
+	LastStatus int        `json:"last_status,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastCallAt *time.Time `json:"last_call_at,omitempty"`
+}
+
+// Status reports the key of p. Variables still holding the env.example
+// placeholders (your_..._here) count as missing, like in the services.
+func (p Provider) Status() ProviderStatus {
+	for _, name := range p.Env {
+		if value := os.Getenv(name); value == "" || strings.HasPrefix(value, "your_") {
+			return ProviderStatus{Key: KeyMissing}
+		}
+	}
+
+	call, ok := metrics.LastCall(p.Name)
+	if !ok {
+		return ProviderStatus{Key: KeyUnchecked}
+	}
+
+	status := ProviderStatus{LastStatus: call.Status, LastError: call.Error, LastCallAt: &call.At}
+	switch {
+	case call.Status == 0 || call.Status >= 500:
+		status.Key = KeyFailing
+	case call.Status == http.StatusUnauthorized || call.Status == http.StatusForbidden:
+		status.Key = KeyInvalid
+	case call.Status == http.StatusTooManyRequests:
+		status.Key = KeyRateLimited
+	case call.Status >= 400:
+		status.Key = KeyFailing
+	default:
+		status.Key = KeyOK
+	}
+	return status
+}
+
+// keyProblem reports whether status is a key the service cannot use.
+func keyProblem(status string) bool {
+	switch status {
+	case KeyMissing, KeyInvalid, KeyRateLimited, KeyFailing:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+// Package health implements the /ready probes of the services and the
+// gateway's /health/deep. Unlike /health, which only says the process is up,
+// /ready checks the dependencies a service needs to answer requests and
+// reports the keys of the third-party providers it calls.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"personalized-dashboard/shared/database"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDisabled = "disabled"
+)
+
+// checkTimeout bounds every individual check so a hung dependency makes the
+// probe fail instead of time out.
+const checkTimeout = 2 * time.Second
+
+// CheckResult is the outcome of one dependency check. A disabled dependency
+// is optional and not configured, so it does not make the service unready.
+type CheckResult struct {
+	Status  string      `json:"status"`
+	Latency string      `json:"latency,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type Check func(ctx context.Context) CheckResult
+
+// Report is a /ready response. Provider keys are informational: a service
+// without a working key serves static data and is still ready.
+type Report struct {
+	Service   string                    `json:"service"`
+	Status    string                    `json:"status"`
+	Checks    map[string]CheckResult    `json:"checks"`
+	Providers map[string]ProviderStatus `json:"providers,omitempty"`
+	CheckedAt time.Time                 `json:"checked_at"`
+}
+
+func (r *Report) Ready() bool {
+	return r.Status == "ready"
+}
+
+// NotReadyError is returned by the gofr handler together with the report;
+// gofr uses StatusCode for the response status.
+type NotReadyError struct {
+	Report *Report
+}
+
+func (e *NotReadyError) Error() string {
+	for name, check := range e.Report.Checks {
+		if check.Status == StatusDown {
+			return fmt.Sprintf("%s is not ready: %s %s", e.Report.Service, name, check.Error)
+		}
+	}
+	return e.Report.Service + " is not ready"
+}
+
+func (e *NotReadyError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+type Readiness struct {
+	service   string
+	names     []string
+	checks    map[string]Check
+	providers []Provider
+}
+
+func NewReadiness(service string) *Readiness {
+	return &Readiness{service: service, checks: make(map[string]Check)}
+}
+
+// Add registers a named check and returns r so calls can be chained.
+func (r *Readiness) Add(name string, check Check) *Readiness {
+	r.names = append(r.names, name)
+	r.checks[name] = check
+	return r
+}
+
+// Providers registers the third-party providers whose keys the report
+// includes and returns r so calls can be chained.
+func (r *Readiness) Providers(providers ...Provider) *Readiness {
+	r.providers = append(r.providers, providers...)
+	return r
+}
+
+// Check runs every registered check concurrently.
+func (r *Readiness) Check(ctx context.Context) *Report {
+	report := &Report{
+		Service:   r.service,
+		Status:    "ready",
+		Checks:    make(map[string]CheckResult, len(r.names)),
+		CheckedAt: time.Now(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range r.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			result := check(checkCtx)
+			if result.Status != StatusDisabled {
+				result.Latency = time.Since(start).String()
+			}
+
+			mu.Lock()
+			report.Checks[name] = result
+			if result.Status == StatusDown {
+				report.Status = "not_ready"
+			}
+			mu.Unlock()
+		}(name, r.checks[name])
+	}
+	wg.Wait()
+
+	if len(r.providers) > 0 {
+		report.Providers = make(map[string]ProviderStatus, len(r.providers))
+		for _, provider := range r.providers {
+			report.Providers[provider.Name] = provider.Status()
+		}
+	}
+
+	return report
+}
+
+// Handle is the gofr-style handler for /ready. The report is returned even
+// when the service is not ready so the failing check is visible.
+func (r *Readiness) Handle(ctx context.Context) (interface{}, error) {
+	report := r.Check(ctx)
+	if !report.Ready() {
+		return report, &NotReadyError{Report: report}
+	}
+	return report, nil
+}
+
+// ServeHTTP serves /ready for the net/http entrypoints.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Database pings db and reports its pool statistics. A nil db means the
+// service runs without a database.
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) CheckResult {
+		if db == nil {
+			return CheckResult{Status: StatusDisabled}
+		}
+
+		result := CheckResult{Status: StatusUp, Details: database.Stats(db)}
+		if err := db.PingContext(ctx); err != nil {
+			result.Status = StatusDown
+			result.Error = err.Error()
+		}
+		return result
+	}
+}
+
+// OpenedDatabase checks the database a service opened at startup with
+// openErr as the outcome. A service that failed to open a configured
+// database stays down, so it is not reported ready without the database it
+// was deployed with; one that runs without a configured database reports it
+// disabled.
+func OpenedDatabase(db *sql.DB, openErr error) Check {
+	if openErr == nil {
+		return Database(db)
+	}
+	if !database.Configured() {
+		return Database(nil)
+	}
+
+	message := fmt.Sprintf("failed to open at startup: %v", openErr)
+	return func(ctx context.Context) CheckResult {
+		return CheckResult{Status: StatusDown, Error: message}
+	}
+}
+
+// Store is the part of a go-cache *cache.Cache the cache check uses.
+type Store interface {
+	Set(key string, value interface{}, expiration time.Duration)
+	Get(key string) (interface{}, bool)
+	Delete(key string)
+	ItemCount() int
+}
+
+// Cache writes and reads back a probe key and reports the item count.
+func Cache(store Store) Check {
+	return func(ctx context.Context) CheckResult {
+		if store == nil {
+			return CheckResult{Status: StatusDisabled}
+		}
+
+		details := map[string]int{"items": store.ItemCount()}
+
+		const probeKey = "__ready_probe"
+		probe := time.Now().UnixNano()
+		store.Set(probeKey, probe, time.Minute)
+		defer store.Delete(probeKey)
+
+		if value, found := store.Get(probeKey); !found || value != probe {
+			return CheckResult{Status: StatusDown, Error: "cache did not return the probe value", Details: details}
+		}
+		return CheckResult{Status: StatusUp, Details: details}
+	}
+}
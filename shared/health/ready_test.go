@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/database/dbtest"
+)
+
+// mapStore is a Store without expiry.
+type mapStore map[string]interface{}
+
+func (s mapStore) Set(key string, value interface{}, expiration time.Duration) { s[key] = value }
+func (s mapStore) Delete(key string)                                           { delete(s, key) }
+func (s mapStore) ItemCount() int                                              { return len(s) }
+
+func (s mapStore) Get(key string) (interface{}, bool) {
+	value, ok := s[key]
+	return value, ok
+}
+
+func TestOpenedDatabase(t *testing.T) {
+	db := dbtest.SQLite(t)
+	openErr := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		configured bool
+		db         bool
+		openErr    error
+		want       string
+	}{
+		{"opened", true, true, nil, StatusUp},
+		{"configured but unreachable", true, false, openErr, StatusDown},
+		{"not configured", false, false, openErr, StatusDisabled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "")
+			t.Setenv("DATABASE_DRIVER", "")
+			if tc.configured {
+				t.Setenv("DATABASE_URL", "postgres://db.internal/dashboard")
+			}
+			opened := db
+			if !tc.db {
+				opened = nil
+			}
+
+			result := OpenedDatabase(opened, tc.openErr)(context.Background())
+			if result.Status != tc.want {
+				t.Errorf("status = %q (%s), want %q", result.Status, result.Error, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	up := func(ctx context.Context) CheckResult { return CheckResult{Status: StatusUp} }
+	down := func(ctx context.Context) CheckResult { return CheckResult{Status: StatusDown, Error: "boom"} }
+	disabled := func(ctx context.Context) CheckResult { return CheckResult{Status: StatusDisabled} }
+	hung := func(ctx context.Context) CheckResult {
+		<-ctx.Done()
+		return CheckResult{Status: StatusDown, Error: ctx.Err().Error()}
+	}
+
+	tests := []struct {
+		name   string
+		checks map[string]Check
+		want   int
+	}{
+		{"all up", map[string]Check{"database": up, "cache": Cache(mapStore{})}, http.StatusOK},
+		{"disabled is optional", map[string]Check{"database": disabled}, http.StatusOK},
+		{"one down", map[string]Check{"database": down, "cache": up}, http.StatusServiceUnavailable},
+		{"hung check times out", map[string]Check{"database": hung}, http.StatusServiceUnavailable},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			readiness := NewReadiness("test")
+			for name, check := range tc.checks {
+				readiness.Add(name, check)
+			}
+
+			rec := httptest.NewRecorder()
+			readiness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+
+			_, err := readiness.Handle(context.Background())
+			var notReady *NotReadyError
+			if got := errors.As(err, &notReady); got != (tc.want != http.StatusOK) {
+				t.Errorf("Handle error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package httpcache is the gateway's response cache. It stores successful
+// GET responses of routes with a TTL, honours the upstream's Cache-Control
+// and Vary, keeps personalized responses apart per user and answers
+// conditional requests with 304 Not Modified.
+package httpcache
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config sizes the cache.
+type Config struct {
+	// MaxBytes bounds the size of all entries together; the least recently
+	// used entries are evicted first. Zero disables the cache.
+	MaxBytes int64
+	// MaxEntryBytes bounds a single response body. Larger responses are
+	// streamed to the client and not stored.
+	MaxEntryBytes int64
+}
+
+func DefaultConfig() Config {
+	return Config{MaxBytes: 64 << 20, MaxEntryBytes: 1 << 20}
+}
+
+// ConfigFromEnv reads GATEWAY_CACHE_MAX_BYTES and
+// GATEWAY_CACHE_MAX_ENTRY_BYTES on top of the defaults.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	for name, target := range map[string]*int64{
+		"GATEWAY_CACHE_MAX_BYTES":       &cfg.MaxBytes,
+		"GATEWAY_CACHE_MAX_ENTRY_BYTES": &cfg.MaxEntryBytes,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 0 {
+				return cfg, fmt.Errorf("invalid %s: %q is not a byte count", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	return cfg, nil
+}
+
+// Policy is how a route is cached.
+type Policy struct {
+	// TTL is how long a response is fresh unless the upstream's
+	// Cache-Control asks for less. Zero leaves the route uncached.
+	TTL time.Duration
+	// PerUser keeps a separate entry for every authenticated user.
+	PerUser bool
+	// Group names the upstream. A successful write by a user drops that
+	// user's cached responses from the same upstream.
+	Group string
+}
+
+// entry is a stored response.
+type entry struct {
+	key   string
+	path  string
+	group string
+	// personal entries belong to user; user is empty for anonymous requests.
+	personal bool
+	user     string
+
+	status int
+	header http.Header
+	body   []byte
+	etag   string
+	// vary holds the request header values the response varies on.
+	vary map[string]string
+
+	stored  time.Time
+	expires time.Time
+	size    int64
+}
+
+// Cache is an in-memory LRU cache of responses, shared by all routes.
+type Cache struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	size    int64
+
+	evictions atomic.Int64
+}
+
+func New(cfg Config) *Cache {
+	return &Cache{
+		cfg:     cfg,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the fresh entry for key that matches the request's Vary
+// headers, or nil.
+func (c *Cache) get(key string, r *http.Request) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := element.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(element)
+		return nil
+	}
+	for name, value := range e.vary {
+		if r.Header.Get(name) != value {
+			return nil
+		}
+	}
+
+	c.order.MoveToFront(element)
+	return e
+}
+
+// put stores e, replacing the entry under the same key and evicting the
+// least recently used entries until the cache fits.
+func (c *Cache) put(e *entry) {
+	if e.size > c.cfg.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[e.key]; ok {
+		c.remove(element)
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	c.size += e.size
+
+	for c.size > c.cfg.MaxBytes {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate drops the entries for path and the personal entries user has
+// from group.
+func (c *Cache) invalidate(path, group, user string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, element := range c.entries {
+		e := element.Value.(*entry)
+		if e.path == path || (e.personal && e.user == user && e.group == group) {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *Cache) remove(element *list.Element) {
+	e := c.order.Remove(element).(*entry)
+	delete(c.entries, e.key)
+	c.size -= e.size
+}
+
+// Stats is a snapshot of the cache's counters.
+type Stats struct {
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	MaxBytes  int64 `json:"max_bytes"`
+	Evictions int64 `json:"evictions"`
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   len(c.entries),
+		Bytes:     c.size,
+		MaxBytes:  c.cfg.MaxBytes,
+		Evictions: c.evictions.Load(),
+	}
+}
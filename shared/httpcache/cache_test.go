@@ -0,0 +1,95 @@
+package httpcache
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes string
+		entry    string
+		want     Config
+		wantErr  bool
+	}{
+		{"defaults", "", "", DefaultConfig(), false},
+		{"overrides", "1024", "64", Config{MaxBytes: 1024, MaxEntryBytes: 64}, false},
+		{"disabled", "0", "", Config{MaxBytes: 0, MaxEntryBytes: DefaultConfig().MaxEntryBytes}, false},
+		{"negative", "-1", "", Config{}, true},
+		{"not a number", "", "1MB", Config{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GATEWAY_CACHE_MAX_BYTES", tc.maxBytes)
+			t.Setenv("GATEWAY_CACHE_MAX_ENTRY_BYTES", tc.entry)
+
+			cfg, err := ConfigFromEnv()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ConfigFromEnv error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && cfg != tc.want {
+				t.Errorf("config = %+v, want %+v", cfg, tc.want)
+			}
+		})
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := New(Config{MaxBytes: 30, MaxEntryBytes: 30})
+	c.now = func() time.Time { return now }
+	r := httptest.NewRequest("GET", "/", nil)
+
+	put := func(key string) {
+		c.put(&entry{key: key, size: 10, expires: now.Add(time.Minute)})
+	}
+	put("a")
+	put("b")
+	put("c")
+	c.get("a", r) // a is now the most recently used
+	put("d")
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if got := c.get(key, r) != nil; got != want {
+			t.Errorf("entry %s cached = %v, want %v", key, got, want)
+		}
+	}
+	if stats := c.Stats(); stats.Entries != 3 || stats.Bytes != 30 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want 3 entries of 30 bytes after one eviction", stats)
+	}
+
+	c.put(&entry{key: "huge", size: 31, expires: now.Add(time.Minute)})
+	if c.get("huge", r) != nil || c.Stats().Entries != 3 {
+		t.Error("an entry larger than the cache should not be stored")
+	}
+
+	now = now.Add(time.Minute)
+	if c.get("a", r) != nil || c.Stats().Entries != 2 {
+		t.Error("an expired entry should be dropped on lookup")
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c := New(DefaultConfig())
+	expires := time.Now().Add(time.Minute)
+	entries := []*entry{
+		{key: "news", path: "/api/news", group: "news", expires: expires},
+		{key: "news alice", path: "/api/news/mine", group: "news", personal: true, user: "alice", expires: expires},
+		{key: "news bob", path: "/api/news/mine", group: "news", personal: true, user: "bob", expires: expires},
+		{key: "movies alice", path: "/api/movies", group: "movies", personal: true, user: "alice", expires: expires},
+	}
+	for _, e := range entries {
+		c.put(e)
+	}
+
+	c.invalidate("/api/news", "news", "alice")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for key, want := range map[string]bool{"news": false, "news alice": false, "news bob": true, "movies alice": true} {
+		if got := c.get(key, r) != nil; got != want {
+			t.Errorf("entry %q cached = %v, want %v", key, got, want)
+		}
+	}
+}
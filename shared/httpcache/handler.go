@@ -0,0 +1,343 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/tracing"
+)
+
+// Serve answers r for a route cached with policy. Fresh entries are served
+// without calling next; otherwise next answers and a cacheable response is
+// stored on the way out. GET and HEAD responses from either path carry a
+// strong ETag, and a matching If-None-Match gets 304 Not Modified. A
+// successful request with any other method invalidates the entries it may
+// have changed.
+func (c *Cache) Serve(w http.ResponseWriter, r *http.Request, policy Policy, next http.Handler) {
+	user := auth.UserIDFrom(r.Context())
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status < http.StatusBadRequest {
+			c.invalidate(r.URL.EscapedPath(), policy.Group, user)
+		}
+		return
+	}
+	if policy.TTL <= 0 || c.cfg.MaxBytes <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	key := cacheKey(r, policy, user)
+	requestCC := parseCacheControl(r.Header)
+	if !requestCC.has("no-cache") && requestCC["max-age"] != "0" {
+		if e := c.get(key, r); e != nil {
+			metrics.CacheLookup("gateway", true)
+			c.respond(w, r, e, policy, "HIT")
+			return
+		}
+	}
+	metrics.CacheLookup("gateway", false)
+
+	rec := &recorder{w: w, header: make(http.Header), limit: c.cfg.MaxEntryBytes}
+	rec.lifetime = func(status int, header http.Header) time.Duration {
+		if r.Method == http.MethodHead || requestCC.has("no-store") {
+			return 0
+		}
+		return lifetime(policy, status, header)
+	}
+	next.ServeHTTP(rec, r)
+	if rec.status == 0 || rec.streaming {
+		return
+	}
+
+	now := c.now()
+	e := &entry{
+		key:     key,
+		path:    r.URL.EscapedPath(),
+		group:   policy.Group,
+		status:  rec.status,
+		header:  rec.header,
+		body:    rec.body.Bytes(),
+		etag:    etag(rec.header, rec.body.Bytes()),
+		vary:    varyValues(r, rec.header),
+		stored:  now,
+		expires: now.Add(rec.ttl),
+	}
+	if policy.PerUser {
+		e.personal, e.user = true, user
+	}
+	for _, name := range perRequestHeaders {
+		e.header.Del(name)
+	}
+	e.size = int64(len(key) + len(e.body))
+	for name, values := range e.header {
+		for _, value := range values {
+			e.size += int64(len(name) + len(value))
+		}
+	}
+	c.put(e)
+
+	c.respond(w, r, e, policy, "MISS")
+}
+
+// perRequestHeaders describe the request that filled an entry rather than
+// the response, and are not replayed.
+var perRequestHeaders = []string{
+	"Date", "Retry-After", tracing.RequestIDHeader,
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+}
+
+// cacheKey identifies a response by path, query and, for personalized
+// routes, user. HEAD shares the entries of GET.
+func cacheKey(r *http.Request, policy Policy, user string) string {
+	key := r.URL.EscapedPath() + "?" + r.URL.Query().Encode()
+	if policy.PerUser {
+		key += "\x00" + user
+	}
+	return key
+}
+
+// respond writes e to the client, or 304 when the client already has it.
+func (c *Cache) respond(w http.ResponseWriter, r *http.Request, e *entry, policy Policy, state string) {
+	header := w.Header()
+	for name, values := range e.header {
+		header[name] = values
+	}
+
+	age := int(c.now().Sub(e.stored) / time.Second)
+	maxAge := int(e.expires.Sub(e.stored)/time.Second) - age
+	cacheControl := "max-age=" + strconv.Itoa(max(maxAge, 0))
+	if policy.PerUser {
+		cacheControl = "private, " + cacheControl
+	}
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", e.etag)
+	header.Set("Age", strconv.Itoa(age))
+	header.Set("X-Cache", state)
+
+	if noneMatch(r.Header.Get("If-None-Match"), e.etag) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(e.body)))
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		w.Write(e.body)
+	}
+}
+
+// lifetime returns how long a response may be stored, or zero when it must
+// not be: only 200 responses without cookies or trailers are kept, for at
+// most the route TTL and never longer than the upstream allows.
+func lifetime(policy Policy, status int, header http.Header) time.Duration {
+	if status != http.StatusOK || header.Get("Set-Cookie") != "" || header.Get("Trailer") != "" {
+		return 0
+	}
+	for _, name := range varyNames(header) {
+		if name == "*" {
+			return 0
+		}
+	}
+
+	cc := parseCacheControl(header)
+	if cc.has("no-store") || cc.has("no-cache") || (cc.has("private") && !policy.PerUser) {
+		return 0
+	}
+
+	ttl := policy.TTL
+	maxAge, ok := cc["s-maxage"]
+	if !ok {
+		maxAge, ok = cc["max-age"]
+	}
+	if ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds <= 0 {
+			return 0
+		}
+		if upstream := time.Duration(seconds) * time.Second; upstream < ttl {
+			ttl = upstream
+		}
+	}
+	return ttl
+}
+
+type cacheControl map[string]string
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// parseCacheControl reads the Cache-Control directives of header.
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// varyValues records the request headers the response varies on, so that
+// only requests with the same values are served the entry.
+func varyValues(r *http.Request, header http.Header) map[string]string {
+	names := varyNames(header)
+	if len(names) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		values[name] = r.Header.Get(name)
+	}
+	return values
+}
+
+// etag keeps a strong ETag from the upstream and otherwise derives one from
+// the body, so that an unchanged body keeps its ETag across refreshes.
+func etag(header http.Header, body []byte) string {
+	if tag := header.Get("ETag"); strings.HasPrefix(tag, `"`) {
+		return tag
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// noneMatch reports whether an If-None-Match value lists tag. Weak and
+// strong tags compare equal, as RFC 9110 asks for If-None-Match.
+func noneMatch(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder buffers a response so that it can be stored. Responses that
+// cannot be stored — because of their status or headers, because they are
+// event streams or because they outgrow limit — are passed through to the
+// client as they arrive instead.
+type recorder struct {
+	w        http.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	lifetime func(status int, header http.Header) time.Duration
+	ttl      time.Duration
+	// streaming is set once the response goes straight to the client.
+	streaming bool
+}
+
+func (rec *recorder) Header() http.Header {
+	if rec.streaming {
+		return rec.w.Header()
+	}
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	// Informational responses are not passed on
+	if rec.status != 0 || status < http.StatusOK {
+		return
+	}
+	rec.status = status
+
+	rec.ttl = rec.lifetime(status, rec.header)
+	length, _ := strconv.ParseInt(rec.header.Get("Content-Length"), 10, 64)
+	if rec.ttl <= 0 || length > rec.limit || strings.HasPrefix(rec.header.Get("Content-Type"), "text/event-stream") {
+		rec.stream()
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.streaming && int64(rec.body.Len()+len(p)) > rec.limit {
+		rec.stream()
+	}
+	if rec.streaming {
+		return rec.w.Write(p)
+	}
+	return rec.body.Write(p)
+}
+
+func (rec *recorder) Flush() {
+	if rec.streaming {
+		http.NewResponseController(rec.w).Flush()
+	}
+}
+
+// stream sends what has been recorded so far and passes the rest through.
+func (rec *recorder) stream() {
+	if rec.streaming {
+		return
+	}
+	rec.streaming = true
+
+	header := rec.w.Header()
+	for name, values := range rec.header {
+		header[name] = values
+	}
+	rec.w.WriteHeader(rec.status)
+	if rec.body.Len() > 0 {
+		rec.w.Write(rec.body.Bytes())
+		rec.body.Reset()
+	}
+}
+
+// statusWriter remembers the status of a response it passes through.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 && status >= http.StatusOK {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
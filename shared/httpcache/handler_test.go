@@ -0,0 +1,328 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/tracing"
+)
+
+// upstream counts its calls and answers with the call number, so that a
+// cached response is told apart from a fresh one.
+type upstream struct {
+	calls  int
+	header http.Header
+	status int
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.calls++
+	for name, values := range u.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if u.status != 0 {
+		w.WriteHeader(u.status)
+	}
+	fmt.Fprintf(w, `{"call":%d,"user":%q}`, u.calls, auth.UserIDFrom(r.Context()))
+}
+
+func do(c *Cache, policy Policy, next http.Handler, method, target, user string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if user != "" {
+		req.Header.Set(auth.UserIDHeader, user)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	auth.Identity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Serve(w, r, policy, next)
+	})).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServe(t *testing.T) {
+	type request struct {
+		method string
+		target string
+		user   string
+		header map[string]string
+		// wantCall is the upstream call that produced the body.
+		wantCall   int
+		wantStatus int
+		wantCache  string
+	}
+
+	policy := Policy{TTL: time.Minute, Group: "news"}
+	personal := Policy{TTL: time.Minute, Group: "news", PerUser: true}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		upstream upstream
+		requests []request
+	}{
+		{
+			name:   "miss then hit",
+			policy: policy,
+			requests: []request{
+				{method: "GET", target: "/api/news?b=2&a=1", wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news?a=1&b=2", wantCall: 1, wantStatus: 200, wantCache: "HIT"},
+				{method: "HEAD", target: "/api/news?a=1&b=2", wantStatus: 200, wantCache: "HIT"},
+				{method: "GET", target: "/api/news?a=2", wantCall: 2, wantStatus: 200, wantCache: "MISS"},
+			},
+		},
+		{
+			name:   "client no-cache revalidates",
+			policy: policy,
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", header: map[string]string{"Cache-Control": "no-cache"}, wantCall: 2, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 200, wantCache: "HIT"},
+			},
+		},
+		{
+			name:   "per user",
+			policy: personal,
+			requests: []request{
+				{method: "GET", target: "/api/news", user: "alice", wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", user: "bob", wantCall: 2, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", user: "alice", wantCall: 1, wantStatus: 200, wantCache: "HIT"},
+				{method: "GET", target: "/api/news", wantCall: 3, wantStatus: 200, wantCache: "MISS"},
+			},
+		},
+		{
+			name:   "shared route ignores the user",
+			policy: policy,
+			requests: []request{
+				{method: "GET", target: "/api/news", user: "alice", wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", user: "bob", wantCall: 1, wantStatus: 200, wantCache: "HIT"},
+			},
+		},
+		{
+			name:   "write invalidates",
+			policy: personal,
+			requests: []request{
+				{method: "GET", target: "/api/news", user: "alice", wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "POST", target: "/api/news", user: "alice", wantCall: 2, wantStatus: 200},
+				{method: "GET", target: "/api/news", user: "alice", wantCall: 3, wantStatus: 200, wantCache: "MISS"},
+			},
+		},
+		{
+			name:     "vary",
+			policy:   policy,
+			upstream: upstream{header: http.Header{"Vary": {"Accept-Language"}}},
+			requests: []request{
+				{method: "GET", target: "/api/news", header: map[string]string{"Accept-Language": "en"}, wantCall: 1, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", header: map[string]string{"Accept-Language": "de"}, wantCall: 2, wantStatus: 200, wantCache: "MISS"},
+				{method: "GET", target: "/api/news", header: map[string]string{"Accept-Language": "de"}, wantCall: 2, wantStatus: 200, wantCache: "HIT"},
+			},
+		},
+		{
+			name:     "upstream no-store",
+			policy:   policy,
+			upstream: upstream{header: http.Header{"Cache-Control": {"no-store"}}},
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 200},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 200},
+			},
+		},
+		{
+			name:     "private response on a shared route",
+			policy:   policy,
+			upstream: upstream{header: http.Header{"Cache-Control": {"private, max-age=60"}}},
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 200},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 200},
+			},
+		},
+		{
+			name:     "cookies",
+			policy:   policy,
+			upstream: upstream{header: http.Header{"Set-Cookie": {"session=1"}}},
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 200},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 200},
+			},
+		},
+		{
+			name:     "errors",
+			policy:   policy,
+			upstream: upstream{status: http.StatusNotFound},
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 404},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 404},
+			},
+		},
+		{
+			name:   "uncached route",
+			policy: Policy{},
+			requests: []request{
+				{method: "GET", target: "/api/news", wantCall: 1, wantStatus: 200},
+				{method: "GET", target: "/api/news", wantCall: 2, wantStatus: 200},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(DefaultConfig())
+			next := tc.upstream
+			for i, req := range tc.requests {
+				rec := do(c, tc.policy, &next, req.method, req.target, req.user, req.header)
+
+				if rec.Code != req.wantStatus {
+					t.Fatalf("request %d: status = %d, want %d", i, rec.Code, req.wantStatus)
+				}
+				if got := rec.Header().Get("X-Cache"); got != req.wantCache {
+					t.Errorf("request %d: X-Cache = %q, want %q", i, got, req.wantCache)
+				}
+				if req.method == "GET" && !strings.Contains(rec.Body.String(), fmt.Sprintf(`"call":%d,`, req.wantCall)) {
+					t.Errorf("request %d: body = %s, want the response of call %d", i, rec.Body, req.wantCall)
+				}
+			}
+		})
+	}
+}
+
+func TestServeConditional(t *testing.T) {
+	c := New(DefaultConfig())
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+	policy := Policy{TTL: time.Minute}
+	next := &upstream{}
+
+	first := do(c, policy, next, "GET", "/api/news", "", nil)
+	tag := first.Header().Get("ETag")
+	if !strings.HasPrefix(tag, `"`) {
+		t.Fatalf("ETag = %q, want a strong tag", tag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "max-age=60" {
+		t.Errorf("Cache-Control = %q, want max-age=60", got)
+	}
+
+	now = now.Add(20 * time.Second)
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"matching", tag, http.StatusNotModified},
+		{"weak form", "W/" + tag, http.StatusNotModified},
+		{"in a list", `"other", ` + tag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"changed", `"other"`, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(c, policy, next, "GET", "/api/news", "", map[string]string{"If-None-Match": tc.ifNoneMatch})
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if tc.wantStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 with body %q", rec.Body)
+			}
+			if rec.Header().Get("Age") != "20" || rec.Header().Get("Cache-Control") != "max-age=40" {
+				t.Errorf("Age = %q, Cache-Control = %q; want the remaining freshness", rec.Header().Get("Age"), rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+	if next.calls != 1 {
+		t.Errorf("upstream called %d times, want conditional requests answered from the cache", next.calls)
+	}
+}
+
+func TestServeStreamsLargeResponses(t *testing.T) {
+	c := New(Config{MaxBytes: 1 << 20, MaxEntryBytes: 8})
+	next := &upstream{}
+
+	for call := 1; call <= 2; call++ {
+		rec := do(c, Policy{TTL: time.Minute}, next, "GET", "/api/news", "", nil)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), fmt.Sprintf(`"call":%d,`, call)) {
+			t.Fatalf("call %d: %d %s, want the upstream's response passed through", call, rec.Code, rec.Body)
+		}
+	}
+	if c.Stats().Entries != 0 {
+		t.Error("a response larger than MaxEntryBytes should not be stored")
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		status int
+		header http.Header
+		want   time.Duration
+	}{
+		{"route TTL", Policy{TTL: time.Minute}, 200, http.Header{}, time.Minute},
+		{"shorter max-age", Policy{TTL: time.Minute}, 200, http.Header{"Cache-Control": {"max-age=10"}}, 10 * time.Second},
+		{"longer max-age", Policy{TTL: time.Minute}, 200, http.Header{"Cache-Control": {"max-age=600"}}, time.Minute},
+		{"s-maxage wins", Policy{TTL: time.Minute}, 200, http.Header{"Cache-Control": {"max-age=5, s-maxage=20"}}, 20 * time.Second},
+		{"max-age=0", Policy{TTL: time.Minute}, 200, http.Header{"Cache-Control": {"max-age=0"}}, 0},
+		{"no-cache", Policy{TTL: time.Minute}, 200, http.Header{"Cache-Control": {"No-Cache"}}, 0},
+		{"private on a per-user route", Policy{TTL: time.Minute, PerUser: true}, 200, http.Header{"Cache-Control": {"private"}}, time.Minute},
+		{"vary *", Policy{TTL: time.Minute}, 200, http.Header{"Vary": {"*"}}, 0},
+		{"trailers", Policy{TTL: time.Minute}, 200, http.Header{"Trailer": {"X-Checksum"}}, 0},
+		{"not 200", Policy{TTL: time.Minute}, 203, http.Header{}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := lifetime(tc.policy, tc.status, tc.header); got != tc.want {
+				t.Errorf("lifetime = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	derived := etag(http.Header{}, body)
+	if derived != etag(http.Header{}, append([]byte(nil), body...)) || derived == etag(http.Header{}, []byte(`{"a":2}`)) {
+		t.Error("derived ETags should depend on the body only")
+	}
+	if got := etag(http.Header{"Etag": {`"v1"`}}, body); got != `"v1"` {
+		t.Errorf("ETag = %s, want the upstream's strong tag", got)
+	}
+	if got := etag(http.Header{"Etag": {`W/"v1"`}}, body); got != derived {
+		t.Errorf("ETag = %s, want a strong tag derived instead of the weak one", got)
+	}
+}
+
+func TestServeDropsPerRequestHeaders(t *testing.T) {
+	c := New(DefaultConfig())
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// Services echo the request ID they were called with
+		w.Header().Set(tracing.RequestIDHeader, r.Header.Get(tracing.RequestIDHeader))
+		w.Header().Set("RateLimit-Remaining", "9")
+		w.Header().Set("X-Upstream", "news")
+		w.Write([]byte(`{}`))
+	})
+
+	for i, requestID := range []string{"req-1", "req-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/news", nil)
+		req.Header.Set(tracing.RequestIDHeader, requestID)
+		rec := httptest.NewRecorder()
+		// The gateway's tracing middleware sets the ID before the cache runs
+		rec.Header().Set(tracing.RequestIDHeader, requestID)
+		c.Serve(rec, req, Policy{TTL: time.Minute}, next)
+
+		if got := rec.Header().Get(tracing.RequestIDHeader); got != requestID {
+			t.Errorf("request %d: X-Request-ID = %q, want %q", i, got, requestID)
+		}
+		if rec.Header().Get("RateLimit-Remaining") != "" || rec.Header().Get("X-Upstream") != "news" {
+			t.Errorf("request %d: headers = %v, want only the response's own headers replayed", i, rec.Header())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("upstream called %d times, want the second request served from the cache", calls)
+	}
+}
@@ -0,0 +1,253 @@
+// Package idempotency makes retried POSTs safe. A request carrying an
+// Idempotency-Key header is served once per key and client; retries with
+// the same key get the stored response back instead of creating a second
+// user or minting a second coupon.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/tracing"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	maxKeyLength    = 255
+	maxRequestBytes = 1 << 20
+	// maxResponseBytes caps the responses that are stored; larger ones are
+	// served but not replayed.
+	maxResponseBytes = 1 << 20
+	// pendingTimeout bounds how long a key stays reserved by a request that
+	// never completes, e.g. because the gateway died while serving it.
+	pendingTimeout = time.Minute
+)
+
+// Config holds the routes that honor the header and how long responses are
+// kept.
+type Config struct {
+	// Paths are the POST routes that honor Idempotency-Key, matched exactly.
+	Paths  []string
+	Window time.Duration
+	// Store is "memory" or "database".
+	Store string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Paths:  []string{"/api/users", "/api/nft/mint"},
+		Window: 24 * time.Hour,
+		Store:  "memory",
+	}
+}
+
+// ConfigFromEnv starts from DefaultConfig and applies IDEMPOTENCY_PATHS (a
+// comma-separated list), IDEMPOTENCY_WINDOW and IDEMPOTENCY_STORE.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("IDEMPOTENCY_PATHS"); value != "" {
+		cfg.Paths = nil
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if !strings.HasPrefix(path, "/") {
+				return cfg, fmt.Errorf("invalid IDEMPOTENCY_PATHS entry %q: expected /path", path)
+			}
+			cfg.Paths = append(cfg.Paths, path)
+		}
+	}
+	if value := os.Getenv("IDEMPOTENCY_WINDOW"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid IDEMPOTENCY_WINDOW: %q is not a positive duration", value)
+		}
+		cfg.Window = parsed
+	}
+	if value := os.Getenv("IDEMPOTENCY_STORE"); value != "" {
+		cfg.Store = strings.ToLower(value)
+	}
+
+	return cfg, nil
+}
+
+type Keeper struct {
+	cfg   Config
+	paths map[string]bool
+	store Store
+	now   func() time.Time
+}
+
+func New(cfg Config, store Store) *Keeper {
+	paths := make(map[string]bool, len(cfg.Paths))
+	for _, path := range cfg.Paths {
+		paths[strings.TrimSuffix(path, "/")] = true
+	}
+	return &Keeper{cfg: cfg, paths: paths, store: store, now: time.Now}
+}
+
+// Middleware serves POSTs to the configured paths at most once per
+// Idempotency-Key and client, and replays the stored response to retries.
+// A reused key with a different request gets 422, and one whose first
+// request is still being served gets 409. Server errors are not stored, so
+// they can be retried with the same key. It must run after the auth
+// middleware so that keys are scoped by user; anonymous clients are scoped
+// by address. When the store fails the request is served as if it had no
+// key.
+func (k *Keeper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(Header)
+		if idempotencyKey == "" || r.Method != http.MethodPost || !k.paths[strings.TrimSuffix(r.URL.Path, "/")] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxKeyLength {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", Header, maxKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("failed to read request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := hash(clientKey(r) + "|" + r.URL.Path + "|" + idempotencyKey)
+		fingerprint := hash(r.URL.RawQuery + "|" + string(body))
+
+		now := k.now()
+		existing, err := k.store.Reserve(r.Context(), key, fingerprint, now, now.Add(pendingTimeout))
+		if err != nil {
+			log.Printf("Warning: idempotency skipped: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if existing != nil {
+			k.replay(w, existing, fingerprint)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		// Store the response even if the client has gone away; its retry is
+		// what the record is for
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= 500 || rec.overflow {
+			if err := k.store.Release(ctx, key); err != nil {
+				log.Printf("Warning: failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		record := Record{Fingerprint: fingerprint, Status: rec.status, Header: rec.stored, Body: rec.body.Bytes()}
+		if err := k.store.Save(ctx, key, record, k.now().Add(k.cfg.Window)); err != nil {
+			log.Printf("Warning: failed to store idempotent response: %v", err)
+		}
+	})
+}
+
+func (k *Keeper) replay(w http.ResponseWriter, record *Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used with a different request", Header))
+	case record.Status == 0:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, fmt.Sprintf("a request with this %s is still being processed", Header))
+	default:
+		for name, values := range record.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(record.Status)
+		w.Write(record.Body)
+	}
+}
+
+// clientKey scopes keys by user, or by address for anonymous clients.
+func clientKey(r *http.Request) string {
+	if userID := auth.UserIDFrom(r.Context()); userID != "" {
+		return "user:" + userID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// perRequestHeaders are set by the gateway for each request and are not
+// replayed.
+var perRequestHeaders = []string{
+	"Date", "Retry-After", tracing.RequestIDHeader,
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+}
+
+// recorder passes the response through and keeps a copy to store.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	stored   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.stored = r.Header().Clone()
+		for _, name := range perRequestHeaders {
+			r.stored.Del(name)
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.overflow {
+		if r.body.Len()+len(p) > maxResponseBytes {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
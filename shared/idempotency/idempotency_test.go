@@ -0,0 +1,221 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/tracing"
+)
+
+// counter creates a resource per request, answering with its number; a body
+// of "fail" gets 500.
+type counter struct {
+	calls atomic.Int32
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := c.calls.Add(1)
+	body, _ := io.ReadAll(r.Body)
+	if string(body) == "fail" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(tracing.RequestIDHeader, r.Header.Get(tracing.RequestIDHeader))
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"id":%d}`, n)
+}
+
+type request struct {
+	path  string
+	key   string
+	body  string
+	user  string
+	query string
+}
+
+func send(handler http.Handler, req request) *httptest.ResponseRecorder {
+	target := req.path
+	if req.query != "" {
+		target += "?" + req.query
+	}
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(req.body))
+	r.RemoteAddr = "203.0.113.7:4000"
+	if req.key != "" {
+		r.Header.Set(Header, req.key)
+	}
+	if req.user != "" {
+		r.Header.Set(auth.UserIDHeader, req.user)
+	}
+	r.Header.Set(tracing.RequestIDHeader, "req-"+req.key+req.body)
+	rec := httptest.NewRecorder()
+	auth.Identity(handler).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store { return NewSQLStore(dbtest.SQLite(t)) },
+	}
+
+	type step struct {
+		at           time.Duration
+		req          request
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+	}
+	users := "/api/users"
+	steps := []step{
+		{0, request{path: users, key: "k1", body: `{"name":"a"}`}, 201, `{"id":1}`, false},
+		{time.Second, request{path: users, key: "k1", body: `{"name":"a"}`}, 201, `{"id":1}`, true},
+		{time.Second, request{path: users, key: "k1", body: `{"name":"b"}`}, 422, "", false},
+		{time.Second, request{path: users, key: "k1", body: `{"name":"a"}`, query: "x=1"}, 422, "", false},
+		// Keys are scoped by client and path
+		{time.Second, request{path: users, key: "k1", body: `{"name":"a"}`, user: "alice"}, 201, `{"id":2}`, false},
+		{time.Second, request{path: "/api/nft/mint", key: "k1", body: `{"name":"a"}`}, 201, `{"id":3}`, false},
+		// Requests without a key or to unlisted paths are not deduplicated
+		{time.Second, request{path: users, body: `{"name":"a"}`}, 201, `{"id":4}`, false},
+		{time.Second, request{path: "/api/nft/1/claim", key: "k1"}, 201, `{"id":5}`, false},
+		{time.Second, request{path: "/api/nft/1/claim", key: "k1"}, 201, `{"id":6}`, false},
+		// Server errors release the key for the retry
+		{time.Second, request{path: users, key: "k2", body: "fail"}, 500, "", false},
+		{time.Second, request{path: users, key: "k2", body: "fail"}, 500, "", false},
+		// After the window the key is free again
+		{25 * time.Hour, request{path: users, key: "k1", body: `{"name":"b"}`}, 201, `{"id":9}`, false},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			keeper := New(DefaultConfig(), open(t))
+			now := time.Unix(1700000000, 0)
+			keeper.now = func() time.Time { return now }
+			next := &counter{}
+			handler := keeper.Middleware(next)
+
+			for i, s := range steps {
+				now = now.Add(s.at)
+				rec := send(handler, s.req)
+				if rec.Code != s.wantStatus {
+					t.Fatalf("step %d: status = %d (%s), want %d", i, rec.Code, rec.Body, s.wantStatus)
+				}
+				if s.wantBody != "" && rec.Body.String() != s.wantBody {
+					t.Errorf("step %d: body = %s, want %s", i, rec.Body, s.wantBody)
+				}
+				replayed := rec.Header().Get(ReplayedHeader) == "true"
+				if replayed != s.wantReplayed {
+					t.Errorf("step %d: replayed = %v, want %v", i, replayed, s.wantReplayed)
+				}
+				if replayed {
+					if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get(tracing.RequestIDHeader) != "" {
+						t.Errorf("step %d: replayed headers = %v, want the response's own headers only", i, rec.Header())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareInFlight(t *testing.T) {
+	keeper := New(DefaultConfig(), NewMemoryStore())
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := keeper.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send(handler, request{path: "/api/users", key: "k"}) }()
+	<-started
+
+	rec := send(handler, request{path: "/api/users", key: "k"})
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("retry while in flight = %d %v, want 409 with Retry-After", rec.Code, rec.Header())
+	}
+
+	close(release)
+	if rec := <-first; rec.Code != http.StatusCreated {
+		t.Errorf("first request = %d, want 201", rec.Code)
+	}
+	if rec := send(handler, request{path: "/api/users", key: "k"}); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retry after completion = %d, want the replayed 201", rec.Code)
+	}
+}
+
+func TestMiddlewareRejectsLongKeys(t *testing.T) {
+	next := &counter{}
+	handler := New(DefaultConfig(), NewMemoryStore()).Middleware(next)
+
+	rec := send(handler, request{path: "/api/users", key: strings.Repeat("k", maxKeyLength+1)})
+	if rec.Code != http.StatusBadRequest || next.calls.Load() != 0 {
+		t.Errorf("status = %d after %d calls, want 400 without calling the service", rec.Code, next.calls.Load())
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Reserve(context.Context, string, string, time.Time, time.Time) (*Record, error) {
+	return nil, errors.New("database is down")
+}
+func (failingStore) Save(context.Context, string, Record, time.Time) error { return nil }
+func (failingStore) Release(context.Context, string) error                 { return nil }
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	next := &counter{}
+	handler := New(DefaultConfig(), failingStore{}).Middleware(next)
+
+	for i := 0; i < 2; i++ {
+		if rec := send(handler, request{path: "/api/users", key: "k", body: "{}"}); rec.Code != http.StatusCreated {
+			t.Fatalf("status with a failing store = %d, want 201", rec.Code)
+		}
+	}
+	if next.calls.Load() != 2 {
+		t.Errorf("service called %d times, want every request served", next.calls.Load())
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   string
+		window  string
+		store   string
+		want    Config
+		wantErr bool
+	}{
+		{"defaults", "", "", "", DefaultConfig(), false},
+		{"overrides", "/api/orders, /api/payments", "1h", "Database", Config{Paths: []string{"/api/orders", "/api/payments"}, Window: time.Hour, Store: "database"}, false},
+		{"relative path", "api/orders", "", "", Config{}, true},
+		{"bad window", "", "-1h", "", Config{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("IDEMPOTENCY_PATHS", tc.paths)
+			t.Setenv("IDEMPOTENCY_WINDOW", tc.window)
+			t.Setenv("IDEMPOTENCY_STORE", tc.store)
+
+			cfg, err := ConfigFromEnv()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if strings.Join(cfg.Paths, ",") != strings.Join(tc.want.Paths, ",") || cfg.Window != tc.want.Window || cfg.Store != tc.want.Store {
+				t.Errorf("config = %+v, want %+v", cfg, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"personalized-dashboard/shared/database"
+)
+
+// Record is what is kept for a key: the fingerprint of the request that
+// reserved it and, once served, its response. Status is 0 while the request
+// is being served.
+type Record struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// Store keeps the records. Reserve must be atomic per key, since a client
+// may retry — possibly against another gateway replica — while the first
+// request is still being served.
+type Store interface {
+	// Reserve claims key for the request with fingerprint until the given
+	// time and returns nil, unless an unexpired record holds the key; then
+	// it returns that record.
+	Reserve(ctx context.Context, key, fingerprint string, now, until time.Time) (*Record, error)
+	// Save stores the response of a reserved key until the given time.
+	Save(ctx context.Context, key string, record Record, until time.Time) error
+	// Release frees a reserved key whose response is not stored.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore keeps records in process memory. Keys only hold per replica;
+// use SQLStore when the gateway runs more than once.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	pruned  time.Time
+}
+
+type memoryRecord struct {
+	Record
+	until time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, now, until time.Time) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget expired records once a minute
+	if now.Sub(s.pruned) >= time.Minute {
+		for recordKey, record := range s.records {
+			if record.until.Before(now) {
+				delete(s.records, recordKey)
+			}
+		}
+		s.pruned = now
+	}
+
+	if existing, ok := s.records[key]; ok && !existing.until.Before(now) {
+		record := existing.Record
+		return &record, nil
+	}
+	s.records[key] = memoryRecord{Record: Record{Fingerprint: fingerprint}, until: until}
+	return nil, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, record Record, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = memoryRecord{Record: record, until: until}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records[key].Status == 0 {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// SQLStore keeps records in the idempotency_keys table so that all gateway
+// replicas share them. Times are Unix seconds, as in the rate limit store.
+type SQLStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: database.DialectOf(db)}
+}
+
+// reserveQuery inserts a pending record, or takes over an expired one; it
+// changes no row when an unexpired record holds the key.
+const reserveQuery = `
+	INSERT INTO idempotency_keys (key, fingerprint, status, header, body, expires_at)
+	VALUES ($1, $2, 0, '', NULL, $4)
+	ON CONFLICT (key) DO UPDATE SET
+		fingerprint = excluded.fingerprint,
+		status = 0,
+		header = '',
+		body = NULL,
+		expires_at = excluded.expires_at
+	WHERE idempotency_keys.expires_at < $3`
+
+func (s *SQLStore) Reserve(ctx context.Context, key, fingerprint string, now, until time.Time) (*Record, error) {
+	s.prune(ctx, now)
+
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(reserveQuery), key, fingerprint, unixSeconds(now), unixSeconds(until))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %v", err)
+	}
+	if reserved, err := result.RowsAffected(); err == nil && reserved > 0 {
+		return nil, nil
+	}
+
+	var record Record
+	var header string
+	query := s.dialect.Rebind(`SELECT fingerprint, status, header, body FROM idempotency_keys WHERE key = $1`)
+	if err := s.db.QueryRowContext(ctx, query, key).Scan(&record.Fingerprint, &record.Status, &header, &record.Body); err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %v", err)
+	}
+	if header != "" {
+		if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+			return nil, fmt.Errorf("failed to decode stored headers: %v", err)
+		}
+	}
+	return &record, nil
+}
+
+func (s *SQLStore) Save(ctx context.Context, key string, record Record, until time.Time) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode headers: %v", err)
+	}
+
+	query := s.dialect.Rebind(`
+		UPDATE idempotency_keys SET status = $2, header = $3, body = $4, expires_at = $5
+		WHERE key = $1 AND fingerprint = $6`)
+	if _, err := s.db.ExecContext(ctx, query, key, record.Status, string(header), record.Body, unixSeconds(until), record.Fingerprint); err != nil {
+		return fmt.Errorf("failed to save idempotent response: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Release(ctx context.Context, key string) error {
+	query := s.dialect.Rebind(`DELETE FROM idempotency_keys WHERE key = $1 AND status = 0`)
+	if _, err := s.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+	return nil
+}
+
+// prune deletes expired records, at most once a minute per replica.
+func (s *SQLStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.pruned) >= time.Minute
+	if due {
+		s.pruned = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	query := s.dialect.Rebind(`DELETE FROM idempotency_keys WHERE expires_at < $1`)
+	if _, err := s.db.ExecContext(ctx, query, unixSeconds(now)); err != nil {
+		log.Printf("Warning: failed to prune idempotency keys: %v", err)
+	}
+}
+
+// OpenStore returns the store named by cfg.Store. The database store uses
+// the shared database configuration.
+func OpenStore(cfg Config) (Store, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "database", "db":
+		db, err := database.SetupDatabase()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open idempotency store: %v", err)
+		}
+		return NewSQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported idempotency store: %s", cfg.Store)
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,197 @@
+// Package ingest stores the results the content services fetch from their
+// providers. Results are normalized into the shared models and upserted on
+// URL, so fetching the same item again refreshes its row and last_seen_at
+// instead of creating a duplicate.
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+)
+
+// Result reports what happened to one batch of provider results.
+type Result struct {
+	Received  int `json:"received"`
+	Created   int `json:"created"`
+	Refreshed int `json:"refreshed"`
+	Skipped   int `json:"skipped"`
+}
+
+type Ingester struct {
+	repos *repository.Repositories
+}
+
+func New(repos *repository.Repositories) *Ingester {
+	return &Ingester{repos: repos}
+}
+
+// Open connects to the configured database, applies migrations and returns
+// an Ingester on top of it.
+func Open() (*Ingester, *sql.DB, error) {
+	db, err := database.SetupDatabase()
+	if err != nil {
+		return nil, nil, err
+	}
+	return New(repository.New(db)), db, nil
+}
+
+// Timeout bounds storing one batch of results in the background.
+const Timeout = 30 * time.Second
+
+// Detach returns a context for storing results after the request that
+// fetched them has been answered: it keeps the request's values, such as its
+// trace, but not its cancellation, and expires after Timeout.
+func Detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), Timeout)
+}
+
+// News stores news results in one transaction. category, when set, is the
+// category the results were requested for and overrides the one on each item.
+func (i *Ingester) News(ctx context.Context, category string, items []map[string]interface{}) (Result, error) {
+	result := Result{Received: len(items)}
+	seen := make(map[string]bool, len(items))
+
+	err := i.repos.InTx(ctx, func(repos *repository.Repositories) error {
+		for _, item := range items {
+			article, ok := NewsArticle(item, category)
+			if !ok || seen[article.URL] {
+				result.Skipped++
+				continue
+			}
+			seen[article.URL] = true
+
+			created, err := repos.News.Upsert(ctx, &article)
+			if err != nil {
+				return err
+			}
+			result.count(created)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (i *Ingester) Jobs(ctx context.Context, category string, items []map[string]interface{}) (Result, error) {
+	result := Result{Received: len(items)}
+	seen := make(map[string]bool, len(items))
+
+	err := i.repos.InTx(ctx, func(repos *repository.Repositories) error {
+		for _, item := range items {
+			job, ok := JobListing(item, category)
+			if !ok || seen[job.URL] {
+				result.Skipped++
+				continue
+			}
+			seen[job.URL] = true
+
+			created, err := repos.Jobs.Upsert(ctx, &job)
+			if err != nil {
+				return err
+			}
+			result.count(created)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (i *Ingester) Videos(ctx context.Context, category string, items []map[string]interface{}) (Result, error) {
+	result := Result{Received: len(items)}
+	seen := make(map[string]bool, len(items))
+
+	err := i.repos.InTx(ctx, func(repos *repository.Repositories) error {
+		for _, item := range items {
+			video, ok := Video(item, category)
+			if !ok || seen[video.URL] {
+				result.Skipped++
+				continue
+			}
+			seen[video.URL] = true
+
+			created, err := repos.Videos.Upsert(ctx, &video)
+			if err != nil {
+				return err
+			}
+			result.count(created)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (i *Ingester) Deals(ctx context.Context, category string, items []map[string]interface{}) (Result, error) {
+	result := Result{Received: len(items)}
+	seen := make(map[string]bool, len(items))
+
+	err := i.repos.InTx(ctx, func(repos *repository.Repositories) error {
+		for _, item := range items {
+			deal, ok := Deal(item, category)
+			if !ok || seen[deal.URL] {
+				result.Skipped++
+				continue
+			}
+			seen[deal.URL] = true
+
+			created, err := repos.Deals.Upsert(ctx, &deal)
+			if err != nil {
+				return err
+			}
+			result.count(created)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// StoredNews returns the stored corpus for a category, newest first.
+func (i *Ingester) StoredNews(ctx context.Context, category string, limit int) ([]models.NewsArticle, error) {
+	return i.repos.News.List(ctx, repository.ListOptions{Category: category, Limit: limit})
+}
+
+func (i *Ingester) StoredJobs(ctx context.Context, category string, limit int) ([]models.JobListing, error) {
+	return i.repos.Jobs.List(ctx, repository.ListOptions{Category: category, Limit: limit})
+}
+
+func (i *Ingester) StoredVideos(ctx context.Context, category string, limit int) ([]models.Video, error) {
+	return i.repos.Videos.List(ctx, repository.ListOptions{Category: category, Limit: limit})
+}
+
+func (i *Ingester) StoredDeals(ctx context.Context, category string, limit int) ([]models.Deal, error) {
+	return i.repos.Deals.List(ctx, repository.ListOptions{Category: category, Limit: limit})
+}
+
+// SearchNews runs a full-text search over the stored news. It needs no
+// provider key, so the services use it when the upstream search fails.
+func (i *Ingester) SearchNews(ctx context.Context, query, category string, limit int) ([]repository.NewsArticleHit, error) {
+	return i.repos.News.Search(ctx, repository.SearchOptions{Query: query, Category: category, Limit: limit})
+}
+
+func (i *Ingester) SearchJobs(ctx context.Context, query, category string, limit int) ([]repository.JobListingHit, error) {
+	return i.repos.Jobs.Search(ctx, repository.SearchOptions{Query: query, Category: category, Limit: limit})
+}
+
+func (i *Ingester) SearchVideos(ctx context.Context, query, category string, limit int) ([]repository.VideoHit, error) {
+	return i.repos.Videos.Search(ctx, repository.SearchOptions{Query: query, Category: category, Limit: limit})
+}
+
+func (i *Ingester) SearchDeals(ctx context.Context, query, category string, limit int) ([]repository.DealHit, error) {
+	return i.repos.Deals.Search(ctx, repository.SearchOptions{Query: query, Category: category, Limit: limit})
+}
+
+func (r *Result) count(created bool) {
+	if created {
+		r.Created++
+	} else {
+		r.Refreshed++
+	}
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d received, %d new, %d refreshed, %d skipped", r.Received, r.Created, r.Refreshed, r.Skipped)
+}
@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/repository"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://Example.com/a?utm_source=x&id=1#top", "https://example.com/a?id=1"},
+		{"HTTP://example.com/a?fbclid=1&ref=feed", "http://example.com/a"},
+		{"  https://example.com/b  ", "https://example.com/b"},
+		{"ftp://example.com/a", ""},
+		{"/relative/path", ""},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		if got := NormalizeURL(tc.raw); got != tc.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestIngesterNews(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.New(dbtest.SQLite(t))
+	ingester := New(repos)
+
+	items := []map[string]interface{}{
+		{"title": "One", "url": "https://example.com/1?utm_medium=rss", "category": "search"},
+		{"title": "One again", "url": "https://example.com/1"},
+		{"title": "Two", "url": "https://example.com/2", "published_at": "2024-05-01T10:00:00Z"},
+		{"title": "", "url": "https://example.com/3"},
+		{"title": "No URL"},
+	}
+	result, err := ingester.News(ctx, "", items)
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	if want := (Result{Received: 5, Created: 2, Skipped: 3}); result != want {
+		t.Errorf("first batch = %+v, want %+v", result, want)
+	}
+
+	result, err = ingester.News(ctx, "technology", items[:1])
+	if err != nil {
+		t.Fatalf("News again: %v", err)
+	}
+	if want := (Result{Received: 1, Refreshed: 1}); result != want {
+		t.Errorf("second batch = %+v, want %+v", result, want)
+	}
+
+	stored, err := ingester.StoredNews(ctx, "technology", 10)
+	if err != nil {
+		t.Fatalf("StoredNews: %v", err)
+	}
+	if len(stored) != 1 || stored[0].URL != "https://example.com/1" {
+		t.Errorf("StoredNews(technology) = %+v, want the refreshed article", stored)
+	}
+}
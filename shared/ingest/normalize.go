@@ -0,0 +1,172 @@
+package ingest
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/models"
+)
+
+// genericCategories are labels the services put on results whose real
+// category is unknown (searches, YouTube details). They are dropped so an
+// upsert never overwrites the category a row was first stored under.
+var genericCategories = map[string]bool{
+	"search": true,
+	"video":  true,
+}
+
+// trackingParams are query parameters that identify the referrer rather than
+// the content, so two URLs differing only in them are the same item.
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"ref":    true,
+}
+
+// NormalizeURL returns the canonical form of a provider URL used as the
+// dedupe key: lowercase scheme and host, no fragment and no tracking
+// parameters. It returns "" for anything that is not an absolute http(s) URL.
+func NormalizeURL(raw string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+
+	query := parsed.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// NewsArticle converts a news result into the shared model. ok is false when
+// the result has no usable title or URL.
+func NewsArticle(item map[string]interface{}, category string) (article models.NewsArticle, ok bool) {
+	article = models.NewsArticle{
+		Title:       stringField(item, "title"),
+		Description: stringField(item, "description"),
+		URL:         NormalizeURL(stringField(item, "url")),
+		Source:      stringField(item, "source"),
+		Category:    categoryField(item, category),
+		PublishedAt: timeField(item, "published_at"),
+		ImageURL:    stringField(item, "image_url"),
+	}
+	return article, article.Title != "" && article.URL != ""
+}
+
+// JobListing converts a job result into the shared model.
+func JobListing(item map[string]interface{}, category string) (job models.JobListing, ok bool) {
+	job = models.JobListing{
+		Title:       stringField(item, "title"),
+		Company:     stringField(item, "company"),
+		Location:    stringField(item, "location"),
+		Description: stringField(item, "description"),
+		URL:         NormalizeURL(stringField(item, "url")),
+		Category:    categoryField(item, category),
+		PostedAt:    timeField(item, "posted_at"),
+		Salary:      stringField(item, "salary"),
+	}
+	return job, job.Title != "" && job.URL != ""
+}
+
+// Video converts a video result into the shared model.
+func Video(item map[string]interface{}, category string) (video models.Video, ok bool) {
+	video = models.Video{
+		Title:       stringField(item, "title"),
+		Description: stringField(item, "description"),
+		URL:         NormalizeURL(stringField(item, "url")),
+		Channel:     stringField(item, "channel"),
+		Category:    categoryField(item, category),
+		PublishedAt: timeField(item, "published_at"),
+		Thumbnail:   stringField(item, "thumbnail"),
+		Duration:    stringField(item, "duration"),
+		Views:       int64(numberField(item, "views")),
+	}
+	return video, video.Title != "" && video.URL != ""
+}
+
+// Deal converts a deal result into the shared model.
+func Deal(item map[string]interface{}, category string) (deal models.Deal, ok bool) {
+	deal = models.Deal{
+		Title:         stringField(item, "title"),
+		Description:   stringField(item, "description"),
+		URL:           NormalizeURL(stringField(item, "url")),
+		Platform:      stringField(item, "platform"),
+		Category:      categoryField(item, category),
+		Price:         numberField(item, "price"),
+		OriginalPrice: numberField(item, "original_price"),
+		Discount:      numberField(item, "discount"),
+		ImageURL:      stringField(item, "image_url"),
+		ValidUntil:    timeField(item, "valid_until"),
+	}
+	return deal, deal.Title != "" && deal.URL != ""
+}
+
+// categoryField prefers the category the results were requested for over
+// the one on the item itself.
+func categoryField(item map[string]interface{}, category string) string {
+	if category == "" {
+		category = stringField(item, "category")
+	}
+	category = strings.ToLower(category)
+	if genericCategories[category] {
+		return ""
+	}
+	return category
+}
+
+func stringField(item map[string]interface{}, key string) string {
+	if value, ok := item[key].(string); ok {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+func numberField(item map[string]interface{}, key string) float64 {
+	switch value := item[key].(type) {
+	case float64:
+		return value
+	case float32:
+		return float64(value)
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case string:
+		number, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return number
+	default:
+		return 0
+	}
+}
+
+// timeField accepts a time.Time or an RFC 3339 string, which is how the
+// providers report timestamps.
+func timeField(item map[string]interface{}, key string) time.Time {
+	switch value := item[key].(type) {
+	case time.Time:
+		return value
+	case *time.Time:
+		if value != nil {
+			return *value
+		}
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
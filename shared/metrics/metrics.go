@@ -0,0 +1,156 @@
+// Package metrics exposes Prometheus metrics for the gateway and the
+// services: request latency per route and status, cache hits and misses,
+// and the calls made to each outbound provider.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is where Middleware serves the metrics.
+const Path = "/metrics"
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the requests served, by route, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	requestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Requests currently being served.",
+	})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_lookups_total",
+		Help: "Cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	providerRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "provider_requests_total",
+		Help: "Outbound calls, by provider and status code; failed calls have code \"error\".",
+	}, []string{"provider", "code"})
+
+	providerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "provider_request_duration_seconds",
+		Help:    "Latency of outbound calls until the response headers arrive, by provider.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider"})
+)
+
+type routeKey struct{}
+
+// SetRoute names the route serving the request of ctx, e.g. /api/users/:id,
+// so that its metrics are not split by path parameters.
+func SetRoute(ctx context.Context, route string) {
+	if label, ok := ctx.Value(routeKey{}).(*string); ok {
+		*label = route
+	}
+}
+
+// DetachRoute returns a copy of ctx whose SetRoute calls leave the route of
+// ctx alone, for requests served inside another one.
+func DetachRoute(ctx context.Context) context.Context {
+	var route string
+	return context.WithValue(ctx, routeKey{}, &route)
+}
+
+// Middleware serves the metrics at /metrics and records the latency of every
+// other request. The route label is the template set with SetRoute, Handle or
+// Mux, or "unmatched", so that paths never create a series each.
+func Middleware(next http.Handler) http.Handler {
+	metricsHandler := promhttp.Handler()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == Path {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		var route string
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+		if route == "" {
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handle wraps the handler registered for route so that its requests are
+// recorded under route, e.g.
+//
+//	app.GET("/api/users/:id", metrics.Handle("/api/users/:id", userService.GetUser))
+func Handle[C context.Context, R any](route string, handler func(C) (R, error)) func(C) (R, error) {
+	return func(ctx C) (R, error) {
+		SetRoute(ctx, route)
+		return handler(ctx)
+	}
+}
+
+// Mux records the requests served by mux under the pattern they matched.
+func Mux(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			SetRoute(r.Context(), pattern)
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Cache is the part of a go-cache *cache.Cache that CacheGet uses.
+type Cache interface {
+	Get(key string) (interface{}, bool)
+}
+
+// CacheGet looks key up in c and counts the hit or miss under name.
+func CacheGet(c Cache, name, key string) (interface{}, bool) {
+	value, found := c.Get(key)
+	CacheLookup(name, found)
+	return value, found
+}
+
+// CacheLookup counts a hit or miss of the named cache.
+func CacheLookup(name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(name, result).Inc()
+}
+
+// statusWriter remembers the status of a response it passes through.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader && status >= http.StatusOK {
+		sw.status, sw.wroteHeader = status, true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/videos/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	claim := Handle("/api/nft/:id/claim", func(ctx context.Context) (interface{}, error) { return nil, nil })
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/nft/42/claim":
+			claim(r.Context())
+		case "/api/news/denied":
+			// Answered before any route was chosen, like a rejected token
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			Mux(mux).ServeHTTP(w, r)
+		}
+	}))
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantRoute string
+		wantCode  string
+	}{
+		{"prefix pattern", "GET", "/api/users/8c0d7a5e-1f7b-4a39-9a7e-2f3b6c1d0e9f", "/api/users/", "200"},
+		{"wildcard pattern", "GET", "/api/videos/dQw4w9WgXcQ", "GET /api/videos/{id}", "200"},
+		{"handler template", "POST", "/api/nft/42/claim", "/api/nft/:id/claim", "200"},
+		{"rejected before routing", "GET", "/api/news/denied", "unmatched", "401"},
+		{"unknown path", "GET", "/wp-login.php", "unmatched", "404"},
+		{"method not allowed", "DELETE", "/api/videos/abc", "unmatched", "405"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requestDuration.Reset()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+
+			if !requestDuration.DeleteLabelValues(tc.wantRoute, tc.method, tc.wantCode) {
+				t.Errorf("no series for route %q, method %s, status %s", tc.wantRoute, tc.method, tc.wantCode)
+			}
+			if n := testutil.CollectAndCount(requestDuration); n != 0 {
+				t.Errorf("%d other series recorded", n)
+			}
+		})
+	}
+}
+
+func TestDetachRoute(t *testing.T) {
+	requestDuration.Reset()
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r.Context(), "/api/batch")
+		// A sub-request naming its own route leaves the outer one alone
+		SetRoute(DetachRoute(r.Context()), "/api/news")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/batch", nil))
+
+	if !requestDuration.DeleteLabelValues("/api/batch", "POST", "200") {
+		t.Error("the outer request should keep its route")
+	}
+}
+
+func TestMiddlewareServesMetrics(t *testing.T) {
+	CacheLookup("test", true)
+	handler := Middleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", Path, nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `cache_lookups_total{cache="test",result="hit"} 1`) {
+		t.Errorf("GET %s = %d, want the exposition with the cache lookup", Path, rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// providers names the third-party APIs by host.
+var providers = map[string]string{
+	"newsapi.org":          "newsapi",
+	"www.googleapis.com":   "youtube",
+	"api.adzuna.com":       "adzuna",
+	"www.omdbapi.com":      "omdb",
+	"api.themoviedb.org":   "tmdb",
+	"api.spoonacular.com":  "spoonacular",
+	"api.edamam.com":       "edamam",
+	"api.verbwire.com":     "verbwire",
+	"api.wolframalpha.com": "wolfram",
+	"api.linkedin.com":     "linkedin",
+}
+
+// Provider names the provider a request goes to: a known third-party API, or
+// else the host without its port, e.g. news-service.
+func Provider(r *http.Request) string {
+	host := strings.ToLower(r.URL.Hostname())
+	if name, ok := providers[host]; ok {
+		return name
+	}
+	return host
+}
+
+// Call is the outcome of the latest call to a provider. Status is 0 when no
+// response came, and Error says why.
+type Call struct {
+	Status int
+	Error  string
+	At     time.Time
+}
+
+var (
+	lastCallsMu sync.Mutex
+	lastCalls   = map[string]Call{}
+)
+
+// LastCall returns the latest call made to provider by this process.
+func LastCall(provider string) (Call, bool) {
+	lastCallsMu.Lock()
+	defer lastCallsMu.Unlock()
+
+	call, ok := lastCalls[provider]
+	return call, ok
+}
+
+// NewTransport counts the requests sent through base and times them, by
+// provider.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return providerTransport{base}
+}
+
+type providerTransport struct {
+	base http.RoundTripper
+}
+
+func (t providerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := Provider(req)
+	start := time.Now()
+
+	resp, err := t.base.RoundTrip(req)
+
+	providerDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	code := "error"
+	call := Call{At: start}
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		call.Status = resp.StatusCode
+	} else {
+		call.Error = err.Error()
+	}
+	providerRequests.WithLabelValues(provider, code).Inc()
+
+	lastCallsMu.Lock()
+	lastCalls[provider] = call
+	lastCallsMu.Unlock()
+	return resp, err
+}
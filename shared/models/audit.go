@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry is one row of the append-only audit log. Actor is who made the
+// change, UserID the user it concerns; they differ when support staff or a
+// service acts on a user's behalf.
+type AuditEntry struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+	Actor      string    `json:"actor" db:"actor"`
+	Action     string    `json:"action" db:"action"` // user.create, user.update, preferences.update, coupon.mint, coupon.claim
+	TargetType string    `json:"target_type" db:"target_type"`
+	TargetID   string    `json:"target_id" db:"target_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	RequestID  string    `json:"request_id" db:"request_id"`
+	Changes    AuditDiff `json:"changes" db:"changes"`
+}
+
+// AuditChange is the value of one field before and after a mutation. Before
+// is nil for created records.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditDiff maps changed field names to their change, stored as JSON.
+type AuditDiff map[string]AuditChange
+
+func (d AuditDiff) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(map[string]AuditChange(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode AuditDiff: %v", err)
+	}
+	return string(data), nil
+}
+
+func (d *AuditDiff) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into AuditDiff", src)
+	}
+
+	changes := make(map[string]AuditChange)
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return fmt.Errorf("failed to decode AuditDiff: %v", err)
+	}
+
+	*d = changes
+	return nil
+}
@@ -14,6 +14,8 @@ type NewsArticle struct {
 	Category    string    `json:"category" db:"category"`
 	PublishedAt time.Time `json:"published_at" db:"published_at"`
 	ImageURL    string    `json:"image_url" db:"image_url"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
 }
 
 type JobListing struct {
@@ -26,6 +28,8 @@ type JobListing struct {
 	Category    string    `json:"category" db:"category"`
 	PostedAt    time.Time `json:"posted_at" db:"posted_at"`
 	Salary      string    `json:"salary" db:"salary"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
 }
 
 type Video struct {
@@ -39,6 +43,8 @@ type Video struct {
 	Thumbnail   string    `json:"thumbnail" db:"thumbnail"`
 	Duration    string    `json:"duration" db:"duration"`
 	Views       int64     `json:"views" db:"views"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
 }
 
 type Deal struct {
@@ -53,6 +59,8 @@ type Deal struct {
 	Discount    float64   `json:"discount" db:"discount"`
 	ImageURL    string    `json:"image_url" db:"image_url"`
 	ValidUntil  time.Time `json:"valid_until" db:"valid_until"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
 }
 
 type Recommendation struct {
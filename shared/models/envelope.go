@@ -0,0 +1,221 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Content types of the ContentItem envelope.
+const (
+	ContentTypeNews   = "news"
+	ContentTypeJob    = "job"
+	ContentTypeVideo  = "video"
+	ContentTypeDeal   = "deal"
+	ContentTypeMovie  = "movie"
+	ContentTypeRecipe = "recipe"
+)
+
+// ContentItem is the envelope every content service returns next to its
+// vertical-specific payload, so clients and the recommender can handle all
+// verticals the same way. Fields that only make sense for one vertical, like
+// a deal's price or a video's duration, go in Extras.
+type ContentItem struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Summary     string                 `json:"summary"`
+	URL         string                 `json:"url"`
+	Image       string                 `json:"image"`
+	Category    string                 `json:"category"`
+	Source      string                 `json:"source"`
+	PublishedAt *time.Time             `json:"published_at,omitempty"`
+	IsStatic    bool                   `json:"is_static"`
+	Extras      map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (a NewsArticle) ContentItem() ContentItem {
+	return ContentItem{
+		ID:          a.ID.String(),
+		Type:        ContentTypeNews,
+		Title:       a.Title,
+		Summary:     a.Description,
+		URL:         a.URL,
+		Image:       a.ImageURL,
+		Category:    a.Category,
+		Source:      a.Source,
+		PublishedAt: timePtr(a.PublishedAt),
+	}
+}
+
+func (j JobListing) ContentItem() ContentItem {
+	return ContentItem{
+		ID:          j.ID.String(),
+		Type:        ContentTypeJob,
+		Title:       j.Title,
+		Summary:     j.Description,
+		URL:         j.URL,
+		Category:    j.Category,
+		Source:      j.Company,
+		PublishedAt: timePtr(j.PostedAt),
+		Extras: map[string]interface{}{
+			"location": j.Location,
+			"salary":   j.Salary,
+		},
+	}
+}
+
+func (v Video) ContentItem() ContentItem {
+	return ContentItem{
+		ID:          v.ID.String(),
+		Type:        ContentTypeVideo,
+		Title:       v.Title,
+		Summary:     v.Description,
+		URL:         v.URL,
+		Image:       v.Thumbnail,
+		Category:    v.Category,
+		Source:      v.Channel,
+		PublishedAt: timePtr(v.PublishedAt),
+		Extras: map[string]interface{}{
+			"duration": v.Duration,
+			"views":    v.Views,
+		},
+	}
+}
+
+func (d Deal) ContentItem() ContentItem {
+	return ContentItem{
+		ID:       d.ID.String(),
+		Type:     ContentTypeDeal,
+		Title:    d.Title,
+		Summary:  d.Description,
+		URL:      d.URL,
+		Image:    d.ImageURL,
+		Category: d.Category,
+		Source:   d.Platform,
+		Extras: map[string]interface{}{
+			"price":          d.Price,
+			"original_price": d.OriginalPrice,
+			"discount":       d.Discount,
+			"valid_until":    d.ValidUntil,
+		},
+	}
+}
+
+// Enveloper is implemented by the content models and by the search hits that
+// embed them.
+type Enveloper interface {
+	ContentItem() ContentItem
+}
+
+// ContentItems wraps a slice of models or search hits.
+func ContentItems[T Enveloper](items []T) []ContentItem {
+	envelopes := make([]ContentItem, 0, len(items))
+	for _, item := range items {
+		envelopes = append(envelopes, item.ContentItem())
+	}
+	return envelopes
+}
+
+// envelopeKeys lists, per envelope field, the keys the services use for it in
+// their untyped payloads: NewsAPI-style "description", TMDB-style "overview"
+// and "poster_path", Spoonacular-style "summary" and "image", and so on.
+var envelopeKeys = struct {
+	summary, image, source, published []string
+}{
+	summary:   []string{"description", "overview", "summary"},
+	image:     []string{"image_url", "thumbnail", "poster_path", "image"},
+	source:    []string{"source", "company", "channel", "platform"},
+	published: []string{"published_at", "posted_at", "release_date"},
+}
+
+// ContentItemFromMap builds the envelope for one item of a service payload.
+// Keys that are not part of the envelope are kept in Extras.
+func ContentItemFromMap(contentType string, item map[string]interface{}) ContentItem {
+	used := map[string]bool{"id": true, "title": true, "url": true, "category": true, "is_static": true}
+	pick := func(keys []string) interface{} {
+		for _, key := range keys {
+			if value, ok := item[key]; ok && value != nil && value != "" {
+				used[key] = true
+				return value
+			}
+		}
+		return nil
+	}
+
+	envelope := ContentItem{
+		Type:        contentType,
+		Title:       stringValue(item["title"]),
+		Summary:     stringValue(pick(envelopeKeys.summary)),
+		URL:         stringValue(item["url"]),
+		Image:       stringValue(pick(envelopeKeys.image)),
+		Category:    stringValue(item["category"]),
+		Source:      stringValue(pick(envelopeKeys.source)),
+		PublishedAt: timeValue(pick(envelopeKeys.published)),
+	}
+	if id, ok := item["id"]; ok && id != nil {
+		envelope.ID = fmt.Sprint(id)
+	}
+	if isStatic, ok := item["is_static"].(bool); ok {
+		envelope.IsStatic = isStatic
+	}
+
+	for key, value := range item {
+		if used[key] {
+			continue
+		}
+		if envelope.Extras == nil {
+			envelope.Extras = make(map[string]interface{})
+		}
+		envelope.Extras[key] = value
+	}
+
+	return envelope
+}
+
+// ContentItemsFromMaps wraps every item of a service payload.
+func ContentItemsFromMaps(contentType string, items []map[string]interface{}) []ContentItem {
+	envelopes := make([]ContentItem, 0, len(items))
+	for _, item := range items {
+		envelopes = append(envelopes, ContentItemFromMap(contentType, item))
+	}
+	return envelopes
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// timeValue accepts the time formats found in the payloads: time.Time values,
+// RFC 3339 strings, dates and bare years.
+func timeValue(value interface{}) *time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return timePtr(v)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return timePtr(*v)
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02", "2006"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
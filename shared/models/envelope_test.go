@@ -0,0 +1,97 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestContentItemFromMap(t *testing.T) {
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		contentType string
+		item        map[string]interface{}
+		want        ContentItem
+	}{
+		{
+			name:        "news",
+			contentType: ContentTypeNews,
+			item: map[string]interface{}{
+				"id": "n1", "title": "Go 1.23", "description": "Released", "url": "https://example.com/n1",
+				"source": "Go Blog", "category": "tech", "published_at": published, "image_url": "https://example.com/n1.png",
+			},
+			want: ContentItem{
+				ID: "n1", Type: ContentTypeNews, Title: "Go 1.23", Summary: "Released", URL: "https://example.com/n1",
+				Image: "https://example.com/n1.png", Category: "tech", Source: "Go Blog", PublishedAt: &published,
+			},
+		},
+		{
+			name:        "movie with TMDB keys and a numeric id",
+			contentType: ContentTypeMovie,
+			item: map[string]interface{}{
+				"id": 42, "title": "Heat", "overview": "Crime", "poster_path": "/heat.jpg", "release_date": "1995-12-15",
+				"vote_average": 8.3,
+			},
+			want: ContentItem{
+				ID: "42", Type: ContentTypeMovie, Title: "Heat", Summary: "Crime", Image: "/heat.jpg",
+				PublishedAt: timePtr(time.Date(1995, 12, 15, 0, 0, 0, 0, time.UTC)),
+				Extras:      map[string]interface{}{"vote_average": 8.3},
+			},
+		},
+		{
+			name:        "static deal falls back to later keys",
+			contentType: ContentTypeDeal,
+			item: map[string]interface{}{
+				"title": "Half off", "description": "", "summary": "50%", "platform": "Shop", "is_static": true, "price": 10.0,
+			},
+			want: ContentItem{
+				Type: ContentTypeDeal, Title: "Half off", Summary: "50%", Source: "Shop", IsStatic: true,
+				Extras: map[string]interface{}{"description": "", "price": 10.0},
+			},
+		},
+		{
+			name:        "unparseable date",
+			contentType: ContentTypeJob,
+			item:        map[string]interface{}{"title": "Gopher", "posted_at": "yesterday"},
+			want:        ContentItem{Type: ContentTypeJob, Title: "Gopher"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ContentItemFromMap(tc.contentType, tc.item)
+			if !sameItem(got, tc.want) {
+				t.Errorf("ContentItemFromMap = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestContentItems(t *testing.T) {
+	id := uuid.New()
+	articles := []NewsArticle{{ID: id, Title: "Stored", Description: "From the store", Category: "tech"}}
+
+	items := ContentItems(articles)
+	if len(items) != 1 {
+		t.Fatalf("ContentItems returned %d items, want 1", len(items))
+	}
+	want := ContentItem{ID: id.String(), Type: ContentTypeNews, Title: "Stored", Summary: "From the store", Category: "tech"}
+	if !sameItem(items[0], want) {
+		t.Errorf("ContentItems = %+v, want %+v", items[0], want)
+	}
+
+	if items := ContentItemsFromMaps(ContentTypeVideo, nil); items == nil || len(items) != 0 {
+		t.Errorf("ContentItemsFromMaps(nil) = %#v, want an empty slice", items)
+	}
+}
+
+func sameItem(a, b ContentItem) bool {
+	if (a.PublishedAt == nil) != (b.PublishedAt == nil) || a.PublishedAt != nil && !a.PublishedAt.Equal(*b.PublishedAt) {
+		return false
+	}
+	a.PublishedAt, b.PublishedAt = nil, nil
+	return reflect.DeepEqual(a, b)
+}
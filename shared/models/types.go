@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StringList maps a Go string slice to a Postgres TEXT[] column using the
+// array literal format ({"a","b"}).
+type StringList []string
+
+func (s StringList) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	quoted := make([]string, len(s))
+	for i, item := range s {
+		item = strings.ReplaceAll(item, `\`, `\\`)
+		item = strings.ReplaceAll(item, `"`, `\"`)
+		quoted[i] = `"` + item + `"`
+	}
+
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+func (s *StringList) Scan(src interface{}) error {
+	var literal string
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		literal = string(v)
+	case string:
+		literal = v
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", src)
+	}
+
+	items, err := parseArrayLiteral(literal)
+	if err != nil {
+		return err
+	}
+
+	*s = items
+	return nil
+}
+
+func parseArrayLiteral(literal string) ([]string, error) {
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal: %q", literal)
+	}
+
+	body := literal[1 : len(literal)-1]
+	items := make([]string, 0)
+	if body == "" {
+		return items, nil
+	}
+
+	var current strings.Builder
+	inQuotes, quoted := false, false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && inQuotes && i+1 < len(body):
+			i++
+			current.WriteByte(body[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case c == ',' && !inQuotes:
+			items = append(items, arrayElement(current.String(), quoted))
+			current.Reset()
+			quoted = false
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in array literal: %q", literal)
+	}
+	items = append(items, arrayElement(current.String(), quoted))
+
+	return items, nil
+}
+
+func arrayElement(raw string, quoted bool) string {
+	// An unquoted NULL is a null element; there is no way to represent it in
+	// a []string so it becomes an empty string.
+	if !quoted && strings.EqualFold(raw, "NULL") {
+		return ""
+	}
+	return raw
+}
+
+// ScoreMap maps per-category scores to a JSONB column.
+type ScoreMap map[string]float64
+
+func (m ScoreMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(map[string]float64(m))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ScoreMap: %v", err)
+	}
+	return string(data), nil
+}
+
+func (m *ScoreMap) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ScoreMap", src)
+	}
+
+	scores := make(map[string]float64)
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return fmt.Errorf("failed to decode ScoreMap: %v", err)
+	}
+
+	*m = scores
+	return nil
+}
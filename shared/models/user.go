@@ -9,7 +9,7 @@ type User struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	Email        string    `json:"email" db:"email"`
 	Name         string    `json:"name" db:"name"`
-	Interests    []string  `json:"interests" db:"interests"`
+	Interests    StringList `json:"interests" db:"interests"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -25,7 +25,7 @@ type UserBehavior struct {
 
 type UserProfile struct {
 	UserID           uuid.UUID `json:"user_id" db:"user_id"`
-	ExplicitInterests []string  `json:"explicit_interests" db:"explicit_interests"`
-	BehavioralScore  map[string]float64 `json:"behavioral_score" db:"behavioral_score"`
+	ExplicitInterests StringList `json:"explicit_interests" db:"explicit_interests"`
+	BehavioralScore  ScoreMap  `json:"behavioral_score" db:"behavioral_score"`
 	LastUpdated      time.Time `json:"last_updated" db:"last_updated"`
 }
@@ -0,0 +1,188 @@
+// Package ratelimit throttles gateway clients with token buckets so that one
+// noisy client cannot use up the quotas of the paid upstream APIs. Buckets
+// are keyed by client and route and kept in a Store, which can be shared by
+// all gateway replicas.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket budget: Requests per Period on average, with bursts
+// of up to Burst requests. A zero Limit means unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// String formats the limit the way ParseLimit reads it.
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	s := strconv.Itoa(l.Requests) + "/" + formatPeriod(l.Period)
+	if l.Burst > 0 && l.Burst != l.Requests {
+		s += ":" + strconv.Itoa(l.Burst)
+	}
+	return s
+}
+
+// ParseLimit reads "<requests>/<period>[:<burst>]", e.g. "60/m", "5/10s" or
+// "100/h:20". The period is s, m, h or a Go duration; the burst defaults to
+// the request count. "off" disables limiting.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return Limit{}, nil
+	}
+
+	spec, burstValue, hasBurst := strings.Cut(value, ":")
+	requestsValue, periodValue, found := strings.Cut(spec, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", value)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsValue))
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad request count", value)
+	}
+	period, err := parsePeriod(strings.TrimSpace(periodValue))
+	if err != nil {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: %v", value, err)
+	}
+
+	limit := Limit{Requests: requests, Period: period}
+	if hasBurst {
+		burst, err := strconv.Atoi(strings.TrimSpace(burstValue))
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: bad burst", value)
+		}
+		limit.Burst = burst
+	}
+	return limit, nil
+}
+
+func parsePeriod(value string) (time.Duration, error) {
+	switch value {
+	case "s", "sec", "second":
+		return time.Second, nil
+	case "m", "min", "minute":
+		return time.Minute, nil
+	case "h", "hour":
+		return time.Hour, nil
+	case "d", "day":
+		return 24 * time.Hour, nil
+	}
+
+	period, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad period %q", value)
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("period must be positive")
+	}
+	return period, nil
+}
+
+func formatPeriod(period time.Duration) string {
+	switch period {
+	case time.Second:
+		return "s"
+	case time.Minute:
+		return "m"
+	case time.Hour:
+		return "h"
+	case 24 * time.Hour:
+		return "d"
+	}
+	return period.String()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a denied client has to wait for the next token.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Buckets are stored as a single time, the theoretical arrival time (TAT) of
+// the generic cell rate algorithm: the time at which the bucket will be full
+// again. Every request pushes it one interval further, and a request is
+// allowed while that stays within burst intervals of now. This behaves like
+// a token bucket but is a single value a Store can update atomically, and
+// buckets with a TAT in the past are full and can be forgotten.
+
+// interval is the time one token takes to refill.
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.rate())
+}
+
+// admit returns the latest stored TAT that still leaves a token at now.
+func (l Limit) admit(now time.Time) time.Time {
+	return now.Add(time.Duration(l.burst()-1) * l.interval())
+}
+
+// take applies one request to a bucket last at tat (zero for a new bucket)
+// and returns its new TAT.
+func take(tat time.Time, limit Limit, now time.Time) (time.Time, Result) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	allowed := !tat.After(limit.admit(now))
+	if allowed {
+		tat = tat.Add(limit.interval())
+	}
+	return tat, result(limit, tat, allowed, now)
+}
+
+// result describes a bucket at tat after a request.
+func result(limit Limit, tat time.Time, allowed bool, now time.Time) Result {
+	res := Result{
+		Allowed: allowed,
+		Limit:   int(limit.burst()),
+		Reset:   positive(tat.Sub(now)),
+	}
+
+	// The epsilon absorbs the rounding of TATs stored as float seconds
+	used := float64(tat.Sub(now)) / float64(limit.interval())
+	if remaining := int(math.Floor(limit.burst() - used + 1e-6)); remaining > 0 {
+		res.Remaining = remaining
+	}
+	if !allowed {
+		res.RetryAfter = positive(tat.Sub(limit.admit(now)))
+	}
+	return res
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
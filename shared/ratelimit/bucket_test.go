@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{"60/m", Limit{Requests: 60, Period: time.Minute}, false},
+		{"5/10s", Limit{Requests: 5, Period: 10 * time.Second}, false},
+		{"100/h:20", Limit{Requests: 100, Period: time.Hour, Burst: 20}, false},
+		{" 1000 / day ", Limit{Requests: 1000, Period: 24 * time.Hour}, false},
+		{"off", Limit{}, false},
+		{"0", Limit{}, false},
+		{"60", Limit{}, true},
+		{"-1/m", Limit{}, true},
+		{"60/fortnight", Limit{}, true},
+		{"60/-5s", Limit{}, true},
+		{"60/m:0", Limit{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseLimit(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseLimit err = %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseLimit = %+v, want %+v", got, tc.want)
+			}
+			if err == nil {
+				if again, err := ParseLimit(got.String()); err != nil || again != got {
+					t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", got.String(), again, err, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTake(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	type step struct {
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}
+	tests := []struct {
+		name  string
+		limit Limit
+		steps []step
+	}{
+		{
+			name:  "burst then refill",
+			limit: Limit{Requests: 3, Period: 3 * time.Second},
+			steps: []step{
+				{0, true, 2, 0},
+				{0, true, 1, 0},
+				{0, true, 0, 0},
+				{0, false, 0, time.Second},
+				{500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+				{time.Second, true, 0, 0},
+				{5 * time.Second, true, 2, 0},
+			},
+		},
+		{
+			name:  "burst below the rate",
+			limit: Limit{Requests: 60, Period: time.Minute, Burst: 2},
+			steps: []step{
+				{0, true, 1, 0},
+				{0, true, 0, 0},
+				{0, false, 0, time.Second},
+				{time.Second, true, 0, 0},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var tat time.Time
+			for i, s := range tc.steps {
+				var res Result
+				tat, res = take(tat, tc.limit, start.Add(s.at))
+				if res.Allowed != s.wantAllowed || res.Remaining != s.wantRemaining || res.RetryAfter != s.wantRetry {
+					t.Errorf("step %d at %v: %+v, want allowed %v, remaining %d, retry after %v",
+						i, s.at, res, s.wantAllowed, s.wantRemaining, s.wantRetry)
+				}
+				if res.Limit != int(tc.limit.burst()) {
+					t.Errorf("step %d: Limit = %d, want %d", i, res.Limit, int(tc.limit.burst()))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/database"
+)
+
+// APIKeyHeader identifies clients that call the gateway with an API key
+// instead of a user token.
+const APIKeyHeader = "X-API-Key"
+
+// Route gives the requests under a path prefix their own budget. The
+// longest matching prefix wins.
+type Route struct {
+	Prefix string
+	Limit  Limit
+}
+
+// Config holds the budgets. Routes without a budget of their own share the
+// Default one.
+type Config struct {
+	Default Limit
+	Routes  []Route
+	// TrustedProxies is the number of proxies in front of the gateway that
+	// append the address they saw to X-Forwarded-For. When set, anonymous
+	// clients are keyed by the address the outermost of them saw, counted
+	// from the right, instead of the connection address. Entries further left
+	// come from the client and are ignored.
+	TrustedProxies int
+	// APIKeys are the keys accepted in X-API-Key. Unknown keys are ignored,
+	// so clients cannot dodge their IP budget by making up keys.
+	APIKeys []string
+	// Store is "memory" or "database".
+	Store string
+}
+
+// DefaultConfig allows 120 requests a minute per client, with tighter
+// budgets for the routes that hit paid upstream APIs on every call and no
+// limit on /health.
+func DefaultConfig() Config {
+	return Config{
+		Default: Limit{Requests: 120, Period: time.Minute},
+		Routes: []Route{
+			{Prefix: "/health"},
+			{Prefix: "/api/news/search", Limit: Limit{Requests: 30, Period: time.Minute}},
+			{Prefix: "/api/jobs/search", Limit: Limit{Requests: 30, Period: time.Minute}},
+			{Prefix: "/api/videos/search", Limit: Limit{Requests: 30, Period: time.Minute}},
+			{Prefix: "/api/food/search", Limit: Limit{Requests: 30, Period: time.Minute}},
+			{Prefix: "/api/nft/mint", Limit: Limit{Requests: 5, Period: time.Minute}},
+		},
+		Store: "memory",
+	}
+}
+
+// ConfigFromEnv starts from DefaultConfig and applies RATE_LIMIT_DEFAULT,
+// RATE_LIMIT_ROUTES, RATE_LIMIT_API_KEYS, RATE_LIMIT_TRUSTED_PROXIES and
+// RATE_LIMIT_STORE. RATE_LIMIT_TRUST_FORWARDED_FOR=true is the same as one
+// trusted proxy.
+// RATE_LIMIT_ROUTES is a comma-separated list of prefix=limit pairs, e.g.
+// "/api/news=60/m,/api/nft/mint=2/m:1"; it adds to and overrides the
+// default routes.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %v", err)
+		}
+		cfg.Default = limit
+	}
+
+	if value := os.Getenv("RATE_LIMIT_ROUTES"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			prefix, limitValue, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || !strings.HasPrefix(prefix, "/") {
+				return cfg, fmt.Errorf("invalid RATE_LIMIT_ROUTES entry %q: expected /prefix=limit", pair)
+			}
+			limit, err := ParseLimit(limitValue)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid RATE_LIMIT_ROUTES entry %q: %v", pair, err)
+			}
+			cfg.SetRoute(prefix, limit)
+		}
+	}
+
+	if value := os.Getenv("RATE_LIMIT_API_KEYS"); value != "" {
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.APIKeys = append(cfg.APIKeys, key)
+			}
+		}
+	}
+
+	if value := os.Getenv("RATE_LIMIT_TRUST_FORWARDED_FOR"); value == "true" || value == "1" {
+		cfg.TrustedProxies = 1
+	}
+	if value := os.Getenv("RATE_LIMIT_TRUSTED_PROXIES"); value != "" {
+		proxies, err := strconv.Atoi(value)
+		if err != nil || proxies < 0 {
+			return cfg, fmt.Errorf("invalid RATE_LIMIT_TRUSTED_PROXIES: %q", value)
+		}
+		cfg.TrustedProxies = proxies
+	}
+	if value := os.Getenv("RATE_LIMIT_STORE"); value != "" {
+		cfg.Store = strings.ToLower(value)
+	}
+
+	return cfg, nil
+}
+
+// SetRoute sets the budget of prefix, replacing an existing one.
+func (c *Config) SetRoute(prefix string, limit Limit) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	for i := range c.Routes {
+		if c.Routes[i].Prefix == prefix {
+			c.Routes[i].Limit = limit
+			return
+		}
+	}
+	c.Routes = append(c.Routes, Route{Prefix: prefix, Limit: limit})
+}
+
+// OpenStore returns the store named by cfg.Store. The database store uses
+// the shared database configuration.
+func OpenStore(cfg Config) (Store, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "database", "db":
+		db, err := database.SetupDatabase()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open rate limit store: %v", err)
+		}
+		return NewSQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store: %s", cfg.Store)
+	}
+}
+
+// Limiter applies the budgets of a Config to requests.
+type Limiter struct {
+	cfg     Config
+	routes  []Route
+	apiKeys map[string]bool
+	store   Store
+	now     func() time.Time
+}
+
+func New(cfg Config, store Store) *Limiter {
+	routes := append([]Route(nil), cfg.Routes...)
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+
+	apiKeys := make(map[string]bool, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		apiKeys[hashKey(key)] = true
+	}
+
+	return &Limiter{cfg: cfg, routes: routes, apiKeys: apiKeys, store: store, now: time.Now}
+}
+
+// route returns the bucket name and budget for path.
+func (l *Limiter) route(path string) (string, Limit) {
+	for _, route := range l.routes {
+		if path == route.Prefix || strings.HasPrefix(path, route.Prefix+"/") {
+			return route.Prefix, route.Limit
+		}
+	}
+	return "*", l.cfg.Default
+}
+
+// clientKey identifies the caller: the authenticated user, else an API key
+// listed in RATE_LIMIT_API_KEYS, else the client IP. API keys are hashed so
+// they never reach the store.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if userID := auth.UserIDFrom(r.Context()); userID != "" {
+		return "user:" + userID
+	}
+	if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+		if hashed := hashKey(apiKey); l.apiKeys[hashed] {
+			return "key:" + hashed
+		}
+	}
+
+	if address := forwardedAddress(r, l.cfg.TrustedProxies); address != "" {
+		return "ip:" + address
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// forwardedAddress returns the X-Forwarded-For entry added by the outermost
+// of proxies trusted proxies, or "" when none is trusted or the header is
+// missing. Repeated headers are read as one list.
+func forwardedAddress(r *http.Request, proxies int) string {
+	if proxies <= 0 {
+		return ""
+	}
+
+	var addresses []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return ""
+	}
+	if proxies > len(addresses) {
+		return addresses[0]
+	}
+	return addresses[len(addresses)-proxies]
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/database/dbtest"
+)
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		proxies       int
+		user          string
+		apiKey        string
+		forwardedFor  []string
+		wantClientKey string
+	}{
+		{"user wins", 1, "alice", "known", []string{"1.1.1.1"}, "user:alice"},
+		{"known API key", 0, "", "known", nil, "key:" + hashKey("known")},
+		{"unknown API key", 0, "", "made-up", nil, "ip:10.0.0.1"},
+		{"forwarded for ignored", 0, "", "", []string{"1.1.1.1"}, "ip:10.0.0.1"},
+		{"one proxy takes the rightmost entry", 1, "", "", []string{"6.6.6.6, 1.1.1.1"}, "ip:1.1.1.1"},
+		{"two proxies", 2, "", "", []string{"6.6.6.6, 1.1.1.1", "2.2.2.2"}, "ip:1.1.1.1"},
+		{"more proxies than entries", 3, "", "", []string{"1.1.1.1, 2.2.2.2"}, "ip:1.1.1.1"},
+		{"no header", 1, "", "", nil, "ip:10.0.0.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := New(Config{TrustedProxies: tc.proxies, APIKeys: []string{"known"}}, NewMemoryStore())
+
+			var got string
+			handler := auth.Identity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = limiter.clientKey(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/news", nil)
+			req.RemoteAddr = "10.0.0.1:51234"
+			if tc.user != "" {
+				req.Header.Set(auth.UserIDHeader, tc.user)
+			}
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+			for _, value := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tc.wantClientKey {
+				t.Errorf("clientKey = %q, want %q", got, tc.wantClientKey)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMIT_DEFAULT", "10/s")
+	t.Setenv("RATE_LIMIT_ROUTES", "/api/news=60/m,/api/nft/mint/=2/m:1")
+	t.Setenv("RATE_LIMIT_TRUST_FORWARDED_FOR", "true")
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "2")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv: %v", err)
+	}
+	if cfg.TrustedProxies != 2 {
+		t.Errorf("TrustedProxies = %d, want 2", cfg.TrustedProxies)
+	}
+
+	limiter := New(cfg, NewMemoryStore())
+	tests := []struct {
+		path      string
+		wantName  string
+		wantLimit Limit
+	}{
+		{"/api/news", "/api/news", Limit{Requests: 60, Period: time.Minute}},
+		{"/api/news/search", "/api/news/search", Limit{Requests: 30, Period: time.Minute}},
+		{"/api/newsletter", "*", Limit{Requests: 10, Period: time.Second}},
+		{"/api/nft/mint", "/api/nft/mint", Limit{Requests: 2, Period: time.Minute, Burst: 1}},
+		{"/health", "/health", Limit{}},
+	}
+	for _, tc := range tests {
+		name, limit := limiter.route(tc.path)
+		if name != tc.wantName || limit != tc.wantLimit {
+			t.Errorf("route(%s) = %s, %+v; want %s, %+v", tc.path, name, limit, tc.wantName, tc.wantLimit)
+		}
+	}
+
+	for name, value := range map[string]string{
+		"RATE_LIMIT_TRUSTED_PROXIES": "-1",
+		"RATE_LIMIT_ROUTES":          "api/news=60/m",
+		"RATE_LIMIT_DEFAULT":         "lots",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := ConfigFromEnv(); err == nil {
+				t.Errorf("ConfigFromEnv with %s=%s succeeded", name, value)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store { return NewSQLStore(dbtest.SQLite(t)) },
+	}
+
+	// A time that float seconds store a few nanoseconds late, which used to
+	// push the SQL store's headers a second too high
+	start := time.Unix(1700000000, 7919)
+
+	type step struct {
+		at         time.Duration
+		wantStatus int
+		wantHeader map[string]string
+	}
+	steps := []step{
+		{0, http.StatusOK, map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "7", "RateLimit-Policy": "2;w=14"}},
+		{0, http.StatusOK, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "14"}},
+		{0, http.StatusTooManyRequests, map[string]string{"RateLimit-Remaining": "0", "Retry-After": "7", "RateLimit-Reset": "14"}},
+		{3 * time.Second, http.StatusTooManyRequests, map[string]string{"Retry-After": "4", "RateLimit-Reset": "11"}},
+		{7 * time.Second, http.StatusOK, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "14"}},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			limiter := New(Config{Default: Limit{Requests: 2, Period: 14 * time.Second}, Routes: []Route{{Prefix: "/health"}}}, open(t))
+			var now time.Time
+			limiter.now = func() time.Time { return now }
+			handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i, s := range steps {
+				now = start.Add(s.at)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/news", nil))
+				if rec.Code != s.wantStatus {
+					t.Errorf("step %d: status = %d, want %d", i, rec.Code, s.wantStatus)
+				}
+				for header, want := range s.wantHeader {
+					if got := rec.Header().Get(header); got != want {
+						t.Errorf("step %d: %s = %q, want %q", i, header, got, want)
+					}
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+				t.Errorf("/health was limited: %d %v", rec.Code, rec.Header())
+			}
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, context.DeadlineExceeded
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	limiter := New(Config{Default: Limit{Requests: 1, Period: time.Minute}}, failingStore{})
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/news", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status with a failing store = %d, want 200", rec.Code)
+	}
+}
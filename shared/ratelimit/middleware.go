@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Middleware takes a token for every request and rejects the request with
+// 429 when the client's bucket for the route is empty. It must run after the
+// auth middleware so that authenticated users are keyed by user. Responses
+// carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; rejections also carry Retry-After. When the store
+// fails the request is let through, so a store outage does not take the
+// gateway down.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, limit := l.route(r.URL.Path)
+		if limit.Unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := name + "|" + l.clientKey(r)
+		res, err := l.store.Take(r.Context(), key, limit, l.now())
+		if err != nil {
+			log.Printf("Warning: rate limiting skipped: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		header.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+
+		if !res.Allowed {
+			header.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			header.Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":       "rate limit exceeded",
+				"limit":       limit.String(),
+				"retry_after": math.Ceil(res.RetryAfter.Round(time.Millisecond).Seconds()),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds d up to whole seconds. d is first rounded to the
+// millisecond, so that the error of TATs stored as float seconds cannot push
+// an exact number of seconds to the next one.
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Round(time.Millisecond).Seconds())), 10)
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"personalized-dashboard/shared/database"
+)
+
+// Store keeps the token buckets. Take must be atomic per key, since several
+// requests — or several gateway replicas — may hit the same bucket at once.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// MemoryStore keeps buckets in process memory. Limits only hold per replica;
+// use SQLStore when the gateway runs more than once.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget full buckets once a minute
+	if now.Sub(s.pruned) >= time.Minute {
+		for bucketKey, tat := range s.buckets {
+			if tat.Before(now) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.pruned = now
+	}
+
+	tat, res := take(s.buckets[key], limit, now)
+	s.buckets[key] = tat
+	return res, nil
+}
+
+// SQLStore keeps buckets in the rate_limit_buckets table so that all gateway
+// replicas share them. Each Take is a single upsert, so concurrent requests
+// for a bucket are serialized by the database.
+type SQLStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: database.DialectOf(db)}
+}
+
+// takeQuery is take in SQL. Times are Unix seconds so that the arithmetic is
+// the same in Postgres and SQLite: $2 is now, $3 the refill interval, $4 the
+// latest TAT that still admits a request and $5 the TAT of a new bucket.
+const takeQuery = `
+	INSERT INTO rate_limit_buckets (key, tat, allowed)
+	VALUES ($1, $5, 1)
+	ON CONFLICT (key) DO UPDATE SET
+		tat = CASE
+			WHEN rate_limit_buckets.tat < $2 THEN $5
+			WHEN rate_limit_buckets.tat <= $4 THEN rate_limit_buckets.tat + $3
+			ELSE rate_limit_buckets.tat
+		END,
+		allowed = CASE WHEN rate_limit_buckets.tat <= $4 THEN 1 ELSE 0 END
+	RETURNING tat, allowed`
+
+func (s *SQLStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.prune(ctx, now)
+
+	var tat float64
+	var allowed int
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(takeQuery),
+		key,
+		unixSeconds(now),
+		limit.interval().Seconds(),
+		unixSeconds(limit.admit(now)),
+		unixSeconds(now.Add(limit.interval())),
+	).Scan(&tat, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %v", err)
+	}
+	return result(limit, fromUnixSeconds(tat), allowed == 1, now), nil
+}
+
+// prune deletes full buckets, at most once a minute per replica.
+func (s *SQLStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.pruned) >= time.Minute
+	if due {
+		s.pruned = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	query := s.dialect.Rebind(`DELETE FROM rate_limit_buckets WHERE tat < $1`)
+	if _, err := s.db.ExecContext(ctx, query, unixSeconds(now)); err != nil {
+		log.Printf("Warning: failed to prune rate limit buckets: %v", err)
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// fromUnixSeconds reads a stored time back to the microsecond, which is all
+// the precision float seconds keep for current times.
+func fromUnixSeconds(s float64) time.Time {
+	return time.UnixMicro(int64(math.Round(s * 1e6)))
+}
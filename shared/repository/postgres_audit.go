@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"personalized-dashboard/shared/models"
+)
+
+// AuditFilter selects audit entries. Empty fields match every entry; Since is
+// inclusive and Until exclusive.
+type AuditFilter struct {
+	UserID string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+const auditColumns = "id, occurred_at, actor, action, target_type, target_id, user_id, request_id, changes"
+
+type pgAuditRepository struct {
+	db dbtx
+}
+
+func (r *pgAuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	ensureID(&entry.ID)
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO audit_log ("+auditColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		entry.ID, entry.OccurredAt, entry.Actor, entry.Action, entry.TargetType, entry.TargetID,
+		nullString(entry.UserID), nullString(entry.RequestID), entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %v", err)
+	}
+	return nil
+}
+
+// List returns matching entries, newest first.
+func (r *pgAuditRepository) List(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE 1 = 1"
+	args := make([]interface{}, 0, 5)
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		query += fmt.Sprintf(" AND occurred_at >= $%d", len(args))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		query += fmt.Sprintf(" AND occurred_at < $%d", len(args))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	args = append(args, limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		var userID, requestID sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.OccurredAt, &entry.Actor, &entry.Action, &entry.TargetType,
+			&entry.TargetID, &userID, &requestID, &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entry.UserID = userID.String
+		entry.RequestID = requestID.String
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,649 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/models"
+)
+
+// listQuery appends the optional filter, category filter and paging to a
+// SELECT.
+func listQuery(base, filter, orderBy string, opts ListOptions) (string, []interface{}) {
+	conditions := make([]string, 0, 2)
+	if filter != "" {
+		conditions = append(conditions, filter)
+	}
+
+	query := base
+	args := make([]interface{}, 0, 3)
+	if opts.Category != "" {
+		args = append(args, opts.Category)
+		conditions = append(conditions, fmt.Sprintf("category = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, opts.limit(), opts.Offset)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, len(args)-1, len(args))
+	return query, args
+}
+
+// activeDeals hides deals the retention sweeper has archived.
+const activeDeals = "archived_at IS NULL"
+
+const newsArticleColumns = `id, title, COALESCE(description, ''), url, COALESCE(source, ''),
+	COALESCE(category, ''), published_at, COALESCE(image_url, ''), first_seen_at, last_seen_at`
+
+type pgNewsArticleRepository struct {
+	db      dbtx
+	dialect database.Dialect
+}
+
+func scanNewsArticle(row rowScanner) (*models.NewsArticle, error) {
+	var article models.NewsArticle
+	var publishedAt, firstSeenAt, lastSeenAt sql.NullTime
+	if err := row.Scan(&article.ID, &article.Title, &article.Description, &article.URL, &article.Source,
+		&article.Category, &publishedAt, &article.ImageURL, &firstSeenAt, &lastSeenAt); err != nil {
+		return nil, err
+	}
+	article.PublishedAt = publishedAt.Time
+	article.FirstSeenAt = firstSeenAt.Time
+	article.LastSeenAt = lastSeenAt.Time
+	return &article, nil
+}
+
+func (r *pgNewsArticleRepository) Create(ctx context.Context, article *models.NewsArticle) error {
+	ensureID(&article.ID)
+	markSeen(&article.FirstSeenAt, &article.LastSeenAt)
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO news_articles
+		(id, title, description, url, source, category, published_at, image_url, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		article.ID, article.Title, article.Description, article.URL, article.Source,
+		article.Category, nullTime(article.PublishedAt), article.ImageURL, article.FirstSeenAt, article.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to create news article: %v", err)
+	}
+	return nil
+}
+
+func (r *pgNewsArticleRepository) Upsert(ctx context.Context, article *models.NewsArticle) (bool, error) {
+	candidate := uuid.New()
+	article.LastSeenAt = time.Now()
+
+	var id uuid.UUID
+	var firstSeenAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `INSERT INTO news_articles
+		(id, title, description, url, source, category, published_at, image_url, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			source = EXCLUDED.source,
+			category = COALESCE(NULLIF(EXCLUDED.category, ''), news_articles.category),
+			published_at = COALESCE(EXCLUDED.published_at, news_articles.published_at),
+			image_url = EXCLUDED.image_url,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, first_seen_at`,
+		candidate, article.Title, article.Description, article.URL, article.Source,
+		article.Category, nullTime(article.PublishedAt), article.ImageURL, article.LastSeenAt).Scan(&id, &firstSeenAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert news article: %v", err)
+	}
+
+	article.ID = id
+	article.FirstSeenAt = firstSeenAt.Time
+	return id == candidate, nil
+}
+
+func (r *pgNewsArticleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.NewsArticle, error) {
+	article, err := scanNewsArticle(r.db.QueryRowContext(ctx,
+		"SELECT "+newsArticleColumns+" FROM news_articles WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return article, nil
+}
+
+func (r *pgNewsArticleRepository) GetByURL(ctx context.Context, url string) (*models.NewsArticle, error) {
+	article, err := scanNewsArticle(r.db.QueryRowContext(ctx,
+		"SELECT "+newsArticleColumns+" FROM news_articles WHERE url = $1", url))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return article, nil
+}
+
+func (r *pgNewsArticleRepository) List(ctx context.Context, opts ListOptions) ([]models.NewsArticle, error) {
+	query, args := listQuery("SELECT "+newsArticleColumns+" FROM news_articles", "", "published_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list news articles: %v", err)
+	}
+	defer rows.Close()
+
+	articles := make([]models.NewsArticle, 0)
+	for rows.Next() {
+		article, err := scanNewsArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan news article: %v", err)
+		}
+		articles = append(articles, *article)
+	}
+	return articles, rows.Err()
+}
+
+func (r *pgNewsArticleRepository) Search(ctx context.Context, opts SearchOptions) ([]NewsArticleHit, error) {
+	hits := make([]NewsArticleHit, 0)
+	if strings.TrimSpace(opts.Query) == "" {
+		return hits, nil
+	}
+
+	query, args := searchQuery(r.dialect, "news_articles", newsArticleColumns, "", "published_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search news articles: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		article, err := scanNewsArticle(hitScanner{row: rows, hit: &hit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan news article: %v", err)
+		}
+		finishHit(r.dialect, &hit, opts.Query)
+		hits = append(hits, NewsArticleHit{NewsArticle: *article, SearchHit: hit})
+	}
+	return hits, rows.Err()
+}
+
+func (r *pgNewsArticleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM news_articles WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete news article: %v", err)
+	}
+	return expectRows(result)
+}
+
+const jobListingColumns = `id, title, COALESCE(company, ''), COALESCE(location, ''), COALESCE(description, ''),
+	url, COALESCE(category, ''), posted_at, COALESCE(salary, ''), first_seen_at, last_seen_at`
+
+type pgJobListingRepository struct {
+	db      dbtx
+	dialect database.Dialect
+}
+
+func scanJobListing(row rowScanner) (*models.JobListing, error) {
+	var job models.JobListing
+	var postedAt, firstSeenAt, lastSeenAt sql.NullTime
+	if err := row.Scan(&job.ID, &job.Title, &job.Company, &job.Location, &job.Description,
+		&job.URL, &job.Category, &postedAt, &job.Salary, &firstSeenAt, &lastSeenAt); err != nil {
+		return nil, err
+	}
+	job.PostedAt = postedAt.Time
+	job.FirstSeenAt = firstSeenAt.Time
+	job.LastSeenAt = lastSeenAt.Time
+	return &job, nil
+}
+
+func (r *pgJobListingRepository) Create(ctx context.Context, job *models.JobListing) error {
+	ensureID(&job.ID)
+	markSeen(&job.FirstSeenAt, &job.LastSeenAt)
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO job_listings
+		(id, title, company, location, description, url, category, posted_at, salary, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		job.ID, job.Title, job.Company, job.Location, job.Description,
+		job.URL, job.Category, nullTime(job.PostedAt), job.Salary, job.FirstSeenAt, job.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to create job listing: %v", err)
+	}
+	return nil
+}
+
+func (r *pgJobListingRepository) Upsert(ctx context.Context, job *models.JobListing) (bool, error) {
+	candidate := uuid.New()
+	job.LastSeenAt = time.Now()
+
+	var id uuid.UUID
+	var firstSeenAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `INSERT INTO job_listings
+		(id, title, company, location, description, url, category, posted_at, salary, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			company = EXCLUDED.company,
+			location = EXCLUDED.location,
+			description = EXCLUDED.description,
+			category = COALESCE(NULLIF(EXCLUDED.category, ''), job_listings.category),
+			posted_at = COALESCE(EXCLUDED.posted_at, job_listings.posted_at),
+			salary = EXCLUDED.salary,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, first_seen_at`,
+		candidate, job.Title, job.Company, job.Location, job.Description,
+		job.URL, job.Category, nullTime(job.PostedAt), job.Salary, job.LastSeenAt).Scan(&id, &firstSeenAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert job listing: %v", err)
+	}
+
+	job.ID = id
+	job.FirstSeenAt = firstSeenAt.Time
+	return id == candidate, nil
+}
+
+func (r *pgJobListingRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.JobListing, error) {
+	job, err := scanJobListing(r.db.QueryRowContext(ctx,
+		"SELECT "+jobListingColumns+" FROM job_listings WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return job, nil
+}
+
+func (r *pgJobListingRepository) GetByURL(ctx context.Context, url string) (*models.JobListing, error) {
+	job, err := scanJobListing(r.db.QueryRowContext(ctx,
+		"SELECT "+jobListingColumns+" FROM job_listings WHERE url = $1", url))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return job, nil
+}
+
+func (r *pgJobListingRepository) List(ctx context.Context, opts ListOptions) ([]models.JobListing, error) {
+	query, args := listQuery("SELECT "+jobListingColumns+" FROM job_listings", "", "posted_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job listings: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]models.JobListing, 0)
+	for rows.Next() {
+		job, err := scanJobListing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job listing: %v", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *pgJobListingRepository) Search(ctx context.Context, opts SearchOptions) ([]JobListingHit, error) {
+	hits := make([]JobListingHit, 0)
+	if strings.TrimSpace(opts.Query) == "" {
+		return hits, nil
+	}
+
+	query, args := searchQuery(r.dialect, "job_listings", jobListingColumns, "", "posted_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search job listings: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		job, err := scanJobListing(hitScanner{row: rows, hit: &hit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job listing: %v", err)
+		}
+		finishHit(r.dialect, &hit, opts.Query)
+		hits = append(hits, JobListingHit{JobListing: *job, SearchHit: hit})
+	}
+	return hits, rows.Err()
+}
+
+func (r *pgJobListingRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM job_listings WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete job listing: %v", err)
+	}
+	return expectRows(result)
+}
+
+const videoColumns = `id, title, COALESCE(description, ''), url, COALESCE(channel, ''), COALESCE(category, ''),
+	published_at, COALESCE(thumbnail, ''), COALESCE(duration, ''), COALESCE(views, 0), first_seen_at, last_seen_at`
+
+type pgVideoRepository struct {
+	db      dbtx
+	dialect database.Dialect
+}
+
+func scanVideo(row rowScanner) (*models.Video, error) {
+	var video models.Video
+	var publishedAt, firstSeenAt, lastSeenAt sql.NullTime
+	if err := row.Scan(&video.ID, &video.Title, &video.Description, &video.URL, &video.Channel, &video.Category,
+		&publishedAt, &video.Thumbnail, &video.Duration, &video.Views, &firstSeenAt, &lastSeenAt); err != nil {
+		return nil, err
+	}
+	video.PublishedAt = publishedAt.Time
+	video.FirstSeenAt = firstSeenAt.Time
+	video.LastSeenAt = lastSeenAt.Time
+	return &video, nil
+}
+
+func (r *pgVideoRepository) Create(ctx context.Context, video *models.Video) error {
+	ensureID(&video.ID)
+	markSeen(&video.FirstSeenAt, &video.LastSeenAt)
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO videos
+		(id, title, description, url, channel, category, published_at, thumbnail, duration, views, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		video.ID, video.Title, video.Description, video.URL, video.Channel, video.Category,
+		nullTime(video.PublishedAt), video.Thumbnail, video.Duration, video.Views, video.FirstSeenAt, video.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to create video: %v", err)
+	}
+	return nil
+}
+
+func (r *pgVideoRepository) Upsert(ctx context.Context, video *models.Video) (bool, error) {
+	candidate := uuid.New()
+	video.LastSeenAt = time.Now()
+
+	var id uuid.UUID
+	var firstSeenAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `INSERT INTO videos
+		(id, title, description, url, channel, category, published_at, thumbnail, duration, views, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			channel = EXCLUDED.channel,
+			category = COALESCE(NULLIF(EXCLUDED.category, ''), videos.category),
+			published_at = COALESCE(EXCLUDED.published_at, videos.published_at),
+			thumbnail = EXCLUDED.thumbnail,
+			duration = EXCLUDED.duration,
+			views = EXCLUDED.views,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, first_seen_at`,
+		candidate, video.Title, video.Description, video.URL, video.Channel, video.Category,
+		nullTime(video.PublishedAt), video.Thumbnail, video.Duration, video.Views, video.LastSeenAt).Scan(&id, &firstSeenAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert video: %v", err)
+	}
+
+	video.ID = id
+	video.FirstSeenAt = firstSeenAt.Time
+	return id == candidate, nil
+}
+
+func (r *pgVideoRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Video, error) {
+	video, err := scanVideo(r.db.QueryRowContext(ctx,
+		"SELECT "+videoColumns+" FROM videos WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return video, nil
+}
+
+func (r *pgVideoRepository) GetByURL(ctx context.Context, url string) (*models.Video, error) {
+	video, err := scanVideo(r.db.QueryRowContext(ctx,
+		"SELECT "+videoColumns+" FROM videos WHERE url = $1", url))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return video, nil
+}
+
+func (r *pgVideoRepository) List(ctx context.Context, opts ListOptions) ([]models.Video, error) {
+	query, args := listQuery("SELECT "+videoColumns+" FROM videos", "", "published_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list videos: %v", err)
+	}
+	defer rows.Close()
+
+	videos := make([]models.Video, 0)
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan video: %v", err)
+		}
+		videos = append(videos, *video)
+	}
+	return videos, rows.Err()
+}
+
+func (r *pgVideoRepository) Search(ctx context.Context, opts SearchOptions) ([]VideoHit, error) {
+	hits := make([]VideoHit, 0)
+	if strings.TrimSpace(opts.Query) == "" {
+		return hits, nil
+	}
+
+	query, args := searchQuery(r.dialect, "videos", videoColumns, "", "published_at DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search videos: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		video, err := scanVideo(hitScanner{row: rows, hit: &hit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan video: %v", err)
+		}
+		finishHit(r.dialect, &hit, opts.Query)
+		hits = append(hits, VideoHit{Video: *video, SearchHit: hit})
+	}
+	return hits, rows.Err()
+}
+
+func (r *pgVideoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM videos WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete video: %v", err)
+	}
+	return expectRows(result)
+}
+
+const dealColumns = `id, title, COALESCE(description, ''), url, COALESCE(platform, ''), COALESCE(category, ''),
+	COALESCE(price, 0), COALESCE(original_price, 0), COALESCE(discount, 0), COALESCE(image_url, ''), valid_until,
+	first_seen_at, last_seen_at`
+
+type pgDealRepository struct {
+	db      dbtx
+	dialect database.Dialect
+}
+
+func scanDeal(row rowScanner) (*models.Deal, error) {
+	var deal models.Deal
+	var validUntil, firstSeenAt, lastSeenAt sql.NullTime
+	if err := row.Scan(&deal.ID, &deal.Title, &deal.Description, &deal.URL, &deal.Platform, &deal.Category,
+		&deal.Price, &deal.OriginalPrice, &deal.Discount, &deal.ImageURL, &validUntil,
+		&firstSeenAt, &lastSeenAt); err != nil {
+		return nil, err
+	}
+	deal.ValidUntil = validUntil.Time
+	deal.FirstSeenAt = firstSeenAt.Time
+	deal.LastSeenAt = lastSeenAt.Time
+	return &deal, nil
+}
+
+func (r *pgDealRepository) Create(ctx context.Context, deal *models.Deal) error {
+	ensureID(&deal.ID)
+	markSeen(&deal.FirstSeenAt, &deal.LastSeenAt)
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO deals
+		(id, title, description, url, platform, category, price, original_price, discount, image_url, valid_until,
+		first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		deal.ID, deal.Title, deal.Description, deal.URL, deal.Platform, deal.Category,
+		deal.Price, deal.OriginalPrice, deal.Discount, deal.ImageURL, nullTime(deal.ValidUntil),
+		deal.FirstSeenAt, deal.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to create deal: %v", err)
+	}
+	return nil
+}
+
+func (r *pgDealRepository) Upsert(ctx context.Context, deal *models.Deal) (bool, error) {
+	candidate := uuid.New()
+	deal.LastSeenAt = time.Now()
+
+	var id uuid.UUID
+	var firstSeenAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `INSERT INTO deals
+		(id, title, description, url, platform, category, price, original_price, discount, image_url, valid_until,
+		first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			platform = EXCLUDED.platform,
+			category = COALESCE(NULLIF(EXCLUDED.category, ''), deals.category),
+			price = EXCLUDED.price,
+			original_price = EXCLUDED.original_price,
+			discount = EXCLUDED.discount,
+			image_url = EXCLUDED.image_url,
+			valid_until = COALESCE(EXCLUDED.valid_until, deals.valid_until),
+			archived_at = CASE WHEN EXCLUDED.valid_until > deals.valid_until THEN NULL ELSE deals.archived_at END,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, first_seen_at`,
+		candidate, deal.Title, deal.Description, deal.URL, deal.Platform, deal.Category,
+		deal.Price, deal.OriginalPrice, deal.Discount, deal.ImageURL, nullTime(deal.ValidUntil),
+		deal.LastSeenAt).Scan(&id, &firstSeenAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert deal: %v", err)
+	}
+
+	deal.ID = id
+	deal.FirstSeenAt = firstSeenAt.Time
+	return id == candidate, nil
+}
+
+func (r *pgDealRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Deal, error) {
+	deal, err := scanDeal(r.db.QueryRowContext(ctx,
+		"SELECT "+dealColumns+" FROM deals WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return deal, nil
+}
+
+func (r *pgDealRepository) GetByURL(ctx context.Context, url string) (*models.Deal, error) {
+	deal, err := scanDeal(r.db.QueryRowContext(ctx,
+		"SELECT "+dealColumns+" FROM deals WHERE url = $1", url))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return deal, nil
+}
+
+func (r *pgDealRepository) List(ctx context.Context, opts ListOptions) ([]models.Deal, error) {
+	query, args := listQuery("SELECT "+dealColumns+" FROM deals", activeDeals, "discount DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deals: %v", err)
+	}
+	defer rows.Close()
+
+	deals := make([]models.Deal, 0)
+	for rows.Next() {
+		deal, err := scanDeal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deal: %v", err)
+		}
+		deals = append(deals, *deal)
+	}
+	return deals, rows.Err()
+}
+
+func (r *pgDealRepository) Search(ctx context.Context, opts SearchOptions) ([]DealHit, error) {
+	hits := make([]DealHit, 0)
+	if strings.TrimSpace(opts.Query) == "" {
+		return hits, nil
+	}
+
+	query, args := searchQuery(r.dialect, "deals", dealColumns, activeDeals, "discount DESC NULLS LAST", opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search deals: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		deal, err := scanDeal(hitScanner{row: rows, hit: &hit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deal: %v", err)
+		}
+		finishHit(r.dialect, &hit, opts.Query)
+		hits = append(hits, DealHit{Deal: *deal, SearchHit: hit})
+	}
+	return hits, rows.Err()
+}
+
+func (r *pgDealRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM deals WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete deal: %v", err)
+	}
+	return expectRows(result)
+}
+
+type pgRecommendationRepository struct {
+	db dbtx
+}
+
+func (r *pgRecommendationRepository) Create(ctx context.Context, recommendation *models.Recommendation) error {
+	ensureID(&recommendation.ID)
+	if recommendation.CreatedAt.IsZero() {
+		recommendation.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO recommendations
+		(id, user_id, content_type, content_id, score, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		recommendation.ID, recommendation.UserID, recommendation.ContentType, recommendation.ContentID,
+		recommendation.Score, recommendation.Reason, recommendation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recommendation: %v", err)
+	}
+	return nil
+}
+
+func (r *pgRecommendationRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.Recommendation, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, content_type, content_id, COALESCE(score, 0),
+		COALESCE(reason, ''), created_at
+		FROM recommendations WHERE user_id = $1 ORDER BY score DESC NULLS LAST LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recommendations: %v", err)
+	}
+	defer rows.Close()
+
+	recommendations := make([]models.Recommendation, 0)
+	for rows.Next() {
+		var recommendation models.Recommendation
+		var createdAt sql.NullTime
+		if err := rows.Scan(&recommendation.ID, &recommendation.UserID, &recommendation.ContentType,
+			&recommendation.ContentID, &recommendation.Score, &recommendation.Reason, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation: %v", err)
+		}
+		recommendation.CreatedAt = createdAt.Time
+		recommendations = append(recommendations, recommendation)
+	}
+	return recommendations, rows.Err()
+}
+
+func (r *pgRecommendationRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM recommendations WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recommendations: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/models"
+)
+
+const nftCouponColumns = `id, user_id, COALESCE(token_id, ''), COALESCE(contract_address, ''), COALESCE(title, ''),
+	COALESCE(description, ''), COALESCE(discount, 0), COALESCE(category, ''), COALESCE(status, ''),
+	minted_at, claimed_at, expires_at`
+
+type pgNFTCouponRepository struct {
+	db dbtx
+}
+
+func scanNFTCoupon(row rowScanner) (*models.NFTCoupon, error) {
+	var coupon models.NFTCoupon
+	var mintedAt, claimedAt, expiresAt sql.NullTime
+	if err := row.Scan(&coupon.ID, &coupon.UserID, &coupon.TokenID, &coupon.ContractAddress, &coupon.Title,
+		&coupon.Description, &coupon.Discount, &coupon.Category, &coupon.Status,
+		&mintedAt, &claimedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	coupon.MintedAt = mintedAt.Time
+	if claimedAt.Valid {
+		coupon.ClaimedAt = &claimedAt.Time
+	}
+	coupon.ExpiresAt = expiresAt.Time
+	return &coupon, nil
+}
+
+func (r *pgNFTCouponRepository) Create(ctx context.Context, coupon *models.NFTCoupon) error {
+	ensureID(&coupon.ID)
+	if coupon.Status == "" {
+		coupon.Status = "minted"
+	}
+	if coupon.MintedAt.IsZero() {
+		coupon.MintedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO nft_coupons
+		(id, user_id, token_id, contract_address, title, description, discount, category, status, minted_at, claimed_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		coupon.ID, coupon.UserID, coupon.TokenID, coupon.ContractAddress, coupon.Title,
+		coupon.Description, coupon.Discount, coupon.Category, coupon.Status,
+		coupon.MintedAt, coupon.ClaimedAt, nullTime(coupon.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to create NFT coupon: %v", err)
+	}
+	return nil
+}
+
+func (r *pgNFTCouponRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.NFTCoupon, error) {
+	coupon, err := scanNFTCoupon(r.db.QueryRowContext(ctx,
+		"SELECT "+nftCouponColumns+" FROM nft_coupons WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return coupon, nil
+}
+
+func (r *pgNFTCouponRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.NFTCoupon, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+nftCouponColumns+" FROM nft_coupons WHERE user_id = $1 ORDER BY minted_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NFT coupons: %v", err)
+	}
+	defer rows.Close()
+
+	coupons := make([]models.NFTCoupon, 0)
+	for rows.Next() {
+		coupon, err := scanNFTCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan NFT coupon: %v", err)
+		}
+		coupons = append(coupons, *coupon)
+	}
+	return coupons, rows.Err()
+}
+
+func (r *pgNFTCouponRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string, claimedAt *time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE nft_coupons SET status = $2, claimed_at = COALESCE($3, claimed_at) WHERE id = $1",
+		id, status, claimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update NFT coupon: %v", err)
+	}
+	return expectRows(result)
+}
+
+type pgNFTActivityRepository struct {
+	db dbtx
+}
+
+func (r *pgNFTActivityRepository) Create(ctx context.Context, activity *models.NFTActivity) error {
+	ensureID(&activity.ID)
+	if activity.Timestamp.IsZero() {
+		activity.Timestamp = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO nft_activities (id, user_id, action, points, timestamp) VALUES ($1, $2, $3, $4, $5)",
+		activity.ID, activity.UserID, activity.Action, activity.Points, activity.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to create NFT activity: %v", err)
+	}
+	return nil
+}
+
+func (r *pgNFTActivityRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.NFTActivity, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, action, COALESCE(points, 0), timestamp FROM nft_activities WHERE user_id = $1 ORDER BY timestamp DESC LIMIT $2",
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NFT activities: %v", err)
+	}
+	defer rows.Close()
+
+	activities := make([]models.NFTActivity, 0)
+	for rows.Next() {
+		var activity models.NFTActivity
+		var timestamp sql.NullTime
+		if err := rows.Scan(&activity.ID, &activity.UserID, &activity.Action, &activity.Points, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan NFT activity: %v", err)
+		}
+		activity.Timestamp = timestamp.Time
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+func (r *pgNFTActivityRepository) TotalPoints(ctx context.Context, userID uuid.UUID) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(points), 0) FROM nft_activities WHERE user_id = $1", userID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum NFT activity points: %v", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/models"
+)
+
+const userColumns = "id, email, name, interests, created_at, updated_at"
+
+type pgUserRepository struct {
+	db dbtx
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var createdAt, updatedAt sql.NullTime
+	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Interests, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	user.CreatedAt = createdAt.Time
+	user.UpdatedAt = updatedAt.Time
+	return &user, nil
+}
+
+func (r *pgUserRepository) Create(ctx context.Context, user *models.User) error {
+	ensureID(&user.ID)
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO users ("+userColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		user.ID, user.Email, user.Name, user.Interests, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %v", err)
+	}
+	return nil
+}
+
+func (r *pgUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
+	user, err := scanUser(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+func (r *pgUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	user, err := scanUser(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+func (r *pgUserRepository) Update(ctx context.Context, user *models.User) error {
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE users SET email = $2, name = $3, interests = $4, updated_at = $5 WHERE id = $1",
+		user.ID, user.Email, user.Name, user.Interests, user.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+	return expectRows(result)
+}
+
+func (r *pgUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+	return expectRows(result)
+}
+
+type pgUserBehaviorRepository struct {
+	db dbtx
+}
+
+func (r *pgUserBehaviorRepository) Create(ctx context.Context, behavior *models.UserBehavior) error {
+	ensureID(&behavior.ID)
+	if behavior.Timestamp.IsZero() {
+		behavior.Timestamp = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO user_behaviors (id, user_id, action, content_id, category, timestamp) VALUES ($1, $2, $3, $4, $5, $6)",
+		behavior.ID, behavior.UserID, behavior.Action, behavior.ContentID, behavior.Category, behavior.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to create user behavior: %v", err)
+	}
+	return nil
+}
+
+func (r *pgUserBehaviorRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.UserBehavior, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, action, content_id, category, timestamp FROM user_behaviors WHERE user_id = $1 ORDER BY timestamp DESC LIMIT $2",
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user behaviors: %v", err)
+	}
+	defer rows.Close()
+
+	behaviors := make([]models.UserBehavior, 0)
+	for rows.Next() {
+		var behavior models.UserBehavior
+		var timestamp sql.NullTime
+		if err := rows.Scan(&behavior.ID, &behavior.UserID, &behavior.Action, &behavior.ContentID, &behavior.Category, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan user behavior: %v", err)
+		}
+		behavior.Timestamp = timestamp.Time
+		behaviors = append(behaviors, behavior)
+	}
+	return behaviors, rows.Err()
+}
+
+type pgUserProfileRepository struct {
+	db dbtx
+}
+
+func (r *pgUserProfileRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error) {
+	var profile models.UserProfile
+	var lastUpdated sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, explicit_interests, behavioral_score, last_updated FROM user_profiles WHERE user_id = $1",
+		userID).Scan(&profile.UserID, &profile.ExplicitInterests, &profile.BehavioralScore, &lastUpdated)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	profile.LastUpdated = lastUpdated.Time
+	return &profile, nil
+}
+
+func (r *pgUserProfileRepository) Upsert(ctx context.Context, profile *models.UserProfile) error {
+	if profile.LastUpdated.IsZero() {
+		profile.LastUpdated = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO user_profiles (user_id, explicit_interests, behavioral_score, last_updated)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			explicit_interests = EXCLUDED.explicit_interests,
+			behavioral_score = EXCLUDED.behavioral_score,
+			last_updated = EXCLUDED.last_updated`,
+		profile.UserID, profile.ExplicitInterests, profile.BehavioralScore, profile.LastUpdated)
+	if err != nil {
+		return fmt.Errorf("failed to upsert user profile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/models"
+)
+
+var ErrNotFound = errors.New("record not found")
+
+// ListOptions filters and pages content listings. An empty Category matches
+// every category and a zero Limit falls back to DefaultLimit.
+type ListOptions struct {
+	Category string
+	Limit    int
+	Offset   int
+}
+
+const DefaultLimit = 20
+
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultLimit
+	}
+	return o.Limit
+}
+
+// UserRepository stores users. Create and Update keep the CreatedAt and
+// UpdatedAt the caller set and stamp the current time where they are zero,
+// so a caller updating a user it loaded clears UpdatedAt first.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type UserBehaviorRepository interface {
+	Create(ctx context.Context, behavior *models.UserBehavior) error
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.UserBehavior, error)
+}
+
+type UserProfileRepository interface {
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error)
+	// Upsert keeps the LastUpdated the caller set, or stamps the current
+	// time if it is zero.
+	Upsert(ctx context.Context, profile *models.UserProfile) error
+}
+
+type NewsArticleRepository interface {
+	Create(ctx context.Context, article *models.NewsArticle) error
+	// Upsert inserts article or refreshes the row with the same URL, keeping
+	// its first_seen_at. It reports whether a new row was created. The other
+	// content repositories behave the same way.
+	Upsert(ctx context.Context, article *models.NewsArticle) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.NewsArticle, error)
+	GetByURL(ctx context.Context, url string) (*models.NewsArticle, error)
+	List(ctx context.Context, opts ListOptions) ([]models.NewsArticle, error)
+	// Search ranks rows matching opts.Query by title and description.
+	Search(ctx context.Context, opts SearchOptions) ([]NewsArticleHit, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type JobListingRepository interface {
+	Create(ctx context.Context, job *models.JobListing) error
+	Upsert(ctx context.Context, job *models.JobListing) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.JobListing, error)
+	GetByURL(ctx context.Context, url string) (*models.JobListing, error)
+	List(ctx context.Context, opts ListOptions) ([]models.JobListing, error)
+	Search(ctx context.Context, opts SearchOptions) ([]JobListingHit, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type VideoRepository interface {
+	Create(ctx context.Context, video *models.Video) error
+	Upsert(ctx context.Context, video *models.Video) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Video, error)
+	GetByURL(ctx context.Context, url string) (*models.Video, error)
+	List(ctx context.Context, opts ListOptions) ([]models.Video, error)
+	Search(ctx context.Context, opts SearchOptions) ([]VideoHit, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type DealRepository interface {
+	Create(ctx context.Context, deal *models.Deal) error
+	Upsert(ctx context.Context, deal *models.Deal) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Deal, error)
+	GetByURL(ctx context.Context, url string) (*models.Deal, error)
+	List(ctx context.Context, opts ListOptions) ([]models.Deal, error)
+	Search(ctx context.Context, opts SearchOptions) ([]DealHit, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type RecommendationRepository interface {
+	Create(ctx context.Context, recommendation *models.Recommendation) error
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.Recommendation, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type NFTCouponRepository interface {
+	Create(ctx context.Context, coupon *models.NFTCoupon) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.NFTCoupon, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.NFTCoupon, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string, claimedAt *time.Time) error
+}
+
+type NFTActivityRepository interface {
+	Create(ctx context.Context, activity *models.NFTActivity) error
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.NFTActivity, error)
+	TotalPoints(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// AuditRepository has no update or delete: the audit log is append-only.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error)
+}
+
+// Repositories bundles one repository per shared model.
+type Repositories struct {
+	Users           UserRepository
+	Behaviors       UserBehaviorRepository
+	Profiles        UserProfileRepository
+	News            NewsArticleRepository
+	Jobs            JobListingRepository
+	Videos          VideoRepository
+	Deals           DealRepository
+	Recommendations RecommendationRepository
+	NFTCoupons      NFTCouponRepository
+	NFTActivities   NFTActivityRepository
+	Audit           AuditRepository
+
+	// db is the connection the repositories were built on, or nil when they
+	// are bound to a transaction.
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// New builds repositories for the dialect of a connection returned by
+// database.SetupDatabase.
+func New(db *sql.DB) *Repositories {
+	dialect := database.DialectOf(db)
+	if dialect == database.Postgres {
+		return NewPostgres(db)
+	}
+
+	// The queries are written for Postgres; SQLite accepts the same
+	// statements once the placeholders are rebound.
+	repos := newRepositories(&rebindingDB{db: db, dialect: dialect}, dialect)
+	repos.db = db
+	return repos
+}
+
+// NewPostgres builds Postgres-backed repositories on a connection returned
+// by database.SetupDatabase.
+func NewPostgres(db *sql.DB) *Repositories {
+	repos := newRepositories(db, database.Postgres)
+	repos.db = db
+	return repos
+}
+
+// InTx calls fn with repositories bound to a new transaction, which is
+// committed if fn returns nil and rolled back otherwise. Repositories that
+// are already bound to a transaction cannot start another.
+func (r *Repositories) InTx(ctx context.Context, fn func(tx *Repositories) error) error {
+	if r.db == nil {
+		return fmt.Errorf("repositories are already bound to a transaction")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	var conn dbtx = tx
+	if r.dialect != database.Postgres {
+		conn = &rebindingDB{db: tx, dialect: r.dialect}
+	}
+	if err := fn(newRepositories(conn, r.dialect)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func newRepositories(db dbtx, dialect database.Dialect) *Repositories {
+	return &Repositories{
+		Users:           &pgUserRepository{db: db},
+		Behaviors:       &pgUserBehaviorRepository{db: db},
+		Profiles:        &pgUserProfileRepository{db: db},
+		News:            &pgNewsArticleRepository{db: db, dialect: dialect},
+		Jobs:            &pgJobListingRepository{db: db, dialect: dialect},
+		Videos:          &pgVideoRepository{db: db, dialect: dialect},
+		Deals:           &pgDealRepository{db: db, dialect: dialect},
+		Recommendations: &pgRecommendationRepository{db: db},
+		NFTCoupons:      &pgNFTCouponRepository{db: db},
+		NFTActivities:   &pgNFTActivityRepository{db: db},
+		Audit:           &pgAuditRepository{db: db},
+		dialect:         dialect,
+	}
+}
+
+// dbtx is the subset of *sql.DB the repositories use.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type rebindingDB struct {
+	db      dbtx
+	dialect database.Dialect
+}
+
+func (r *rebindingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.db.ExecContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r *rebindingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.QueryContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r *rebindingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.db.QueryRowContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func expectRows(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullTime stores a zero time.Time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// markSeen defaults both seen timestamps of a newly created content row to now.
+func markSeen(firstSeenAt, lastSeenAt *time.Time) {
+	now := time.Now()
+	if firstSeenAt.IsZero() {
+		*firstSeenAt = now
+	}
+	if lastSeenAt.IsZero() {
+		*lastSeenAt = *firstSeenAt
+	}
+}
+
+func ensureID(id *uuid.UUID) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/models"
+)
+
+func newTestUser(t *testing.T, repos *Repositories, email string) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Name: "Test", Interests: models.StringList{"tech"}}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create(%s): %v", email, err)
+	}
+	return user
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+	user := newTestUser(t, repos, "alice@example.com")
+
+	got, err := repos.Users.GetByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if got.ID != user.ID || got.Name != "Test" || len(got.Interests) != 1 || got.CreatedAt.IsZero() {
+		t.Errorf("GetByEmail = %+v, want %+v", got, user)
+	}
+
+	user.Name = "Alice"
+	user.Interests = models.StringList{"tech", "travel"}
+	if err := repos.Users.Update(ctx, user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = repos.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "Alice" || len(got.Interests) != 2 {
+		t.Errorf("GetByID after Update = %+v", got)
+	}
+
+	if err := repos.Users.Create(ctx, &models.User{Email: "alice@example.com"}); err == nil {
+		t.Error("Create with a duplicate email succeeded")
+	}
+
+	if err := repos.Users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	missing := []struct {
+		name string
+		call func() error
+	}{
+		{"GetByID", func() error { _, err := repos.Users.GetByID(ctx, user.ID); return err }},
+		{"GetByEmail", func() error { _, err := repos.Users.GetByEmail(ctx, "alice@example.com"); return err }},
+		{"Update", func() error { return repos.Users.Update(ctx, user) }},
+		{"Delete", func() error { return repos.Users.Delete(ctx, user.ID) }},
+		{"Profiles.Get", func() error { _, err := repos.Profiles.Get(ctx, user.ID); return err }},
+		{"NFTCoupons.UpdateStatus", func() error { return repos.NFTCoupons.UpdateStatus(ctx, uuid.New(), "claimed", nil) }},
+	}
+	for _, tc := range missing {
+		if err := tc.call(); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s of a deleted user: err = %v, want ErrNotFound", tc.name, err)
+		}
+	}
+}
+
+func TestBehaviorsAndProfiles(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+	user := newTestUser(t, repos, "bob@example.com")
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, action := range []string{"click", "bookmark", "share"} {
+		behavior := &models.UserBehavior{UserID: user.ID, Action: action, ContentID: "c1", Category: "tech", Timestamp: start.Add(time.Duration(i) * time.Hour)}
+		if err := repos.Behaviors.Create(ctx, behavior); err != nil {
+			t.Fatalf("Behaviors.Create(%s): %v", action, err)
+		}
+	}
+
+	behaviors, err := repos.Behaviors.ListByUser(ctx, user.ID, 2)
+	if err != nil {
+		t.Fatalf("Behaviors.ListByUser: %v", err)
+	}
+	if len(behaviors) != 2 || behaviors[0].Action != "share" || behaviors[1].Action != "bookmark" {
+		t.Errorf("Behaviors.ListByUser = %+v, want the latest two, newest first", behaviors)
+	}
+	if !behaviors[0].Timestamp.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Timestamp = %v, want %v", behaviors[0].Timestamp, start.Add(2*time.Hour))
+	}
+
+	profile := &models.UserProfile{UserID: user.ID, ExplicitInterests: models.StringList{"go"}, BehavioralScore: models.ScoreMap{"tech": 1}}
+	if err := repos.Profiles.Upsert(ctx, profile); err != nil {
+		t.Fatalf("Profiles.Upsert: %v", err)
+	}
+	profile.BehavioralScore = models.ScoreMap{"tech": 2.5}
+	if err := repos.Profiles.Upsert(ctx, profile); err != nil {
+		t.Fatalf("Profiles.Upsert again: %v", err)
+	}
+	got, err := repos.Profiles.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Profiles.Get: %v", err)
+	}
+	if got.BehavioralScore["tech"] != 2.5 || len(got.ExplicitInterests) != 1 {
+		t.Errorf("Profiles.Get = %+v", got)
+	}
+
+	if err := repos.Users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Users.Delete: %v", err)
+	}
+	if behaviors, _ := repos.Behaviors.ListByUser(ctx, user.ID, 0); len(behaviors) != 0 {
+		t.Errorf("behaviors of a deleted user = %d, want 0", len(behaviors))
+	}
+}
+
+func TestContentUpsert(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+
+	first := &models.NewsArticle{Title: "First", URL: "https://example.com/a", Category: "tech"}
+	created, err := repos.News.Upsert(ctx, first)
+	if err != nil || !created {
+		t.Fatalf("Upsert new = %v, %v; want true, nil", created, err)
+	}
+
+	second := &models.NewsArticle{Title: "Second", URL: "https://example.com/a"}
+	created, err = repos.News.Upsert(ctx, second)
+	if err != nil || created {
+		t.Fatalf("Upsert existing = %v, %v; want false, nil", created, err)
+	}
+	if second.ID != first.ID || !second.FirstSeenAt.Equal(first.FirstSeenAt) {
+		t.Errorf("Upsert existing kept id %s and first seen %v, want %s and %v", second.ID, second.FirstSeenAt, first.ID, first.FirstSeenAt)
+	}
+
+	got, err := repos.News.GetByURL(ctx, "https://example.com/a")
+	if err != nil {
+		t.Fatalf("GetByURL: %v", err)
+	}
+	if got.Title != "Second" || got.Category != "tech" {
+		t.Errorf("GetByURL = %+v, want the new title and the old category", got)
+	}
+
+	for _, article := range []*models.NewsArticle{
+		{Title: "B", URL: "https://example.com/b", Category: "business"},
+		{Title: "C", URL: "https://example.com/c", Category: "tech"},
+	} {
+		if err := repos.News.Create(ctx, article); err != nil {
+			t.Fatalf("Create(%s): %v", article.URL, err)
+		}
+	}
+
+	tests := []struct {
+		opts ListOptions
+		want int
+	}{
+		{ListOptions{}, 3},
+		{ListOptions{Category: "tech"}, 2},
+		{ListOptions{Category: "business"}, 1},
+		{ListOptions{Limit: 1}, 1},
+		{ListOptions{Offset: 2}, 1},
+	}
+	for _, tc := range tests {
+		articles, err := repos.News.List(ctx, tc.opts)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", tc.opts, err)
+		}
+		if len(articles) != tc.want {
+			t.Errorf("List(%+v) returned %d articles, want %d", tc.opts, len(articles), tc.want)
+		}
+	}
+}
+
+func TestNFTsAndAudit(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+	user := newTestUser(t, repos, "carol@example.com")
+
+	coupon := &models.NFTCoupon{UserID: user.ID, Title: "10% off", Discount: 10, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repos.NFTCoupons.Create(ctx, coupon); err != nil {
+		t.Fatalf("NFTCoupons.Create: %v", err)
+	}
+	claimedAt := time.Now().UTC().Truncate(time.Second)
+	if err := repos.NFTCoupons.UpdateStatus(ctx, coupon.ID, "claimed", &claimedAt); err != nil {
+		t.Fatalf("NFTCoupons.UpdateStatus: %v", err)
+	}
+	got, err := repos.NFTCoupons.GetByID(ctx, coupon.ID)
+	if err != nil {
+		t.Fatalf("NFTCoupons.GetByID: %v", err)
+	}
+	if got.Status != "claimed" || got.ClaimedAt == nil || !got.ClaimedAt.Equal(claimedAt) {
+		t.Errorf("NFTCoupons.GetByID = %+v, want claimed at %v", got, claimedAt)
+	}
+
+	for _, points := range []int{10, 25} {
+		if err := repos.NFTActivities.Create(ctx, &models.NFTActivity{UserID: user.ID, Action: "engagement", Points: points}); err != nil {
+			t.Fatalf("NFTActivities.Create: %v", err)
+		}
+	}
+	if total, err := repos.NFTActivities.TotalPoints(ctx, user.ID); err != nil || total != 35 {
+		t.Errorf("TotalPoints = %d, %v; want 35", total, err)
+	}
+
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, userID := range []string{"u1", "u2", "u1"} {
+		entry := &models.AuditEntry{
+			OccurredAt: base.Add(time.Duration(i) * time.Hour),
+			Actor:      userID,
+			Action:     "user.update",
+			TargetType: "user",
+			TargetID:   userID,
+			UserID:     userID,
+			Changes:    models.AuditDiff{"name": {Before: "old", After: "new"}},
+		}
+		if err := repos.Audit.Append(ctx, entry); err != nil {
+			t.Fatalf("Audit.Append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   int
+	}{
+		{"all", AuditFilter{}, 3},
+		{"by user", AuditFilter{UserID: "u1"}, 2},
+		{"since is inclusive", AuditFilter{Since: base.Add(time.Hour)}, 2},
+		{"until is exclusive", AuditFilter{Until: base.Add(time.Hour)}, 1},
+		{"paged", AuditFilter{Limit: 1, Offset: 1}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := repos.Audit.List(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(entries) != tc.want {
+				t.Fatalf("List returned %d entries, want %d", len(entries), tc.want)
+			}
+			if entries[0].Changes["name"].After != "new" {
+				t.Errorf("Changes = %+v, want the stored diff", entries[0].Changes)
+			}
+		})
+	}
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+	failure := errors.New("failure")
+
+	tests := []struct {
+		email string
+		err   error
+		want  error
+	}{
+		{"committed@example.com", nil, nil},
+		{"rolled-back@example.com", failure, ErrNotFound},
+	}
+	for _, tc := range tests {
+		err := repos.InTx(ctx, func(tx *Repositories) error {
+			if err := tx.Users.Create(ctx, &models.User{Email: tc.email}); err != nil {
+				return err
+			}
+			if err := tx.InTx(ctx, func(*Repositories) error { return nil }); err == nil {
+				t.Error("nested InTx succeeded")
+			}
+			return tc.err
+		})
+		if err != tc.err {
+			t.Errorf("InTx(%s) = %v, want %v", tc.email, err, tc.err)
+		}
+		if _, err := repos.Users.GetByEmail(ctx, tc.email); err != tc.want {
+			t.Errorf("GetByEmail(%s) after InTx = %v, want %v", tc.email, err, tc.want)
+		}
+	}
+}
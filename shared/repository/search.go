@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/models"
+)
+
+// SearchOptions configures a full-text search over one content table. An
+// empty Category searches every category.
+type SearchOptions struct {
+	Query    string
+	Category string
+	Limit    int
+	Offset   int
+}
+
+func (o SearchOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultLimit
+	}
+	return o.Limit
+}
+
+// SearchHit is the ranking data added to every search result. Snippet is an
+// HTML-escaped excerpt with the matched terms wrapped in <mark> tags.
+type SearchHit struct {
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type NewsArticleHit struct {
+	models.NewsArticle
+	SearchHit
+}
+
+type JobListingHit struct {
+	models.JobListing
+	SearchHit
+}
+
+type VideoHit struct {
+	models.Video
+	SearchHit
+}
+
+type DealHit struct {
+	models.Deal
+	SearchHit
+}
+
+// startSel and stopSel delimit the matches in a ts_headline snippet until it
+// is escaped; they are private-use characters, which provider text does not
+// contain.
+const (
+	startSel = "\uE000"
+	stopSel  = "\uE001"
+)
+
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", MaxWords=35, MinWords=15, MaxFragments=2"
+
+// wordSeparators are the characters SQLite searches treat as spaces, as SQL
+// expressions.
+var wordSeparators = []string{
+	"'.'", "','", "';'", "':'", "'!'", "'?'", "'('", "')'", "'['", "']'",
+	`'"'`, `''''`, "'/'", "'-'", "char(9)", "char(10)", "char(13)",
+}
+
+// searchQuery builds the search SELECT for one content table, returning the
+// table's columns followed by the rank and snippet. Postgres ranks the
+// search_vector column against a websearch_to_tsquery and builds the snippet
+// with ts_headline. SQLite has no full-text column, so it falls back to a
+// case-insensitive whole-word match ranking title matches first, and returns
+// the raw text for highlight to mark up.
+func searchQuery(dialect database.Dialect, table, columns, filter, orderBy string, opts SearchOptions) (string, []interface{}) {
+	var query string
+	args := make([]interface{}, 0, 4)
+
+	if dialect == database.SQLite {
+		args = append(args, "% "+escapeLike(searchWords(opts.Query))+" %")
+		title, description := sqliteWords("title"), sqliteWords("description")
+		query = `SELECT ` + columns + `,
+			(CASE WHEN ` + title + ` LIKE $1 ESCAPE '\' THEN 2 ELSE 0 END) +
+			(CASE WHEN ` + description + ` LIKE $1 ESCAPE '\' THEN 1 ELSE 0 END) AS search_rank,
+			COALESCE(NULLIF(description, ''), title)
+			FROM ` + table + `
+			WHERE (` + title + ` LIKE $1 ESCAPE '\' OR ` + description + ` LIKE $1 ESCAPE '\')`
+	} else {
+		args = append(args, opts.Query)
+		query = `SELECT ` + columns + `,
+			ts_rank(search_vector, search_query) AS search_rank,
+			ts_headline('english', COALESCE(NULLIF(description, ''), title), search_query, '` + headlineOptions + `')
+			FROM ` + table + `, websearch_to_tsquery('english', $1) AS search_query
+			WHERE search_vector @@ search_query`
+	}
+
+	if filter != "" {
+		query += " AND " + filter
+	}
+	if opts.Category != "" {
+		args = append(args, opts.Category)
+		query += fmt.Sprintf(" AND category = $%d", len(args))
+	}
+
+	args = append(args, opts.limit(), opts.Offset)
+	query += fmt.Sprintf(" ORDER BY search_rank DESC, %s LIMIT $%d OFFSET $%d", orderBy, len(args)-1, len(args))
+	return query, args
+}
+
+// hitScanner appends the rank and snippet columns to every Scan call, so the
+// regular scan functions can read search rows.
+type hitScanner struct {
+	row rowScanner
+	hit *SearchHit
+}
+
+func (s hitScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, &s.hit.Rank, &s.hit.Snippet)...)
+}
+
+// sqliteWords pads a column with spaces and turns the word separators into
+// spaces, so that LIKE '% term %' only matches whole words.
+func sqliteWords(column string) string {
+	expr := column
+	for _, separator := range wordSeparators {
+		expr = "replace(" + expr + ", " + separator + ", ' ')"
+	}
+	return "(' ' || " + expr + " || ' ')"
+}
+
+// searchWords normalizes a query the way sqliteWords normalizes a column.
+func searchWords(query string) string {
+	words := strings.Fields(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`.,;:!?()[]"'/-`, r) {
+			return ' '
+		}
+		return r
+	}, query))
+	if len(words) == 0 {
+		return strings.TrimSpace(query)
+	}
+	return strings.Join(words, " ")
+}
+
+// finishHit builds the SQLite snippet and escapes the Postgres one.
+func finishHit(dialect database.Dialect, hit *SearchHit, query string) {
+	if dialect == database.SQLite {
+		hit.Snippet = highlight(hit.Snippet, strings.TrimSpace(query))
+		return
+	}
+	hit.Snippet = strings.NewReplacer(startSel, "<mark>", stopSel, "</mark>").Replace(html.EscapeString(hit.Snippet))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+const snippetLength = 200
+
+// highlight cuts an excerpt of text around the first whole-word occurrence
+// of term, escapes it for HTML and wraps every whole-word occurrence in
+// <mark> tags, like ts_headline does on Postgres.
+func highlight(text, term string) string {
+	lower := strings.ToLower(text)
+	lowerTerm := strings.ToLower(term)
+	if term == "" || len(lower) != len(text) {
+		return html.EscapeString(truncate(text, 0, snippetLength))
+	}
+
+	start := 0
+	if first := indexWord(lower, lowerTerm); first > snippetLength/3 {
+		start = first - snippetLength/3
+	}
+	excerpt := truncate(text, start, snippetLength)
+	lowerExcerpt := strings.ToLower(excerpt)
+
+	var marked strings.Builder
+	for {
+		i := indexWord(lowerExcerpt, lowerTerm)
+		if i < 0 {
+			marked.WriteString(html.EscapeString(excerpt))
+			break
+		}
+		marked.WriteString(html.EscapeString(excerpt[:i]))
+		marked.WriteString("<mark>" + html.EscapeString(excerpt[i:i+len(term)]) + "</mark>")
+		excerpt = excerpt[i+len(term):]
+		lowerExcerpt = lowerExcerpt[i+len(term):]
+	}
+	return marked.String()
+}
+
+// indexWord returns the index of the first occurrence of term in s that is
+// not part of a longer word, or -1.
+func indexWord(s, term string) int {
+	for offset := 0; offset < len(s); {
+		i := strings.Index(s[offset:], term)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if wordBoundary(s, i) && wordBoundary(s, i+len(term)) {
+			return i
+		}
+		offset = i + 1
+	}
+	return -1
+}
+
+// wordBoundary reports whether position i of s is not between two letters
+// or digits.
+func wordBoundary(s string, i int) bool {
+	if i == 0 || i == len(s) {
+		return true
+	}
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i:])
+	return !isWordRune(before) || !isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// truncate returns up to length bytes of text from start, moved onto rune
+// boundaries and marked with an ellipsis where text was cut.
+func truncate(text string, start, length int) string {
+	for start > 0 && start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	end := start + length
+	if end >= len(text) {
+		end = len(text)
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	excerpt := text[start:end]
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(text) {
+		excerpt += "…"
+	}
+	return excerpt
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"personalized-dashboard/shared/database"
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/models"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		term string
+		want string
+	}{
+		{"marks every match", "AI beats AI", "ai", "<mark>AI</mark> beats <mark>AI</mark>"},
+		{"whole words only", "Retailers bet on AI", "AI", "Retailers bet on <mark>AI</mark>"},
+		{"punctuation is a boundary", "(AI), AI-powered", "ai", "(<mark>AI</mark>), <mark>AI</mark>-powered"},
+		{"no match", "retailers", "ai", "retailers"},
+		{"escapes text", `<script>alert("AI")</script>`, "ai", "&lt;script&gt;alert(&#34;<mark>AI</mark>&#34;)&lt;/script&gt;"},
+		{"escapes the match", "Tom & Jerry", "&", "Tom <mark>&amp;</mark> Jerry"},
+		{"empty term", "<b>", "", "&lt;b&gt;"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := highlight(tc.text, tc.term); got != tc.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tc.text, tc.term, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHighlightExcerpt(t *testing.T) {
+	text := strings.Repeat("word ", 100) + "golang " + strings.Repeat("word ", 100)
+	got := highlight(text, "golang")
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "<mark>golang</mark>") {
+		t.Errorf("highlight = %q, want an excerpt around the match", got)
+	}
+}
+
+func TestFinishHitPostgres(t *testing.T) {
+	hit := SearchHit{Snippet: "a <b> " + startSel + "Go" + stopSel + " & more"}
+	finishHit(database.Postgres, &hit, "go")
+	if want := "a &lt;b&gt; <mark>Go</mark> &amp; more"; hit.Snippet != want {
+		t.Errorf("Snippet = %q, want %q", hit.Snippet, want)
+	}
+}
+
+func TestSearchSQLite(t *testing.T) {
+	ctx := context.Background()
+	repos := New(dbtest.SQLite(t))
+
+	for _, article := range []*models.NewsArticle{
+		{Title: "AI chips are here", URL: "https://example.com/ai", Category: "technology"},
+		{Title: "Retailers report record sales", URL: "https://example.com/retail", Category: "business"},
+		{Title: "Markets", Description: "Investors bet on AI, again.", URL: "https://example.com/markets", Category: "business"},
+		{Title: "Node.js 22 released", URL: "https://example.com/node", Category: "technology"},
+	} {
+		if err := repos.News.Create(ctx, article); err != nil {
+			t.Fatalf("Create(%s): %v", article.URL, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want []string
+	}{
+		{"whole words, title first", SearchOptions{Query: "ai"}, []string{"https://example.com/ai", "https://example.com/markets"}},
+		{"category", SearchOptions{Query: "AI", Category: "business"}, []string{"https://example.com/markets"}},
+		{"punctuation in the query", SearchOptions{Query: "node.js"}, []string{"https://example.com/node"}},
+		{"phrase", SearchOptions{Query: "record sales"}, []string{"https://example.com/retail"}},
+		{"like wildcards are literal", SearchOptions{Query: "%"}, nil},
+		{"no match", SearchOptions{Query: "tail"}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hits, err := repos.News.Search(ctx, tc.opts)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got := make([]string, 0, len(hits))
+			for _, hit := range hits {
+				got = append(got, hit.URL)
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Search(%q) = %v, want %v", tc.opts.Query, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package resilience keeps one slow or failing upstream from dragging the
+// gateway down: pooled connections, bounded retries with jittered backoff
+// and a circuit breaker per upstream.
+package resilience
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	// StateClosed lets every request through.
+	StateClosed State = "closed"
+	// StateOpen fails requests fast until the cooldown has passed.
+	StateOpen State = "open"
+	// StateHalfOpen lets a single probe through; its outcome closes or
+	// reopens the breaker.
+	StateHalfOpen State = "half-open"
+)
+
+// OpenError is returned instead of calling an upstream whose breaker is open.
+type OpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.Upstream)
+}
+
+// Breaker opens after Threshold consecutive failures and stays open for
+// Cooldown. After that one probe request is let through: success closes the
+// breaker, failure opens it for another cooldown.
+type Breaker struct {
+	name string
+
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     State
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+
+	// Counters for the admin endpoint
+	successes int64
+	errors    int64
+	rejected  int64
+	opened    int64
+}
+
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown, state: StateClosed, now: time.Now}
+}
+
+// Configure changes the threshold and cooldown, keeping the current state.
+func (b *Breaker) Configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// Allow reports whether a request may go to the upstream. Every allowed
+// request must be followed by Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if wait := b.openedAt.Add(b.cooldown).Sub(b.now()); wait > 0 {
+			b.rejected++
+			return &OpenError{Upstream: b.name, RetryAfter: wait}
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			b.rejected++
+			return &OpenError{Upstream: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.successes++
+	b.failures = 0
+	b.probing = false
+	b.state = StateClosed
+}
+
+// Cancel ends an allowed request without an outcome, e.g. when the client
+// went away; a half-open breaker will let the next probe through.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.errors++
+	b.failures++
+	b.probing = false
+	if b.state == StateHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		if b.state != StateOpen {
+			b.opened++
+		}
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// BreakerStatus is a snapshot of a breaker for the admin endpoint.
+type BreakerStatus struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+	Threshold           int        `json:"threshold"`
+	Cooldown            string     `json:"cooldown"`
+	Successes           int64      `json:"successes"`
+	Errors              int64      `json:"errors"`
+	Rejected            int64      `json:"rejected"`
+	TimesOpened         int64      `json:"times_opened"`
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Threshold:           b.threshold,
+		Cooldown:            b.cooldown.String(),
+		Successes:           b.successes,
+		Errors:              b.errors,
+		Rejected:            b.rejected,
+		TimesOpened:         b.opened,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt.UTC()
+		retryAt := openedAt.Add(b.cooldown)
+		status.OpenedAt, status.RetryAt = &openedAt, &retryAt
+	}
+	return status
+}
@@ -0,0 +1,134 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		call      string // "allow", "success", "failure" or "cancel"
+		wantErr   bool
+		wantState State
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after the threshold",
+			steps: []step{
+				{call: "allow", wantState: StateClosed},
+				{call: "failure", wantState: StateClosed},
+				{call: "allow", wantState: StateClosed},
+				{call: "failure", wantState: StateClosed},
+				{call: "allow", wantState: StateClosed},
+				{call: "failure", wantState: StateOpen},
+				{call: "allow", wantErr: true, wantState: StateOpen},
+			},
+		},
+		{
+			name: "success resets the failure count",
+			steps: []step{
+				{call: "failure", wantState: StateClosed},
+				{call: "failure", wantState: StateClosed},
+				{call: "success", wantState: StateClosed},
+				{call: "failure", wantState: StateClosed},
+				{call: "failure", wantState: StateClosed},
+				{call: "allow", wantState: StateClosed},
+			},
+		},
+		{
+			name: "probe after the cooldown closes",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", wantState: StateOpen},
+				{advance: 9 * time.Second, call: "allow", wantErr: true, wantState: StateOpen},
+				{advance: time.Second, call: "allow", wantState: StateHalfOpen},
+				{call: "allow", wantErr: true, wantState: StateHalfOpen},
+				{call: "success", wantState: StateClosed},
+				{call: "allow", wantState: StateClosed},
+			},
+		},
+		{
+			name: "failed probe reopens",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", wantState: StateOpen},
+				{advance: 10 * time.Second, call: "allow", wantState: StateHalfOpen},
+				{call: "failure", wantState: StateOpen},
+				{advance: 5 * time.Second, call: "allow", wantErr: true, wantState: StateOpen},
+				{advance: 5 * time.Second, call: "allow", wantState: StateHalfOpen},
+			},
+		},
+		{
+			name: "cancelled probe lets the next one through",
+			steps: []step{
+				{call: "failure"}, {call: "failure"}, {call: "failure", wantState: StateOpen},
+				{advance: 10 * time.Second, call: "allow", wantState: StateHalfOpen},
+				{call: "cancel", wantState: StateHalfOpen},
+				{call: "allow", wantState: StateHalfOpen},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			b := NewBreaker("news", 3, 10*time.Second)
+			b.now = func() time.Time { return now }
+
+			for i, s := range tc.steps {
+				now = now.Add(s.advance)
+				var err error
+				switch s.call {
+				case "allow":
+					err = b.Allow()
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "cancel":
+					b.Cancel()
+				}
+
+				if (err != nil) != s.wantErr {
+					t.Fatalf("step %d: %s error = %v, want error %v", i, s.call, err, s.wantErr)
+				}
+				var open *OpenError
+				if err != nil && (!errors.As(err, &open) || open.Upstream != "news" || open.RetryAfter <= 0) {
+					t.Errorf("step %d: error = %#v, want an OpenError with a retry delay", i, err)
+				}
+				if s.wantState != "" && b.Status().State != s.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, b.Status().State, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerStatus(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := NewBreaker("news", 1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Allow()
+	b.Success()
+	b.Allow()
+	b.Failure()
+	b.Allow()
+
+	status := b.Status()
+	if status.State != StateOpen || status.Successes != 1 || status.Errors != 1 || status.Rejected != 1 || status.TimesOpened != 1 {
+		t.Errorf("status = %+v, want one success, error, rejection and opening", status)
+	}
+	if status.OpenedAt == nil || !status.RetryAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("retry at = %v, want %v", status.RetryAt, now.Add(time.Minute))
+	}
+
+	b.Configure(5, time.Second)
+	if status := b.Status(); status.State != StateOpen || status.Threshold != 5 || status.Cooldown != "1s" {
+		t.Errorf("status after Configure = %+v, want the state kept and the new settings", status)
+	}
+}
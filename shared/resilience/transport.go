@@ -0,0 +1,175 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Policy is the resilience policy of one upstream.
+type Policy struct {
+	// Retries is how often a failed idempotent request is retried.
+	Retries int
+	// Backoff is the base of the exponential backoff between retries; each
+	// wait is a random duration up to Backoff * 2^attempt, capped at
+	// MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// FailureThreshold consecutive failures open the breaker for Cooldown.
+	FailureThreshold int
+	Cooldown         time.Duration
+	// MaxIdleConns is the size of the upstream's idle connection pool.
+	MaxIdleConns int
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Retries:          2,
+		Backoff:          100 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+		MaxIdleConns:     32,
+	}
+}
+
+// Transport is the http.RoundTripper of one upstream. It keeps a pool of
+// connections to the upstream, retries idempotent requests that fail with a
+// network error or a 502, 503 or 504, and fails fast while the upstream's
+// breaker is open. The request deadline comes from the request context.
+type Transport struct {
+	name    string
+	base    *http.Transport
+	breaker *Breaker
+	policy  atomic.Pointer[Policy]
+}
+
+func NewTransport(name string, policy Policy) *Transport {
+	t := &Transport{
+		name: name,
+		base: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          policy.MaxIdleConns,
+			MaxIdleConnsPerHost:   policy.MaxIdleConns,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		breaker: NewBreaker(name, policy.FailureThreshold, policy.Cooldown),
+	}
+	t.policy.Store(&policy)
+	return t
+}
+
+// SetPolicy replaces the policy, keeping the pool and the breaker state.
+// The pool size only changes for connections opened afterwards.
+func (t *Transport) SetPolicy(policy Policy) {
+	t.policy.Store(&policy)
+	t.breaker.Configure(policy.FailureThreshold, policy.Cooldown)
+}
+
+func (t *Transport) Breaker() *Breaker {
+	return t.breaker
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.policy.Load()
+	retries := 0
+	if retryable(req) {
+		retries = policy.Retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		failed := err != nil || isUpstreamFailure(resp.StatusCode)
+		if !failed {
+			t.breaker.Success()
+			return resp, nil
+		}
+
+		// The client giving up is not the upstream's fault
+		if err != nil && errors.Is(req.Context().Err(), context.Canceled) {
+			t.breaker.Cancel()
+			return nil, err
+		}
+		t.breaker.Failure()
+
+		if attempt >= retries || !sleep(req.Context(), backoff(policy, attempt)) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+	}
+}
+
+// retryable reports whether req may be sent again: its method must be
+// idempotent and its body, if any, replayable.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func isUpstreamFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// backoff returns a random wait up to Backoff * 2^attempt ("full jitter"),
+// so that clients retrying together spread out.
+func backoff(policy *Policy, attempt int) time.Duration {
+	ceiling := policy.Backoff << attempt
+	if ceiling <= 0 || ceiling > policy.MaxBackoff {
+		ceiling = policy.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// sleep waits for d unless ctx ends first, or would end before d is over.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// CloseIdleConnections empties the connection pool.
+func (t *Transport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
@@ -0,0 +1,205 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPolicy retries without noticeable waits.
+func testPolicy() Policy {
+	return Policy{
+		Retries:          2,
+		Backoff:          time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		FailureThreshold: 10,
+		Cooldown:         time.Minute,
+		MaxIdleConns:     4,
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{"success", http.MethodGet, "", []int{200}, 200, 1},
+		{"retries 502", http.MethodGet, "", []int{502, 200}, 200, 2},
+		{"retries 503", http.MethodGet, "", []int{503, 503, 200}, 200, 3},
+		{"gives up after the retries", http.MethodGet, "", []int{504, 504, 504, 200}, 504, 3},
+		{"no retry for 500", http.MethodGet, "", []int{500, 200}, 500, 1},
+		{"no retry for POST", http.MethodPost, "{}", []int{502, 200}, 502, 1},
+		{"retries PUT with its body", http.MethodPut, `{"a":1}`, []int{503, 200}, 200, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				if body, _ := io.ReadAll(r.Body); string(body) != tc.body {
+					t.Errorf("attempt %d: body = %q, want %q", n, body, tc.body)
+				}
+				w.WriteHeader(tc.statuses[n-1])
+			}))
+			defer upstream.Close()
+
+			transport := NewTransport("api", testPolicy())
+			defer transport.CloseIdleConnections()
+
+			req, _ := http.NewRequest(tc.method, upstream.URL, strings.NewReader(tc.body))
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.wantStatus || attempts.Load() != tc.wantAttempts {
+				t.Errorf("status %d after %d attempts, want %d after %d", resp.StatusCode, attempts.Load(), tc.wantStatus, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestTransportBreaker(t *testing.T) {
+	var attempts atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	policy := testPolicy()
+	policy.Retries = 0
+	policy.FailureThreshold = 2
+	transport := NewTransport("api", policy)
+	defer transport.CloseIdleConnections()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+	_, err := transport.RoundTrip(req)
+	var open *OpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("error = %v, want an OpenError once the threshold is reached", err)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("upstream called %d times, want the open breaker to fail fast", attempts.Load())
+	}
+}
+
+func TestTransportClientCancel(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	policy := testPolicy()
+	policy.FailureThreshold = 1
+	transport := NewTransport("api", policy)
+	defer transport.CloseIdleConnections()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("RoundTrip succeeded, want the cancellation error")
+	}
+
+	if status := transport.Breaker().Status(); status.State != StateClosed || status.Errors != 0 {
+		t.Errorf("breaker = %+v, want a client cancellation not counted against the upstream", status)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	withBody := func(method string) *http.Request {
+		req, _ := http.NewRequest(method, "http://api", strings.NewReader("x"))
+		return req
+	}
+	unreplayable := withBody(http.MethodPut)
+	unreplayable.GetBody = nil
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want bool
+	}{
+		{"GET", httptest.NewRequest(http.MethodGet, "/", nil), true},
+		{"HEAD", httptest.NewRequest(http.MethodHead, "/", nil), true},
+		{"DELETE", httptest.NewRequest(http.MethodDelete, "/", nil), true},
+		{"PUT with a replayable body", withBody(http.MethodPut), true},
+		{"PUT with a one-shot body", unreplayable, false},
+		{"POST", withBody(http.MethodPost), false},
+		{"PATCH", withBody(http.MethodPatch), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := retryable(tc.req); got != tc.want {
+				t.Errorf("retryable = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &Policy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{70, time.Second}, // the shift overflows
+	}
+	for _, tc := range tests {
+		for i := 0; i < 100; i++ {
+			if d := backoff(policy, tc.attempt); d < 0 || d > tc.ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [0, %v]", tc.attempt, d, tc.ceiling)
+			}
+		}
+	}
+
+	if d := backoff(&Policy{}, 2); d != 0 {
+		t.Errorf("backoff without a policy = %v, want 0", d)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if !sleep(context.Background(), time.Millisecond) {
+		t.Error("sleep = false, want true without a deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if sleep(ctx, time.Second) || time.Since(start) > 500*time.Millisecond {
+		t.Error("sleep past the deadline should return false right away")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sleep(cancelled, time.Second) {
+		t.Error("sleep = true, want false for a cancelled context")
+	}
+}
@@ -0,0 +1,99 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type Action string
+
+const (
+	// ActionOff leaves the table alone.
+	ActionOff Action = "off"
+	// ActionExpire marks unclaimed coupons past expires_at as expired.
+	ActionExpire Action = "expire"
+	// ActionArchive hides deals past valid_until from listings and search.
+	ActionArchive Action = "archive"
+	// ActionRollup folds old behavior events into daily aggregates and
+	// deletes them.
+	ActionRollup Action = "rollup"
+	// ActionDelete removes the rows outright.
+	ActionDelete Action = "delete"
+)
+
+// Policy says what the sweeper does with the rows of one table once their
+// time column is older than After.
+type Policy struct {
+	Action Action
+	After  time.Duration
+}
+
+// Policies holds one policy per swept table.
+type Policies struct {
+	Coupons   Policy
+	Deals     Policy
+	Behaviors Policy
+}
+
+// allowedActions lists the actions that make sense for each table.
+var allowedActions = map[string][]Action{
+	"coupons":   {ActionOff, ActionExpire, ActionDelete},
+	"deals":     {ActionOff, ActionArchive, ActionDelete},
+	"behaviors": {ActionOff, ActionRollup, ActionDelete},
+}
+
+// DefaultPolicies expires coupons and archives deals as soon as they lapse
+// and rolls up behavior events after 90 days.
+func DefaultPolicies() Policies {
+	return Policies{
+		Coupons:   Policy{Action: ActionExpire},
+		Deals:     Policy{Action: ActionArchive},
+		Behaviors: Policy{Action: ActionRollup, After: 90 * 24 * time.Hour},
+	}
+}
+
+// PoliciesFromEnv starts from DefaultPolicies and applies the
+// RETENTION_<TABLE>_ACTION and RETENTION_<TABLE>_AFTER overrides, e.g.
+// RETENTION_DEALS_ACTION=delete and RETENTION_DEALS_AFTER=720h.
+func PoliciesFromEnv() (Policies, error) {
+	policies := DefaultPolicies()
+
+	for table, policy := range map[string]*Policy{
+		"coupons":   &policies.Coupons,
+		"deals":     &policies.Deals,
+		"behaviors": &policies.Behaviors,
+	} {
+		prefix := "RETENTION_" + strings.ToUpper(table)
+
+		if value := os.Getenv(prefix + "_ACTION"); value != "" {
+			policy.Action = Action(strings.ToLower(value))
+		}
+		if value := os.Getenv(prefix + "_AFTER"); value != "" {
+			after, err := time.ParseDuration(value)
+			if err != nil {
+				return policies, fmt.Errorf("invalid %s_AFTER: %v", prefix, err)
+			}
+			policy.After = after
+		}
+
+		if err := policy.validate(table); err != nil {
+			return policies, err
+		}
+	}
+
+	return policies, nil
+}
+
+func (p Policy) validate(table string) error {
+	if p.After < 0 {
+		return fmt.Errorf("retention period for %s must not be negative", table)
+	}
+	for _, action := range allowedActions[table] {
+		if p.Action == action {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported retention action %q for %s", p.Action, table)
+}
@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoliciesFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Policies
+		wantErr bool
+	}{
+		{name: "defaults", want: DefaultPolicies()},
+		{
+			name: "overrides",
+			env:  map[string]string{"RETENTION_DEALS_ACTION": "DELETE", "RETENTION_DEALS_AFTER": "720h", "RETENTION_COUPONS_ACTION": "off"},
+			want: Policies{
+				Coupons:   Policy{Action: ActionOff},
+				Deals:     Policy{Action: ActionDelete, After: 720 * time.Hour},
+				Behaviors: DefaultPolicies().Behaviors,
+			},
+		},
+		{name: "action of another table", env: map[string]string{"RETENTION_DEALS_ACTION": "rollup"}, wantErr: true},
+		{name: "unknown action", env: map[string]string{"RETENTION_BEHAVIORS_ACTION": "shred"}, wantErr: true},
+		{name: "bad duration", env: map[string]string{"RETENTION_BEHAVIORS_AFTER": "90 days"}, wantErr: true},
+		{name: "negative duration", env: map[string]string{"RETENTION_BEHAVIORS_AFTER": "-1h"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, table := range []string{"COUPONS", "DEALS", "BEHAVIORS"} {
+				t.Setenv("RETENTION_"+table+"_ACTION", "")
+				t.Setenv("RETENTION_"+table+"_AFTER", "")
+			}
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+
+			got, err := PoliciesFromEnv()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("PoliciesFromEnv() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PoliciesFromEnv: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("PoliciesFromEnv() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Package retention applies the expiry and retention policies for
+// time-bound rows: lapsed NFT coupons, deals past valid_until and old
+// user behavior events.
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/database"
+)
+
+// Change is one action the sweeper applied to a table.
+type Change struct {
+	Table  string `json:"table"`
+	Action Action `json:"action"`
+	Rows   int64  `json:"rows"`
+}
+
+// Report lists what a single sweep changed.
+type Report struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Changes   []Change  `json:"changes"`
+}
+
+func (r *Report) String() string {
+	if len(r.Changes) == 0 {
+		return "nothing to do"
+	}
+
+	parts := make([]string, 0, len(r.Changes))
+	for _, change := range r.Changes {
+		parts = append(parts, fmt.Sprintf("%s %s %d row(s)", change.Table, change.Action, change.Rows))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type Sweeper struct {
+	db       *sql.DB
+	dialect  database.Dialect
+	policies Policies
+}
+
+func New(db *sql.DB, policies Policies) *Sweeper {
+	return &Sweeper{db: db, dialect: database.DialectOf(db), policies: policies}
+}
+
+// Sweep applies every enabled policy once. Each table is handled on its own,
+// so a failure stops the sweep but keeps the changes already reported.
+func (s *Sweeper) Sweep(ctx context.Context) (*Report, error) {
+	now := time.Now()
+	report := &Report{StartedAt: now, Changes: make([]Change, 0, 3)}
+
+	steps := []struct {
+		table  string
+		policy Policy
+		sweep  func(ctx context.Context, action Action, cutoff time.Time) (int64, error)
+	}{
+		{"nft_coupons", s.policies.Coupons, s.sweepCoupons},
+		{"deals", s.policies.Deals, s.sweepDeals},
+		{"user_behaviors", s.policies.Behaviors, s.sweepBehaviors},
+	}
+
+	for _, step := range steps {
+		if step.policy.Action == ActionOff {
+			continue
+		}
+
+		rows, err := step.sweep(ctx, step.policy.Action, now.Add(-step.policy.After))
+		if err != nil {
+			report.Duration = time.Since(now).String()
+			return report, fmt.Errorf("failed to sweep %s: %v", step.table, err)
+		}
+		report.Changes = append(report.Changes, Change{Table: step.table, Action: step.policy.Action, Rows: rows})
+	}
+
+	report.Duration = time.Since(now).String()
+	return report, nil
+}
+
+// Run sweeps immediately and then every interval until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.Sweep(ctx)
+		if err != nil {
+			log.Printf("Retention sweep failed after %s: %v", report, err)
+		} else {
+			log.Printf("Retention sweep: %s", report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepCoupons expires or deletes unclaimed coupons past expires_at.
+// Claimed coupons are kept as a record of the redemption.
+func (s *Sweeper) sweepCoupons(ctx context.Context, action Action, cutoff time.Time) (int64, error) {
+	query := "UPDATE nft_coupons SET status = 'expired' WHERE status = 'minted' AND expires_at < $1"
+	if action == ActionDelete {
+		query = "DELETE FROM nft_coupons WHERE status <> 'claimed' AND expires_at < $1"
+	}
+	return s.exec(ctx, s.db, query, cutoff)
+}
+
+// sweepDeals archives or deletes deals past valid_until. Archived deals stay
+// in the table, hidden from listings, until a provider returns them again
+// with a later valid_until.
+func (s *Sweeper) sweepDeals(ctx context.Context, action Action, cutoff time.Time) (int64, error) {
+	if action == ActionDelete {
+		return s.exec(ctx, s.db, "DELETE FROM deals WHERE valid_until < $1", cutoff)
+	}
+	return s.exec(ctx, s.db,
+		"UPDATE deals SET archived_at = $2 WHERE archived_at IS NULL AND valid_until < $1", cutoff, time.Now())
+}
+
+// sweepBehaviors deletes behavior events older than cutoff. With the rollup
+// action they are first added to the daily counts in
+// user_behavior_aggregates, in the same transaction.
+func (s *Sweeper) sweepBehaviors(ctx context.Context, action Action, cutoff time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if action == ActionRollup {
+		day := "CAST(timestamp AS DATE)"
+		if s.dialect == database.SQLite {
+			// Rows written before _time_format=sqlite hold Go's time format,
+			// which date() cannot parse; its first ten characters are the day
+			day = "COALESCE(date(timestamp), date(substr(timestamp, 1, 10)))"
+		}
+
+		if _, err := s.exec(ctx, tx, `INSERT INTO user_behavior_aggregates (user_id, action, category, day, events)
+			SELECT user_id, action, COALESCE(category, ''), `+day+`, COUNT(*)
+			FROM user_behaviors
+			WHERE user_id IS NOT NULL AND timestamp < $1
+			GROUP BY user_id, action, COALESCE(category, ''), `+day+`
+			ON CONFLICT (user_id, action, category, day) DO UPDATE SET
+				events = user_behavior_aggregates.events + EXCLUDED.events`, cutoff); err != nil {
+			return 0, fmt.Errorf("failed to roll up user behaviors: %v", err)
+		}
+	}
+
+	deleted, err := s.exec(ctx, tx, "DELETE FROM user_behaviors WHERE timestamp < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %v", err)
+	}
+	return deleted, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *Sweeper) exec(ctx context.Context, db execer, query string, args ...interface{}) (int64, error) {
+	result, err := db.ExecContext(ctx, s.dialect.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,114 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"personalized-dashboard/shared/database/dbtest"
+	"personalized-dashboard/shared/models"
+	"personalized-dashboard/shared/repository"
+)
+
+func TestSweepSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.SQLite(t)
+	repos := repository.New(db)
+
+	user := &models.User{Email: "sweep@example.com"}
+	if err := repos.Users.Create(ctx, user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	now := time.Now()
+	old := now.Add(-100 * 24 * time.Hour)
+	oldDay := old.UTC().Format("2006-01-02")
+	behaviors := []models.UserBehavior{
+		{UserID: user.ID, Action: "click", Category: "tech", Timestamp: old},
+		{UserID: user.ID, Action: "click", Category: "tech", Timestamp: old.Add(time.Minute)},
+		{UserID: user.ID, Action: "share", Timestamp: old},
+		{UserID: user.ID, Action: "click", Category: "tech", Timestamp: now},
+	}
+	for i := range behaviors {
+		if err := repos.Behaviors.Create(ctx, &behaviors[i]); err != nil {
+			t.Fatalf("Create behavior: %v", err)
+		}
+	}
+	// A row written in Go's time format, as files created before
+	// _time_format=sqlite hold
+	if _, err := db.ExecContext(ctx, "INSERT INTO user_behaviors (id, user_id, action, content_id, category, timestamp) VALUES (?, ?, 'click', 'c1', 'tech', ?)",
+		uuid.New(), user.ID, old.UTC().String()); err != nil {
+		t.Fatalf("insert legacy behavior: %v", err)
+	}
+
+	coupons := []*models.NFTCoupon{
+		{UserID: user.ID, ExpiresAt: now.Add(-time.Hour)},
+		{UserID: user.ID, ExpiresAt: now.Add(time.Hour)},
+	}
+	for _, coupon := range coupons {
+		if err := repos.NFTCoupons.Create(ctx, coupon); err != nil {
+			t.Fatalf("Create coupon: %v", err)
+		}
+	}
+	for i, validUntil := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		deal := &models.Deal{Title: "Deal", URL: "https://example.com/deal/" + string(rune('a'+i)), ValidUntil: validUntil}
+		if err := repos.Deals.Create(ctx, deal); err != nil {
+			t.Fatalf("Create deal: %v", err)
+		}
+	}
+
+	report, err := New(db, DefaultPolicies()).Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	want := map[string]int64{"nft_coupons": 1, "deals": 1, "user_behaviors": 4}
+	for _, change := range report.Changes {
+		if change.Rows != want[change.Table] {
+			t.Errorf("%s: %s %d rows, want %d", change.Table, change.Action, change.Rows, want[change.Table])
+		}
+	}
+
+	aggregates := []struct {
+		action   string
+		category string
+		events   int
+	}{
+		{"click", "tech", 3},
+		{"share", "", 1},
+	}
+	for _, tc := range aggregates {
+		var day string
+		var events int
+		err := db.QueryRowContext(ctx, "SELECT day, events FROM user_behavior_aggregates WHERE action = ? AND category = ?",
+			tc.action, tc.category).Scan(&day, &events)
+		if err != nil {
+			t.Fatalf("aggregate %s/%s: %v", tc.action, tc.category, err)
+		}
+		if events != tc.events || day[:10] != oldDay {
+			t.Errorf("aggregate %s/%s = %d events on %s, want %d on %s", tc.action, tc.category, events, day, tc.events, oldDay)
+		}
+	}
+
+	if remaining, _ := repos.Behaviors.ListByUser(ctx, user.ID, 0); len(remaining) != 1 {
+		t.Errorf("%d behaviors remain, want the recent one", len(remaining))
+	}
+	if coupon, _ := repos.NFTCoupons.GetByID(ctx, coupons[0].ID); coupon.Status != "expired" {
+		t.Errorf("lapsed coupon status = %q, want expired", coupon.Status)
+	}
+	if deals, _ := repos.Deals.List(ctx, repository.ListOptions{}); len(deals) != 1 {
+		t.Errorf("%d deals listed after the sweep, want 1", len(deals))
+	}
+
+	// A second sweep finds nothing new
+	report, err = New(db, DefaultPolicies()).Sweep(ctx)
+	if err != nil {
+		t.Fatalf("second Sweep: %v", err)
+	}
+	for _, change := range report.Changes {
+		if change.Rows != 0 {
+			t.Errorf("second sweep: %s %s %d rows, want 0", change.Table, change.Action, change.Rows)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"personalized-dashboard/shared/resilience"
+)
+
+// UpstreamStatus describes an upstream's policy and breaker for the admin
+// endpoint.
+type UpstreamStatus struct {
+	Name       string                   `json:"name"`
+	Timeout    string                   `json:"timeout"`
+	Retries    int                      `json:"retries"`
+	Backoff    string                   `json:"backoff"`
+	MaxBackoff string                   `json:"max_backoff"`
+	PoolSize   int                      `json:"max_idle_conns"`
+	Breaker    resilience.BreakerStatus `json:"breaker"`
+}
+
+// UpstreamStatus lists the upstreams of the current table, sorted by name.
+func (rt *Router) UpstreamStatus() []UpstreamStatus {
+	upstreams := rt.Table().Upstreams()
+
+	statuses := make([]UpstreamStatus, 0, len(upstreams))
+	for name, upstream := range upstreams {
+		statuses = append(statuses, UpstreamStatus{
+			Name:       name,
+			Timeout:    upstream.Timeout.String(),
+			Retries:    upstream.Policy.Retries,
+			Backoff:    upstream.Policy.Backoff.String(),
+			MaxBackoff: upstream.Policy.MaxBackoff.String(),
+			PoolSize:   upstream.Policy.MaxIdleConns,
+			Breaker:    rt.transport(name).Breaker().Status(),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// UpstreamURLs returns the base URL of every upstream of the current table,
+// by name.
+func (rt *Router) UpstreamURLs() map[string]string {
+	upstreams := rt.Table().Upstreams()
+
+	urls := make(map[string]string, len(upstreams))
+	for name, upstream := range upstreams {
+		urls[name] = upstream.URL.String()
+	}
+	return urls
+}
+
+// AdminHandler serves UpstreamStatus as JSON.
+func (rt *Router) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"upstreams":    rt.UpstreamStatus(),
+			"generated_at": time.Now().UTC(),
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"personalized-dashboard/shared/httpcache"
+)
+
+// CacheSpec is a route's cache setting as written in the file: either just
+// the TTL or an object that also marks the route as personalized.
+type CacheSpec struct {
+	TTL     string `json:"ttl"`
+	PerUser bool   `json:"per_user,omitempty"`
+}
+
+func (s *CacheSpec) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*s = CacheSpec{TTL: raw}
+		return nil
+	}
+
+	type plain CacheSpec
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(s))
+}
+
+// compileCache validates the cache setting of route.
+func compileCache(spec *CacheSpec, route *Route) error {
+	if spec == nil {
+		return nil
+	}
+
+	ttl, err := time.ParseDuration(spec.TTL)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("invalid cache ttl %q", spec.TTL)
+	}
+	if !route.allows(http.MethodGet) {
+		return errors.New("cache needs a GET route")
+	}
+
+	route.Cache.TTL = ttl
+	route.Cache.PerUser = spec.PerUser
+	return nil
+}
+
+// UseCache caches the responses of routes with a cache setting in cache and
+// lets writes through any route invalidate them. Without it nothing is
+// cached.
+func (rt *Router) UseCache(cache *httpcache.Cache) {
+	rt.cache = cache
+}
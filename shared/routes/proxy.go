@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"personalized-dashboard/shared/resilience"
+	"personalized-dashboard/shared/tracing"
+)
+
+type targetKey struct{}
+
+// target is what the proxy needs to know about a request it forwards.
+type target struct {
+	route  *Route
+	params Params
+}
+
+// newProxy returns the reverse proxy behind every route. It streams request
+// and response bodies instead of buffering them, passes the upstream status
+// and headers through, drops hop-by-hop headers in both directions and sets
+// X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto. An existing
+// X-Forwarded-For is extended rather than replaced. Requests go out through
+// the transport of their upstream, each in a client span whose traceparent
+// the upstream receives.
+func newProxy(rt *Router) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: tracing.NewTransport(upstreamTransport{rt}),
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			t := pr.In.Context().Value(targetKey{}).(target)
+			pr.Out.URL = t.route.UpstreamURL(pr.In.URL.EscapedPath(), pr.In.URL.RawQuery, t.params)
+			pr.Out.Host = ""
+
+			pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			pr.SetXForwarded()
+		},
+		ErrorHandler: proxyError,
+	}
+}
+
+// upstreamTransport sends each request through the resilience.Transport of
+// the upstream it is proxied to.
+type upstreamTransport struct {
+	rt *Router
+}
+
+func (u upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := req.Context().Value(targetKey{}).(target)
+	return u.rt.transport(t.route.Upstream.Name).RoundTrip(req)
+}
+
+// forward sends r to the route's upstream within the route's timeout and
+// streams the response back.
+func (rt *Router) forward(w http.ResponseWriter, r *http.Request, route *Route, params Params) {
+	ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, targetKey{}, target{route: route, params: params})
+	rt.reverseProxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// proxyError answers 503 while the upstream's breaker is open, 504 when the
+// upstream did not answer within the route timeout and 502 for any other
+// upstream failure.
+func proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	message := "upstream unavailable"
+	var open *resilience.OpenError
+	switch {
+	case errors.As(err, &open):
+		status = http.StatusServiceUnavailable
+		message = "upstream is failing, try again later"
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(open.RetryAfter.Seconds()))))
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+		message = "upstream timed out"
+	}
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		// The client went away; there is nobody to answer
+		return
+	}
+
+	if open == nil {
+		tracing.Logf(r.Context(), "Warning: proxying %s %s failed: %v", r.Method, r.URL.Path, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"personalized-dashboard/shared/auth"
+)
+
+// newTestRouter serves routes from a route file whose upstreams all point at
+// upstreamURL, without retries.
+func newTestRouter(t *testing.T, upstreamURL, routes string) http.Handler {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routes.json")
+	file := fmt.Sprintf(`{"upstreams": {"api": {"url": %q, "retries": 0}}, "routes": [%s]}`, upstreamURL, routes)
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("failed to write route file: %v", err)
+	}
+
+	router, err := NewRouter(path)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	return auth.Identity(router.Middleware(next))
+}
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/stream":
+			// Flushed chunks must reach the client before the handler returns
+			flusher := w.(http.Flusher)
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "chunk %d\n", i)
+				flusher.Flush()
+			}
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Connection", "X-Internal")
+		w.Header().Set("X-Internal", "hop")
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":           r.Method,
+			"uri":              r.URL.RequestURI(),
+			"body":             string(body),
+			"x_forwarded_for":  r.Header.Get("X-Forwarded-For"),
+			"x_forwarded_host": r.Header.Get("X-Forwarded-Host"),
+			"x_hop":            r.Header.Get("X-Hop"),
+			"user":             r.Header.Get(auth.UserIDHeader),
+		})
+	}))
+	defer upstream.Close()
+
+	handler := newTestRouter(t, upstream.URL, `
+		{"path": "/api/users/:id", "methods": ["GET", "POST"], "upstream": "api", "upstream_path": "/v2/users/:id"},
+		{"path": "/api/audit", "upstream": "api", "auth": "required"},
+		{"path": "/slow", "upstream": "api", "timeout": "50ms"},
+		{"path": "/stream", "upstream": "api"}`)
+
+	t.Run("forwards", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://gateway.example.com/api/users/u%201?x=1", strings.NewReader(`{"name":"a"}`))
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		req.Header.Set("Connection", "X-Hop")
+		req.Header.Set("X-Hop", "dropped")
+		req.Header.Set(auth.UserIDHeader, "alice")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want the upstream's 201: %s", rec.Code, rec.Body)
+		}
+		if rec.Header().Get("X-Upstream") != "yes" || rec.Header().Get("X-Internal") != "" {
+			t.Errorf("response headers = %v, want X-Upstream kept and the hop-by-hop X-Internal dropped", rec.Header())
+		}
+
+		var got map[string]string
+		json.NewDecoder(rec.Body).Decode(&got)
+		want := map[string]string{
+			"method":           http.MethodPost,
+			"uri":              "/v2/users/u%201?x=1",
+			"body":             `{"name":"a"}`,
+			"x_forwarded_for":  "198.51.100.1, 203.0.113.7",
+			"x_forwarded_host": "gateway.example.com",
+			"x_hop":            "",
+			"user":             "alice",
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("upstream saw %s = %q, want %q", key, got[key], value)
+			}
+		}
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{"method not allowed", http.MethodDelete, "/api/users/u1", "", http.StatusMethodNotAllowed, map[string]string{"Allow": "GET, POST"}},
+		{"not a route", http.MethodGet, "/health", "", http.StatusTeapot, nil},
+		{"auth required", http.MethodGet, "/api/audit", "", http.StatusUnauthorized, map[string]string{"WWW-Authenticate": `Bearer realm="api"`}},
+		{"auth required with a user", http.MethodGet, "/api/audit", "alice", http.StatusCreated, nil},
+		{"route timeout", http.MethodGet, "/slow", "", http.StatusGatewayTimeout, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.user != "" {
+				req.Header.Set(auth.UserIDHeader, tc.user)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			for header, want := range tc.wantHeader {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("streams", func(t *testing.T) {
+		gateway := httptest.NewServer(handler)
+		defer gateway.Close()
+
+		resp, err := http.Get(gateway.URL + "/stream")
+		if err != nil {
+			t.Fatalf("GET /stream: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "chunk 0\nchunk 1\nchunk 2\n" || resp.ContentLength != -1 {
+			t.Errorf("streamed body = %q with length %d, want the chunks unbuffered", body, resp.ContentLength)
+		}
+	})
+}
+
+func TestProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstreamURL := upstream.URL
+	upstream.Close()
+
+	handler := newTestRouter(t, upstreamURL, `{"path": "/api/news", "upstream": "api"}`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/news", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", rec.Code)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] == "" {
+		t.Errorf("body = %v, %v; want a JSON error", body, err)
+	}
+}
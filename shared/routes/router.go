@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"personalized-dashboard/shared/auth"
+	"personalized-dashboard/shared/httpcache"
+	"personalized-dashboard/shared/metrics"
+	"personalized-dashboard/shared/resilience"
+)
+
+// FileFromEnv returns the route file named by GATEWAY_ROUTES_FILE, by
+// default routes.json in the working directory.
+func FileFromEnv() string {
+	if path := os.Getenv("GATEWAY_ROUTES_FILE"); path != "" {
+		return path
+	}
+	return "routes.json"
+}
+
+// Router serves the routes of the current table. The table is swapped
+// atomically on reload, so requests in flight keep the route they matched.
+type Router struct {
+	path         string
+	table        atomic.Pointer[Table]
+	reverseProxy *httputil.ReverseProxy
+	cache        *httpcache.Cache
+
+	// transports holds one resilience.Transport per upstream name. They
+	// outlive reloads so that pools and breaker states are kept.
+	mu         sync.Mutex
+	transports map[string]*resilience.Transport
+}
+
+// NewRouter loads the route file at path. It fails when the file is invalid,
+// so a broken file stops the gateway at startup.
+func NewRouter(path string) (*Router, error) {
+	table, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	router := &Router{path: path, transports: make(map[string]*resilience.Transport)}
+	router.reverseProxy = newProxy(router)
+	router.use(table)
+	return router, nil
+}
+
+// Table returns the routes currently served.
+func (rt *Router) Table() *Table {
+	return rt.table.Load()
+}
+
+// Reload loads the route file again. An invalid file is rejected and the
+// current routes stay in place.
+func (rt *Router) Reload() error {
+	table, err := Load(rt.path)
+	if err != nil {
+		return err
+	}
+	rt.use(table)
+	return nil
+}
+
+// use serves table, creating transports for new upstreams, applying the
+// policies of existing ones and closing those no longer listed.
+func (rt *Router) use(table *Table) {
+	rt.mu.Lock()
+	for name, upstream := range table.Upstreams() {
+		if transport, ok := rt.transports[name]; ok {
+			transport.SetPolicy(upstream.Policy)
+		} else {
+			rt.transports[name] = resilience.NewTransport(name, upstream.Policy)
+		}
+	}
+	for name, transport := range rt.transports {
+		if _, ok := table.Upstreams()[name]; !ok {
+			transport.CloseIdleConnections()
+			delete(rt.transports, name)
+		}
+	}
+	rt.mu.Unlock()
+
+	rt.table.Store(table)
+}
+
+func (rt *Router) transport(name string) *resilience.Transport {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	transport, ok := rt.transports[name]
+	if !ok {
+		// Only a request that raced a reload removing its upstream gets here
+		transport = resilience.NewTransport(name, resilience.DefaultPolicy())
+		rt.transports[name] = transport
+	}
+	return transport
+}
+
+// ReloadOnSignal reloads the route file on every SIGHUP until ctx is done.
+func (rt *Router) ReloadOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := rt.Reload(); err != nil {
+					log.Printf("Warning: keeping current routes: %v", err)
+					continue
+				}
+				log.Printf("Reloaded %d routes from %s", len(rt.Table().Routes()), rt.path)
+			}
+		}
+	}()
+}
+
+// Public reports whether the route serving r lets anonymous requests
+// through. Pass it to the verifier so AUTH_REQUIRED spares public routes.
+func (rt *Router) Public(r *http.Request) bool {
+	route, _, _ := rt.Table().Match(r.Method, r.URL.EscapedPath())
+	return route != nil && route.Auth == AuthPublic
+}
+
+// Middleware proxies the requests that match a route, through the cache when
+// one is in use, and passes everything else to next, so the gateway's own
+// endpoints keep working. A path served
+// by routes that do not allow the method gets 405.
+func (rt *Router) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params, allowed := rt.Table().Match(r.Method, r.URL.EscapedPath())
+		if route == nil {
+			if len(allowed) > 0 {
+				w.Header().Set("Allow", allowHeader(allowed))
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		metrics.SetRoute(r.Context(), route.Path)
+
+		if route.Auth == AuthRequired && auth.UserIDFrom(r.Context()) == "" {
+			auth.Unauthorized(w, auth.ErrMissingToken)
+			return
+		}
+
+		if rt.cache == nil {
+			rt.forward(w, r, route, params)
+			return
+		}
+		rt.cache.Serve(w, r, route.Cache, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rt.forward(w, r, route, params)
+		}))
+	})
+}
+
+func allowHeader(methods []string) string {
+	unique := make(map[string]bool)
+	var list []string
+	for _, method := range methods {
+		if !unique[method] {
+			unique[method] = true
+			list = append(list, method)
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,393 @@
+// Package routes loads the gateway's declarative route table and serves the
+// proxied routes from it. Both gateway entrypoints build their routes from
+// the same file, which is validated at startup and can be reloaded with
+// SIGHUP.
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/httpcache"
+)
+
+// Auth requirements of a route.
+const (
+	// AuthDefault follows AUTH_REQUIRED.
+	AuthDefault = ""
+	// AuthRequired rejects anonymous requests even when AUTH_REQUIRED is off.
+	AuthRequired = "required"
+	// AuthPublic lets anonymous requests through even when AUTH_REQUIRED is on.
+	AuthPublic = "public"
+)
+
+// DefaultTimeout applies to routes without a timeout.
+const DefaultTimeout = 30 * time.Second
+
+// File is the route file. Upstream URLs may reference environment variables
+// as ${NAME} or ${NAME:-default}.
+type File struct {
+	Upstreams map[string]UpstreamSpec `json:"upstreams"`
+	Routes    []RouteSpec             `json:"routes"`
+}
+
+// RouteSpec is one route as written in the file. Path segments starting
+// with : match one segment; a final * (optionally named, as in *rest)
+// matches the rest of the path. UpstreamPath is the path sent upstream, with
+// the parameters of Path substituted; it defaults to the request path.
+// Timeout defaults to the upstream's timeout. Routes with a Cache setting
+// have their GET responses cached by the gateway.
+type RouteSpec struct {
+	Path         string     `json:"path"`
+	Methods      []string   `json:"methods"`
+	Upstream     string     `json:"upstream"`
+	UpstreamPath string     `json:"upstream_path"`
+	Timeout      string     `json:"timeout"`
+	Auth         string     `json:"auth"`
+	Cache        *CacheSpec `json:"cache"`
+}
+
+// Route is a validated route.
+type Route struct {
+	Path         string
+	Methods      []string
+	Upstream     *Upstream
+	UpstreamPath string
+	Timeout      time.Duration
+	Auth         string
+	// Cache has a zero TTL when the route is not cached.
+	Cache httpcache.Policy
+
+	segments []segment
+	// template is nil when the request path is forwarded as it is.
+	template []segment
+}
+
+type segmentKind int
+
+// Kinds are ordered by precedence: a static segment beats a parameter, which
+// beats a wildcard.
+const (
+	staticSegment segmentKind = iota
+	paramSegment
+	wildcardSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+var (
+	envPattern   = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+	namePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	validMethods = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+		http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+	}
+)
+
+// Load reads and validates a route file. All problems are reported at once.
+func Load(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route file: %v", err)
+	}
+
+	var file File
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse route file %s: %v", path, err)
+	}
+
+	table, err := Compile(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route file %s: %v", path, err)
+	}
+	return table, nil
+}
+
+// Compile validates file and builds its route table.
+func Compile(file File) (*Table, error) {
+	var errs []error
+
+	upstreams := make(map[string]*Upstream, len(file.Upstreams))
+	for name, spec := range file.Upstreams {
+		upstream, upstreamErrs := compileUpstream(name, spec)
+		for _, err := range upstreamErrs {
+			errs = append(errs, fmt.Errorf("upstream %q: %v", name, err))
+		}
+		if len(upstreamErrs) == 0 {
+			upstreams[name] = upstream
+		}
+	}
+
+	if len(file.Routes) == 0 {
+		errs = append(errs, errors.New("no routes defined"))
+	}
+
+	table := &Table{upstreams: upstreams}
+	seen := make(map[string]int)
+	for i, spec := range file.Routes {
+		route, routeErrs := compileRoute(spec, upstreams)
+		for _, err := range routeErrs {
+			errs = append(errs, fmt.Errorf("route %d (%s): %v", i+1, spec.Path, err))
+		}
+		if len(routeErrs) > 0 {
+			continue
+		}
+
+		for _, method := range route.Methods {
+			key := method + " " + route.pattern()
+			if previous, ok := seen[key]; ok {
+				errs = append(errs, fmt.Errorf("route %d (%s): %s is already served by route %d", i+1, spec.Path, method, previous))
+			}
+			seen[key] = i + 1
+		}
+		table.routes = append(table.routes, route)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return table, nil
+}
+
+func compileRoute(spec RouteSpec, upstreams map[string]*Upstream) (*Route, []error) {
+	var errs []error
+	route := &Route{Path: spec.Path, UpstreamPath: spec.UpstreamPath, Timeout: DefaultTimeout, Auth: spec.Auth}
+
+	segments, err := parsePattern(spec.Path)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	route.segments = segments
+
+	if spec.UpstreamPath != "" {
+		template, err := parseTemplate(spec.UpstreamPath, segments)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("upstream_path: %v", err))
+		}
+		route.template = template
+	}
+
+	if len(spec.Methods) == 0 {
+		route.Methods = []string{http.MethodGet}
+	}
+	for _, method := range spec.Methods {
+		method = strings.ToUpper(method)
+		if !validMethods[method] {
+			errs = append(errs, fmt.Errorf("unsupported method %q", method))
+			continue
+		}
+		route.Methods = append(route.Methods, method)
+	}
+
+	if spec.Upstream == "" {
+		errs = append(errs, errors.New("upstream is required"))
+	} else if upstream, ok := upstreams[spec.Upstream]; ok {
+		route.Upstream = upstream
+		route.Timeout = upstream.Timeout
+		route.Cache.Group = upstream.Name
+	} else {
+		errs = append(errs, fmt.Errorf("unknown upstream %q", spec.Upstream))
+	}
+
+	if spec.Timeout != "" {
+		timeout, err := time.ParseDuration(spec.Timeout)
+		if err != nil || timeout <= 0 {
+			errs = append(errs, fmt.Errorf("invalid timeout %q", spec.Timeout))
+		}
+		route.Timeout = timeout
+	}
+
+	switch spec.Auth {
+	case AuthDefault, AuthRequired, AuthPublic:
+	default:
+		errs = append(errs, fmt.Errorf("auth must be %q or %q, got %q", AuthRequired, AuthPublic, spec.Auth))
+	}
+
+	if err := compileCache(spec.Cache, route); err != nil {
+		errs = append(errs, err)
+	}
+
+	return route, errs
+}
+
+// parsePattern splits a path pattern into segments.
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, errors.New("path must start with /")
+	}
+
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		return nil, nil
+	}
+
+	segments := make([]segment, 0, len(parts))
+	names := make(map[string]bool)
+	for i, part := range parts {
+		switch {
+		case part == "":
+			return nil, errors.New("path has an empty segment")
+		case strings.HasPrefix(part, ":"):
+			name := part[1:]
+			if !namePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid parameter name %q", part)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("parameter %q appears twice", name)
+			}
+			names[name] = true
+			segments = append(segments, segment{kind: paramSegment, value: name})
+		case strings.HasPrefix(part, "*"):
+			if i != len(parts)-1 {
+				return nil, errors.New("* must be the last segment")
+			}
+			segments = append(segments, segment{kind: wildcardSegment, value: part[1:]})
+		default:
+			segments = append(segments, segment{kind: staticSegment, value: part})
+		}
+	}
+	return segments, nil
+}
+
+// pattern is the route path with parameter names erased, so that routes
+// which match the same requests compare equal.
+func (r *Route) pattern() string {
+	var b strings.Builder
+	for _, seg := range r.segments {
+		b.WriteByte('/')
+		switch seg.kind {
+		case paramSegment:
+			b.WriteByte(':')
+		case wildcardSegment:
+			b.WriteByte('*')
+		default:
+			b.WriteString(seg.value)
+		}
+	}
+	return b.String()
+}
+
+func (r *Route) allows(method string) bool {
+	for _, allowed := range r.Methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Params are the values a request path gives a route's parameters. Values of
+// :name parameters are unescaped; the wildcard value is the rest of the path
+// as it was escaped in the request, so encoded slashes survive.
+type Params map[string]string
+
+// match reports whether the escaped path matches the route and returns its
+// parameters. Segments are unescaped before they are compared, so an
+// encoded slash stays inside its segment.
+func (r *Route) match(escapedPath string) (Params, bool) {
+	parts := strings.Split(strings.Trim(escapedPath, "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		parts = nil
+	}
+
+	params := make(Params)
+	for i, seg := range r.segments {
+		if seg.kind == wildcardSegment {
+			params[wildcardName(seg.value)] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+
+		value, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, false
+		}
+		switch seg.kind {
+		case staticSegment:
+			if value != seg.value {
+				return nil, false
+			}
+		case paramSegment:
+			params[seg.value] = value
+		}
+	}
+	return params, len(parts) == len(r.segments)
+}
+
+// wildcardName is the Params key of a wildcard; an unnamed * is stored as *.
+func wildcardName(name string) string {
+	if name == "" {
+		return "*"
+	}
+	return name
+}
+
+// moreSpecific reports whether r should win over other when both match.
+func (r *Route) moreSpecific(other *Route) bool {
+	for i := 0; i < len(r.segments) && i < len(other.segments); i++ {
+		if r.segments[i].kind != other.segments[i].kind {
+			return r.segments[i].kind < other.segments[i].kind
+		}
+	}
+	return len(r.segments) > len(other.segments)
+}
+
+// Table is a compiled route file.
+type Table struct {
+	routes    []*Route
+	upstreams map[string]*Upstream
+}
+
+func (t *Table) Routes() []*Route {
+	return t.routes
+}
+
+func (t *Table) Upstreams() map[string]*Upstream {
+	return t.upstreams
+}
+
+// Match finds the most specific route for the escaped request path (see
+// url.URL.EscapedPath). When routes match the path but none allows method,
+// it returns them in allowed so the caller can answer 405.
+func (t *Table) Match(method, escapedPath string) (route *Route, params Params, allowed []string) {
+	for _, candidate := range t.routes {
+		candidateParams, ok := candidate.match(escapedPath)
+		if !ok {
+			continue
+		}
+		if !candidate.allows(method) {
+			allowed = append(allowed, candidate.Methods...)
+			continue
+		}
+		if route == nil || candidate.moreSpecific(route) {
+			route, params = candidate, candidateParams
+		}
+	}
+	return route, params, allowed
+}
+
+// expandEnv replaces ${NAME} and ${NAME:-default} with environment values.
+func expandEnv(value string) string {
+	return envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := envPattern.FindStringSubmatch(ref)
+		if env := os.Getenv(match[1]); env != "" {
+			return env
+		}
+		return match[2]
+	})
+}
@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTable(t *testing.T, specs ...RouteSpec) *Table {
+	t.Helper()
+	table, err := Compile(File{
+		Upstreams: map[string]UpstreamSpec{
+			"api":  {URL: "http://api.internal:8001/base/"},
+			"user": {URL: "http://user.internal", Timeout: "5s"},
+		},
+		Routes: specs,
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return table
+}
+
+func TestMatch(t *testing.T) {
+	table := testTable(t,
+		RouteSpec{Path: "/api/videos/:id", Upstream: "api"},
+		RouteSpec{Path: "/api/videos/trending", Upstream: "api"},
+		RouteSpec{Path: "/api/videos/*rest", Upstream: "api"},
+		RouteSpec{Path: "/api/users/:id", Methods: []string{"get", "PUT"}, Upstream: "user"},
+		RouteSpec{Path: "/api/users", Methods: []string{"POST"}, Upstream: "user"},
+		RouteSpec{Path: "/files/*", Upstream: "api"},
+	)
+
+	tests := []struct {
+		method      string
+		path        string
+		wantRoute   string
+		wantParams  Params
+		wantAllowed []string
+	}{
+		{"GET", "/api/videos/trending", "/api/videos/trending", Params{}, nil},
+		{"GET", "/api/videos/abc", "/api/videos/:id", Params{"id": "abc"}, nil},
+		{"GET", "/api/videos/a%2Fb", "/api/videos/:id", Params{"id": "a/b"}, nil},
+		{"GET", "/api/videos/abc/comments/1", "/api/videos/*rest", Params{"rest": "abc/comments/1"}, nil},
+		{"GET", "/api/videos/", "/api/videos/*rest", Params{"rest": ""}, nil},
+		{"PUT", "/api/users/u1", "/api/users/:id", Params{"id": "u1"}, nil},
+		{"DELETE", "/api/users/u1", "", nil, []string{"GET", "PUT"}},
+		{"GET", "/api/users", "", nil, []string{"POST"}},
+		{"GET", "/files/a%2Fb/c", "/files/*", Params{"*": "a%2Fb/c"}, nil},
+		{"GET", "/api/news", "", nil, nil},
+		{"GET", "/api/videos%2Fabc", "", nil, nil},
+		{"GET", "/api/videos/%zz", "/api/videos/*rest", Params{"rest": "%zz"}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			route, params, allowed := table.Match(tc.method, tc.path)
+			got := ""
+			if route != nil {
+				got = route.Path
+			}
+			if got != tc.wantRoute {
+				t.Fatalf("Match = %q, want %q", got, tc.wantRoute)
+			}
+			if route != nil && !reflect.DeepEqual(params, tc.wantParams) {
+				t.Errorf("params = %v, want %v", params, tc.wantParams)
+			}
+			if !reflect.DeepEqual(allowed, tc.wantAllowed) {
+				t.Errorf("allowed = %v, want %v", allowed, tc.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec RouteSpec
+		want string
+	}{
+		{"relative path", RouteSpec{Path: "api/news", Upstream: "api"}, "must start with /"},
+		{"empty segment", RouteSpec{Path: "/api//news", Upstream: "api"}, "empty segment"},
+		{"bad parameter", RouteSpec{Path: "/api/:1d", Upstream: "api"}, "invalid parameter name"},
+		{"repeated parameter", RouteSpec{Path: "/api/:id/:id", Upstream: "api"}, "appears twice"},
+		{"wildcard not last", RouteSpec{Path: "/api/*/news", Upstream: "api"}, "must be the last segment"},
+		{"unknown template parameter", RouteSpec{Path: "/api/:id", Upstream: "api", UpstreamPath: "/x/:name"}, "is not in the route path"},
+		{"unknown template wildcard", RouteSpec{Path: "/api/:id", Upstream: "api", UpstreamPath: "/x/*rest"}, "is not in the route path"},
+		{"missing upstream", RouteSpec{Path: "/api"}, "upstream is required"},
+		{"unknown upstream", RouteSpec{Path: "/api", Upstream: "nope"}, "unknown upstream"},
+		{"bad method", RouteSpec{Path: "/api", Upstream: "api", Methods: []string{"FETCH"}}, "unsupported method"},
+		{"bad timeout", RouteSpec{Path: "/api", Upstream: "api", Timeout: "soon"}, "invalid timeout"},
+		{"bad auth", RouteSpec{Path: "/api", Upstream: "api", Auth: "maybe"}, "auth must be"},
+		{"bad cache ttl", RouteSpec{Path: "/api", Upstream: "api", Cache: &CacheSpec{TTL: "-1s"}}, "invalid cache ttl"},
+		{"cached POST", RouteSpec{Path: "/api", Upstream: "api", Methods: []string{"POST"}, Cache: &CacheSpec{TTL: "1m"}}, "cache needs a GET route"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile(File{Upstreams: map[string]UpstreamSpec{"api": {URL: "http://api"}}, Routes: []RouteSpec{tc.spec}})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Compile err = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+
+	_, err := Compile(File{
+		Upstreams: map[string]UpstreamSpec{"api": {URL: "http://api"}, "bad": {URL: "ftp://files", Retries: new(int)}},
+		Routes: []RouteSpec{
+			{Path: "/api/:id", Upstream: "api"},
+			{Path: "/api/:name", Upstream: "api"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "already served by route 1") || !strings.Contains(err.Error(), "not an http(s) URL") {
+		t.Errorf("Compile err = %v, want both the duplicate route and the bad upstream", err)
+	}
+
+	if _, err := Compile(File{}); err == nil {
+		t.Error("Compile of an empty file succeeded")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("NEWS_SERVICE_URL", "http://news.internal:9001")
+
+	table, err := Load(filepath.Join("..", "..", "gateway", "routes.json"))
+	if err != nil {
+		t.Fatalf("Load(gateway/routes.json): %v", err)
+	}
+	if got := table.Upstreams()["news"].URL.String(); got != "http://news.internal:9001" {
+		t.Errorf("news upstream = %s, want the NEWS_SERVICE_URL value", got)
+	}
+	if got := table.Upstreams()["jobs"].URL.String(); got != "http://localhost:8002" {
+		t.Errorf("jobs upstream = %s, want the default", got)
+	}
+
+	route, _, _ := table.Match("GET", "/api/recommendations")
+	if route == nil || route.Cache.TTL != time.Minute || !route.Cache.PerUser || route.Timeout != 20*time.Second {
+		t.Errorf("/api/recommendations = %+v, want a per-user 60s cache and the upstream's 20s timeout", route)
+	}
+	// Profile updates go through the cached route, so they invalidate it
+	route, _, _ = table.Match("PUT", "/api/users/u1")
+	if route == nil || route.Cache.TTL != 30*time.Second || route.Cache.Group != "user" {
+		t.Errorf("PUT /api/users/u1 = %+v, want the cached user route", route)
+	}
+
+	path := filepath.Join(t.TempDir(), "routes.json")
+	os.WriteFile(path, []byte(`{"upstreams": {}, "routes": [], "extra": true}`), 0o600)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("Load with an unknown field: err = %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write route file: %v", err)
+		}
+	}
+
+	write(`{"upstreams": {"news": {"url": "http://news", "retries": 0}}, "routes": [{"path": "/api/news", "upstream": "news"}]}`)
+	router, err := NewRouter(path)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	breaker := router.transport("news").Breaker()
+
+	write(`{"upstreams": {"news": "http://news"}, "routes": [{"path": "/api/news", "upstream": "missing"}]}`)
+	if err := router.Reload(); err == nil {
+		t.Fatal("Reload of an invalid file succeeded")
+	}
+	if route, _, _ := router.Table().Match("GET", "/api/news"); route == nil {
+		t.Error("an invalid reload dropped the current routes")
+	}
+
+	write(`{"upstreams": {"news": {"url": "http://news", "retries": 3}, "jobs": "http://jobs"}, "routes": [{"path": "/api/jobs", "upstream": "jobs"}, {"path": "/api/news", "upstream": "news"}]}`)
+	if err := router.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if route, _, _ := router.Table().Match("GET", "/api/jobs"); route == nil {
+		t.Error("Reload did not add /api/jobs")
+	}
+	if router.transport("news").Breaker() != breaker {
+		t.Error("Reload replaced the transport of an upstream it kept")
+	}
+
+	statuses := router.UpstreamStatus()
+	if len(statuses) != 2 || statuses[0].Name != "jobs" || statuses[1].Name != "news" || statuses[1].Retries != 3 {
+		t.Errorf("UpstreamStatus = %+v, want jobs and news with the reloaded policy", statuses)
+	}
+}
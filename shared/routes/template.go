@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseTemplate parses an upstream path template. It may only use the
+// parameters and wildcard of the route path it belongs to.
+func parseTemplate(template string, route []segment) ([]segment, error) {
+	segments, err := parsePattern(template)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]bool)
+	wildcard, hasWildcard := "", false
+	for _, seg := range route {
+		switch seg.kind {
+		case paramSegment:
+			params[seg.value] = true
+		case wildcardSegment:
+			wildcard, hasWildcard = seg.value, true
+		}
+	}
+
+	for _, seg := range segments {
+		switch seg.kind {
+		case paramSegment:
+			if !params[seg.value] {
+				return nil, fmt.Errorf("parameter :%s is not in the route path", seg.value)
+			}
+		case wildcardSegment:
+			if !hasWildcard || seg.value != wildcard {
+				return nil, fmt.Errorf("wildcard *%s is not in the route path", seg.value)
+			}
+		}
+	}
+	return segments, nil
+}
+
+// UpstreamURL returns the URL a request for the route is sent to: the
+// upstream base URL, the request path or the rendered upstream_path, and the
+// request query.
+func (r *Route) UpstreamURL(escapedPath, rawQuery string, params Params) *url.URL {
+	path := escapedPath
+	if r.template != nil {
+		path = render(r.template, params)
+	}
+
+	target := *r.Upstream.URL
+	target.RawPath = r.Upstream.URL.EscapedPath() + path
+	target.Path, _ = url.PathUnescape(target.RawPath)
+	target.RawQuery = rawQuery
+	return &target
+}
+
+// render fills in a template. Parameters are escaped as single path
+// segments, so a value such as "a/b" cannot change the upstream path;
+// wildcard segments are escaped one by one and keep their slashes.
+func render(template []segment, params Params) string {
+	var b strings.Builder
+	for _, seg := range template {
+		switch seg.kind {
+		case staticSegment:
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(seg.value))
+		case paramSegment:
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(params[seg.value]))
+		case wildcardSegment:
+			rest := params[wildcardName(seg.value)]
+			if rest == "" {
+				continue
+			}
+			for _, part := range strings.Split(rest, "/") {
+				unescaped, err := url.PathUnescape(part)
+				if err != nil {
+					unescaped = part
+				}
+				b.WriteByte('/')
+				b.WriteString(url.PathEscape(unescaped))
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package routes
+
+import "testing"
+
+func TestUpstreamURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		upstreamPath string
+		request      string
+		query        string
+		want         string
+	}{
+		{"request path", "/api/news", "", "/api/news", "category=tech", "http://api.internal:8001/base/api/news?category=tech"},
+		{"parameter", "/api/v2/users/:id", "/users/:id", "/api/v2/users/u1", "", "http://api.internal:8001/base/users/u1"},
+		{"parameter with a slash", "/api/v2/users/:id", "/users/:id/profile", "/api/v2/users/a%2F..%2Fadmin", "", "http://api.internal:8001/base/users/a%2F..%2Fadmin/profile"},
+		{"reordered parameters", "/a/:x/b/:y", "/:y/:x", "/a/1/b/2", "", "http://api.internal:8001/base/2/1"},
+		{"wildcard", "/static/*rest", "/assets/*rest", "/static/css/site%20main.css", "", "http://api.internal:8001/base/assets/css/site%20main.css"},
+		{"empty wildcard", "/static/*rest", "/assets/*rest", "/static/", "", "http://api.internal:8001/base/assets"},
+		{"escaped static segment", "/old", "/new path", "/old", "", "http://api.internal:8001/base/new%20path"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			table := testTable(t, RouteSpec{Path: tc.path, Upstream: "api", UpstreamPath: tc.upstreamPath})
+			route, params, _ := table.Match("GET", tc.request)
+			if route == nil {
+				t.Fatalf("%s does not match %s", tc.request, tc.path)
+			}
+			if got := route.UpstreamURL(tc.request, tc.query, params).String(); got != tc.want {
+				t.Errorf("UpstreamURL = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"personalized-dashboard/shared/resilience"
+)
+
+// UpstreamSpec is an upstream as written in the file: either just its URL or
+// an object with the URL and its resilience policy. Unset fields keep the
+// defaults of resilience.DefaultPolicy.
+type UpstreamSpec struct {
+	URL string `json:"url"`
+	// Timeout is the request deadline of routes that do not set their own.
+	Timeout          string `json:"timeout,omitempty"`
+	Retries          *int   `json:"retries,omitempty"`
+	Backoff          string `json:"backoff,omitempty"`
+	MaxBackoff       string `json:"max_backoff,omitempty"`
+	FailureThreshold *int   `json:"failure_threshold,omitempty"`
+	Cooldown         string `json:"cooldown,omitempty"`
+	MaxIdleConns     *int   `json:"max_idle_conns,omitempty"`
+}
+
+func (s *UpstreamSpec) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*s = UpstreamSpec{URL: raw}
+		return nil
+	}
+
+	type plain UpstreamSpec
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(s))
+}
+
+// Upstream is a validated upstream.
+type Upstream struct {
+	Name    string
+	URL     *url.URL
+	Timeout time.Duration
+	Policy  resilience.Policy
+}
+
+func compileUpstream(name string, spec UpstreamSpec) (*Upstream, []error) {
+	var errs []error
+	upstream := &Upstream{Name: name, Timeout: DefaultTimeout, Policy: resilience.DefaultPolicy()}
+
+	expanded := expandEnv(spec.URL)
+	parsed, err := url.Parse(expanded)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		errs = append(errs, fmt.Errorf("%q is not an http(s) URL", expanded))
+	} else {
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+		upstream.URL = parsed
+	}
+
+	durations := []struct {
+		field string
+		value string
+		into  *time.Duration
+	}{
+		{"timeout", spec.Timeout, &upstream.Timeout},
+		{"backoff", spec.Backoff, &upstream.Policy.Backoff},
+		{"max_backoff", spec.MaxBackoff, &upstream.Policy.MaxBackoff},
+		{"cooldown", spec.Cooldown, &upstream.Policy.Cooldown},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			errs = append(errs, fmt.Errorf("invalid %s %q", d.field, d.value))
+			continue
+		}
+		*d.into = parsed
+	}
+
+	counts := []struct {
+		field string
+		value *int
+		min   int
+		into  *int
+	}{
+		{"retries", spec.Retries, 0, &upstream.Policy.Retries},
+		{"failure_threshold", spec.FailureThreshold, 1, &upstream.Policy.FailureThreshold},
+		{"max_idle_conns", spec.MaxIdleConns, 1, &upstream.Policy.MaxIdleConns},
+	}
+	for _, c := range counts {
+		if c.value == nil {
+			continue
+		}
+		if *c.value < c.min {
+			errs = append(errs, fmt.Errorf("%s must be at least %d", c.field, c.min))
+			continue
+		}
+		*c.into = *c.value
+	}
+
+	return upstream, errs
+}
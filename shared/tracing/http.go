@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"personalized-dashboard/shared/metrics"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom returns the ID of the request ctx belongs to, or "".
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Middleware gives every request a server span, continuing the trace of an
+// incoming traceparent, and an ID: the X-Request-ID it came with or a new
+// one. The ID is set on the request, so proxied requests carry it, and
+// echoed in the response. Each request is logged with both IDs when it
+// completes.
+func Middleware(next http.Handler) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		r.Header.Set(RequestIDHeader, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", requestID))
+
+		ctx := WithRequestID(r.Context(), requestID)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		Logf(ctx, "%s %s %d %s", r.Method, r.URL.Path, sw.status, time.Since(start).Round(time.Millisecond))
+	})
+
+	return otelhttp.NewHandler(handler, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}))
+}
+
+// validRequestID accepts client-supplied IDs that are safe to log and
+// forward: up to 128 printable ASCII characters.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTransport traces the requests sent through base: each gets a client
+// span and carries the traceparent of that span and the X-Request-ID of the
+// request it is made for. Query values are redacted in the span, as provider
+// URLs carry API keys (NewsAPI's apiKey, YouTube's key).
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return redactQueryTransport{otelhttp.NewTransport(requestIDTransport{base},
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Host
+		}))}
+}
+
+type rawQueryKey struct{}
+
+// redactQueryTransport hands otelhttp a request whose query values are
+// redacted, so that they do not end up in url.full. requestIDTransport puts
+// the real query back before the request is sent.
+type redactQueryTransport struct {
+	traced http.RoundTripper
+}
+
+func (t redactQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.RawQuery == "" {
+		return t.traced.RoundTrip(req)
+	}
+	redacted := req.Clone(context.WithValue(req.Context(), rawQueryKey{}, req.URL.RawQuery))
+	redacted.URL.RawQuery = redactQuery(req.URL.RawQuery)
+	return t.traced.RoundTrip(redacted)
+}
+
+// redactQuery keeps the parameter names of a raw query and replaces their
+// values.
+func redactQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		if name, _, ok := strings.Cut(param, "="); ok {
+			params[i] = name + "=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rawQuery, redacted := req.Context().Value(rawQueryKey{}).(string)
+	requestID := RequestIDFrom(req.Context())
+	addRequestID := requestID != "" && req.Header.Get(RequestIDHeader) == ""
+	if redacted || addRequestID {
+		req = req.Clone(req.Context())
+	}
+	if redacted {
+		req.URL.RawQuery = rawQuery
+	}
+	if addRequestID {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Client is an http.Client whose requests are traced and counted per
+// provider.
+var Client = &http.Client{Transport: NewTransport(metrics.NewTransport(http.DefaultTransport)), Timeout: 30 * time.Second}
+
+// Get is http.Get for a request made on behalf of ctx, traced through
+// Client.
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Client.Do(req)
+}
+
+// Logf logs like log.Printf, followed by the request and trace IDs of ctx
+// so that the line can be found from either.
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if requestID := RequestIDFrom(ctx); requestID != "" {
+		message += " request_id=" + requestID
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		message += " trace_id=" + sc.TraceID().String()
+	}
+	log.Print(message)
+}
+
+// statusWriter remembers the status of a response it passes through.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader && status >= http.StatusOK {
+		sw.status, sw.wroteHeader = status, true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
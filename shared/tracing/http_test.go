@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewTransportRedactsQuery(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	var gotQuery, gotRequestID string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotRequestID = r.URL.RawQuery, r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+	ctx := WithRequestID(context.Background(), "req-1")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL+"/v2/top-headlines?country=us&apiKey=secret", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotQuery != "country=us&apiKey=secret" || gotRequestID != "req-1" {
+		t.Errorf("upstream got query %q and request ID %q, want the real query and req-1", gotQuery, gotRequestID)
+	}
+	if req.URL.RawQuery != "country=us&apiKey=secret" {
+		t.Errorf("caller's request changed to %q", req.URL.RawQuery)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	for _, attr := range spans[0].Attributes() {
+		if value := attr.Value.Emit(); strings.Contains(value, "secret") {
+			t.Errorf("span attribute %s = %q leaks the API key", attr.Key, value)
+		}
+		if attr.Key == "url.full" && !strings.HasSuffix(attr.Value.AsString(), "?country=REDACTED&apiKey=REDACTED") {
+			t.Errorf("url.full = %q, want the query values redacted", attr.Value.AsString())
+		}
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		rawQuery string
+		want     string
+	}{
+		{"key=abc", "key=REDACTED"},
+		{"q=a%20b&key=abc&part=snippet", "q=REDACTED&key=REDACTED&part=REDACTED"},
+		{"flag&key=", "flag&key=REDACTED"},
+	}
+	for _, tc := range tests {
+		if got := redactQuery(tc.rawQuery); got != tc.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tc.rawQuery, got, tc.want)
+		}
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		requestID string
+		want      bool
+	}{
+		{"0b6c7c1e-3f1a-4d7e-9a52-6c1f0e2b9d41", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tc := range tests {
+		if got := validRequestID(tc.requestID); got != tc.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tc.requestID, got, tc.want)
+		}
+	}
+}
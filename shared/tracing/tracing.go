@@ -0,0 +1,118 @@
+// Package tracing ties together everything one request does across the
+// gateway and the services. Each request gets an X-Request-ID and a span;
+// both travel with every outbound call, the request ID as a header and the
+// span as a W3C traceparent, so the log lines and spans of the services a
+// request reaches share its IDs.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters spans can be sent to.
+const (
+	// ExporterNone records spans for propagation but exports nothing.
+	ExporterNone = "none"
+	// ExporterStdout writes one JSON span per line to stdout.
+	ExporterStdout = "stdout"
+	// ExporterFile appends one JSON span per line to Config.File.
+	ExporterFile = "file"
+	// ExporterOTLP sends spans to an OTLP/HTTP collector configured with the
+	// standard OTEL_EXPORTER_OTLP_* variables.
+	ExporterOTLP = "otlp"
+)
+
+type Config struct {
+	Service  string
+	Exporter string
+	File     string
+}
+
+// ConfigFromEnv reads OTEL_TRACES_EXPORTER, OTEL_TRACES_FILE and
+// OTEL_SERVICE_NAME; service is the name used when the latter is unset.
+func ConfigFromEnv(service string) (Config, error) {
+	cfg := Config{Service: service, Exporter: ExporterNone, File: "traces.jsonl"}
+
+	if value := os.Getenv("OTEL_SERVICE_NAME"); value != "" {
+		cfg.Service = value
+	}
+	if value := os.Getenv("OTEL_TRACES_FILE"); value != "" {
+		cfg.File = value
+	}
+	if value := os.Getenv("OTEL_TRACES_EXPORTER"); value != "" {
+		cfg.Exporter = strings.ToLower(value)
+	}
+
+	switch cfg.Exporter {
+	case ExporterNone, ExporterStdout, ExporterFile, ExporterOTLP:
+	case "console":
+		cfg.Exporter = ExporterStdout
+	default:
+		return cfg, fmt.Errorf("invalid OTEL_TRACES_EXPORTER %q: expected none, stdout, file or otlp", cfg.Exporter)
+	}
+
+	return cfg, nil
+}
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans; call it before
+// the process exits.
+func Init(cfg Config) (func(context.Context) error, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.Service)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %v", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	var closer io.Closer
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+		closer = file
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}